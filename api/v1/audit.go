@@ -0,0 +1,168 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/internal/service"
+)
+
+// AuditController 命令审计相关API控制器
+type AuditController struct {
+	AuditService *service.AuditService
+}
+
+// NewAuditController 创建命令审计控制器
+func NewAuditController(cfg *config.Config) *AuditController {
+	return &AuditController{
+		AuditService: service.NewAuditService(cfg),
+	}
+}
+
+// ListAudits 获取命令审计记录
+// @Summary 获取命令审计记录
+// @Description 按条件分页获取发送到服务器的命令记录，按时间倒序排列
+// @Tags 命令审计
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param userId query int false "用户ID"
+// @Param username query string false "用户名"
+// @Param server query string false "服务器名称"
+// @Param source query string false "命令来源（http、session、websocket、script、schedule、broadcast、restart、scale、pod）"
+// @Param executor query string false "执行器类型（rcon、attach、exec）"
+// @Param success query bool false "是否执行成功"
+// @Param query query string false "命令内容关键词"
+// @Param from query string false "起始时间（RFC3339格式）"
+// @Param to query string false "结束时间（RFC3339格式）"
+// @Success 200 {object} model.PagedResponse{items=[]model.CommandAudit} "获取成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/audits [get]
+func (c *AuditController) ListAudits(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	query, err := parseAuditQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	audits, total, err := c.AuditService.ListAudits(query, page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取命令审计记录失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.NewPagedResponse(total, pageSize, page, audits))
+}
+
+// ExportAudits 导出命令审计记录
+// @Summary 导出命令审计记录
+// @Description 按条件导出全部匹配的命令记录，支持CSV和JSON Lines格式
+// @Tags 命令审计
+// @Produce text/csv,application/x-ndjson
+// @Security ApiKeyAuth
+// @Param format query string false "导出格式（csv、jsonl）" default(csv)
+// @Param userId query int false "用户ID"
+// @Param username query string false "用户名"
+// @Param server query string false "服务器名称"
+// @Param source query string false "命令来源（http、session、websocket、script、schedule、broadcast、restart、scale、pod）"
+// @Param executor query string false "执行器类型（rcon、attach、exec）"
+// @Param success query bool false "是否执行成功"
+// @Param query query string false "命令内容关键词"
+// @Param from query string false "起始时间（RFC3339格式）"
+// @Param to query string false "结束时间（RFC3339格式）"
+// @Success 200 {string} string "导出文件"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Router /api/v1/audits/export [get]
+func (c *AuditController) ExportAudits(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", service.AuditExportCSV)
+
+	var contentType string
+	switch format {
+	case service.AuditExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	case service.AuditExportJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, service.ErrUnsupportedExportFormat.Error()))
+		return
+	}
+
+	query, err := parseAuditQuery(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	filename := fmt.Sprintf("command-audit-%s.%s", time.Now().Format("20060102-150405"), format)
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Status(http.StatusOK)
+
+	// 响应头已发送，导出过程中的错误只能中断输出
+	if err := c.AuditService.ExportAudits(query, format, ctx.Writer); err != nil {
+		_ = ctx.Error(err)
+	}
+}
+
+// parseAuditQuery 从请求参数中解析审计记录查询条件
+func parseAuditQuery(ctx *gin.Context) (model.CommandAuditQuery, error) {
+	query := model.CommandAuditQuery{
+		Username:     ctx.Query("username"),
+		ServerName:   ctx.Query("server"),
+		Source:       ctx.Query("source"),
+		ExecutorType: ctx.Query("executor"),
+		Keyword:      ctx.Query("query"),
+	}
+
+	if value := ctx.Query("userId"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, errors.New("无效的用户ID")
+		}
+		query.UserID = uint(userID)
+	}
+
+	if value := ctx.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, errors.New("无效的success参数")
+		}
+		query.Success = &success
+	}
+
+	var err error
+	if value := ctx.Query("from"); value != "" {
+		if query.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return query, errors.New("无效的起始时间: " + err.Error())
+		}
+	}
+	if value := ctx.Query("to"); value != "" {
+		if query.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return query, errors.New("无效的结束时间: " + err.Error())
+		}
+	}
+
+	return query, nil
+}
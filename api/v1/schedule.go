@@ -0,0 +1,323 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/internal/service"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// ScheduleController 定时任务相关API控制器
+type ScheduleController struct {
+	Scheduler *service.SchedulerService
+}
+
+// NewScheduleController 创建定时任务控制器
+func NewScheduleController(scheduler *service.SchedulerService) *ScheduleController {
+	return &ScheduleController{
+		Scheduler: scheduler,
+	}
+}
+
+// ListSchedules 获取定时任务列表
+// @Summary 获取定时任务列表
+// @Description 分页获取当前角色有权访问的服务器的定时任务及其下次执行时间
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param server query string false "服务器名称"
+// @Success 200 {object} model.PagedResponse{items=[]model.ScheduledTask} "获取成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/schedules [get]
+func (c *ScheduleController) ListSchedules(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	tasks, total, err := c.Scheduler.ListTasks(commandActor(ctx, model.CommandSourceSchedule), ctx.Query("server"), page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取定时任务列表失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.NewPagedResponse(total, pageSize, page, tasks))
+}
+
+// GetSchedule 获取定时任务详情
+// @Summary 获取定时任务详情
+// @Description 根据ID获取定时任务
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} model.Response{data=model.ScheduledTask} "获取成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "任务不存在"
+// @Router /api/v1/schedules/{id} [get]
+func (c *ScheduleController) GetSchedule(ctx *gin.Context) {
+	id, ok := scheduleID(ctx)
+	if !ok {
+		return
+	}
+
+	task, err := c.Scheduler.GetTask(commandActor(ctx, model.CommandSourceSchedule), id)
+	if err != nil {
+		scheduleError(ctx, "获取定时任务失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(task))
+}
+
+// CreateSchedule 创建定时任务
+// @Summary 创建定时任务
+// @Description 创建按cron表达式执行的定时任务，任务以当前用户的身份执行，当前用户需有权执行任务中的每条命令
+// @Tags 定时任务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param task body model.ScheduledTaskCreate true "任务信息"
+// @Success 200 {object} model.Response{data=model.ScheduledTask} "创建成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/schedules [post]
+func (c *ScheduleController) CreateSchedule(ctx *gin.Context) {
+	var req model.ScheduledTaskCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	task, err := c.Scheduler.CreateTask(commandActor(ctx, model.CommandSourceSchedule), req)
+	if err != nil {
+		scheduleError(ctx, "创建定时任务失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(task))
+}
+
+// UpdateSchedule 更新定时任务
+// @Summary 更新定时任务
+// @Description 更新定时任务，之后任务以当前用户的身份执行
+// @Tags 定时任务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Param task body model.ScheduledTaskUpdate true "任务信息"
+// @Success 200 {object} model.Response{data=model.ScheduledTask} "更新成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "任务不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/schedules/{id} [put]
+func (c *ScheduleController) UpdateSchedule(ctx *gin.Context) {
+	id, ok := scheduleID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.ScheduledTaskUpdate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	task, err := c.Scheduler.UpdateTask(commandActor(ctx, model.CommandSourceSchedule), id, req)
+	if err != nil {
+		scheduleError(ctx, "更新定时任务失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(task))
+}
+
+// DeleteSchedule 删除定时任务
+// @Summary 删除定时任务
+// @Description 删除定时任务及其执行记录
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} model.Response "删除成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "任务不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/schedules/{id} [delete]
+func (c *ScheduleController) DeleteSchedule(ctx *gin.Context) {
+	id, ok := scheduleID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.Scheduler.DeleteTask(commandActor(ctx, model.CommandSourceSchedule), id); err != nil {
+		scheduleError(ctx, "删除定时任务失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(nil))
+}
+
+// RunSchedule 立即执行定时任务
+// @Summary 立即执行定时任务
+// @Description 以当前用户的身份立即执行一次定时任务并返回执行记录，当前用户需有权执行任务中的每条命令；服务器的定时任务暂停时也会执行
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} model.Response{data=model.ScheduledTaskRun} "执行完成，结果见status"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "任务不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/schedules/{id}/run [post]
+func (c *ScheduleController) RunSchedule(ctx *gin.Context) {
+	id, ok := scheduleID(ctx)
+	if !ok {
+		return
+	}
+
+	run, err := c.Scheduler.RunTask(ctx.Request.Context(), commandActor(ctx, model.CommandSourceSchedule), id)
+	if err != nil {
+		scheduleError(ctx, "执行定时任务失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(run))
+}
+
+// ListScheduleRuns 获取定时任务的执行记录
+// @Summary 获取定时任务的执行记录
+// @Description 分页获取定时任务的执行记录，按时间倒序排列
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Success 200 {object} model.PagedResponse{items=[]model.ScheduledTaskRun} "获取成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "任务不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/schedules/{id}/runs [get]
+func (c *ScheduleController) ListScheduleRuns(ctx *gin.Context) {
+	id, ok := scheduleID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	runs, total, err := c.Scheduler.ListRuns(commandActor(ctx, model.CommandSourceSchedule), id, page, pageSize)
+	if err != nil {
+		scheduleError(ctx, "获取执行记录失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.NewPagedResponse(total, pageSize, page, runs))
+}
+
+// PauseServerSchedules 暂停服务器的定时任务
+// @Summary 暂停服务器的定时任务
+// @Description 暂停指定服务器的所有定时任务，暂停期间到达执行时间的任务记录为跳过
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response "暂停成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name}/schedules/pause [post]
+func (c *ScheduleController) PauseServerSchedules(ctx *gin.Context) {
+	c.setServerPaused(ctx, true)
+}
+
+// ResumeServerSchedules 恢复服务器的定时任务
+// @Summary 恢复服务器的定时任务
+// @Description 恢复指定服务器的所有定时任务，暂停期间错过的执行不会补执行
+// @Tags 定时任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response "恢复成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足或无权访问任务所属的服务器"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name}/schedules/resume [post]
+func (c *ScheduleController) ResumeServerSchedules(ctx *gin.Context) {
+	c.setServerPaused(ctx, false)
+}
+
+// setServerPaused 暂停或恢复服务器的定时任务
+func (c *ScheduleController) setServerPaused(ctx *gin.Context, paused bool) {
+	if err := c.Scheduler.SetServerPaused(ctx.Param("name"), paused); err != nil {
+		scheduleError(ctx, "更新定时任务状态失败: ", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(nil))
+}
+
+// scheduleID 解析路径中的任务ID
+func scheduleID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的任务ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// scheduleError 按错误类型返回对应的状态码
+func scheduleError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrScheduleNotFound), errors.Is(err, service.ErrServerNotFound):
+		ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+	case errors.Is(err, service.ErrInvalidCron), errors.Is(err, mccontrol.ErrInvalidScript):
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+	case errors.Is(err, service.ErrScheduleForbidden):
+		ctx.JSON(http.StatusForbidden, model.ErrorResponse(http.StatusForbidden, err.Error()))
+	case errors.Is(err, service.ErrInvalidCommand):
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+	case errors.Is(err, service.ErrCommandDenied):
+		ctx.JSON(http.StatusForbidden, model.ErrorResponse(http.StatusForbidden, err.Error()))
+	default:
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, message+err.Error()))
+	}
+}
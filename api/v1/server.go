@@ -0,0 +1,1053 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/middleware"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/internal/service"
+	"city.newnan/k8s-console/internal/sse"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// ServerController Minecraft服务器相关API控制器
+type ServerController struct {
+	ServerService *service.ServerService
+	StatusHistory *service.StatusHistoryService
+	Commands      *service.CommandService
+	Restarts      *service.RestartService
+	Scales        *service.ScaleService
+	Sessions      *service.SessionService
+	Pods          *service.PodService
+	Registry      *service.ServerRegistry
+	Config        *config.Config
+}
+
+// NewServerController 创建服务器控制器
+func NewServerController(registry *service.ServerRegistry, cfg *config.Config) *ServerController {
+	return &ServerController{
+		ServerService: service.NewServerService(cfg, registry),
+		StatusHistory: service.NewStatusHistoryService(cfg),
+		Commands:      service.NewCommandService(cfg, registry),
+		Restarts:      service.NewRestartService(cfg, registry, sse.GlobalBroker),
+		Scales:        service.NewScaleService(cfg, registry),
+		Sessions:      service.NewSessionService(cfg, registry),
+		Pods:          service.NewPodService(cfg, registry),
+		Registry:      registry,
+		Config:        cfg,
+	}
+}
+
+// getController 根据路径参数获取对应服务器的Minecraft控制器
+func (c *ServerController) getController(ctx *gin.Context) (*mccontrol.MinecraftController, bool) {
+	controller, err := c.Registry.Get(ctx.Param("name"))
+	if err != nil {
+		if errors.Is(err, service.ErrServerNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+		} else {
+			ctx.JSON(http.StatusServiceUnavailable, model.ErrorResponse(http.StatusServiceUnavailable, "Minecraft控制器不可用: "+err.Error()))
+		}
+		return nil, false
+	}
+	return controller, true
+}
+
+// commandActor 根据当前请求的用户信息构造命令发起者
+func commandActor(ctx *gin.Context, source string) service.CommandActor {
+	return service.CommandActor{
+		UserID:   middleware.GetCurrentUserID(ctx),
+		Username: middleware.GetCurrentUsername(ctx),
+		RoleName: middleware.GetCurrentRoleName(ctx),
+		Source:   source,
+	}
+}
+
+// ListServers 获取服务器列表
+// @Summary 获取服务器列表
+// @Description 获取当前角色有权访问的服务器列表
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Success 200 {object} model.PagedResponse{items=[]model.Server} "获取成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers [get]
+func (c *ServerController) ListServers(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	roleName := ctx.GetString("role_name")
+
+	servers, total, err := c.ServerService.ListAccessibleServers(roleName, page, pageSize)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取服务器列表失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.NewPagedResponse(total, pageSize, page, servers))
+}
+
+// GetServer 获取服务器详情
+// @Summary 获取服务器详情
+// @Description 获取指定服务器的注册信息
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response{data=model.Server} "获取成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name} [get]
+func (c *ServerController) GetServer(ctx *gin.Context) {
+	server, err := c.ServerService.GetServerByName(ctx.Param("name"))
+	if err != nil {
+		if errors.Is(err, service.ErrServerNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取服务器信息失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(server))
+}
+
+// CreateServer 注册服务器
+// @Summary 注册服务器
+// @Description 注册一个新的Minecraft服务器
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param server body model.ServerCreate true "服务器信息"
+// @Success 200 {object} model.Response{data=model.Server} "创建成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers [post]
+func (c *ServerController) CreateServer(ctx *gin.Context) {
+	var req model.ServerCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	server, err := c.ServerService.CreateServer(req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "注册服务器失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(server))
+}
+
+// UpdateServer 更新服务器
+// @Summary 更新服务器
+// @Description 更新指定服务器的注册信息，已建立的会话将被关闭
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param server body model.ServerUpdate true "服务器信息"
+// @Success 200 {object} model.Response{data=model.Server} "更新成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name} [put]
+func (c *ServerController) UpdateServer(ctx *gin.Context) {
+	var req model.ServerUpdate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	server, err := c.ServerService.UpdateServer(ctx.Param("name"), req)
+	if err != nil {
+		if errors.Is(err, service.ErrServerNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "更新服务器失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(server))
+}
+
+// DeleteServer 删除服务器
+// @Summary 删除服务器
+// @Description 删除指定服务器的注册信息并释放控制器
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response "删除成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name} [delete]
+func (c *ServerController) DeleteServer(ctx *gin.Context) {
+	if err := c.ServerService.DeleteServer(ctx.Param("name")); err != nil {
+		if errors.Is(err, service.ErrServerNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "删除服务器失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(nil))
+}
+
+// GetStatus 获取服务器状态
+// @Summary 获取服务器状态
+// @Description 检查Minecraft服务器的在线状态、玩家数量及Pod信息，Pod信息包括所在节点、就绪状态、容器重启次数和上一次终止原因、状态条件、最近事件，集群安装了metrics-server时还包括CPU和内存用量
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response{data=mccontrol.ServerStatus} "获取成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/status [get]
+func (c *ServerController) GetStatus(ctx *gin.Context) {
+	controller, ok := c.getController(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := controller.CheckServerStatus()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "检查服务器状态失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(status))
+}
+
+// GetStatusHistory 获取服务器状态历史
+// @Summary 获取服务器状态历史
+// @Description 获取时间窗口内按时间粒度汇总的状态时间序列，包括在线率、玩家数量和延迟
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param from query string false "起始时间（RFC3339格式），默认为24小时前"
+// @Param to query string false "结束时间（RFC3339格式），默认为当前时间"
+// @Param step query string false "时间粒度（如5m、1h），为空则自动选择"
+// @Success 200 {object} model.Response{data=[]model.StatusPoint} "获取成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name}/status/history [get]
+func (c *ServerController) GetStatusHistory(ctx *gin.Context) {
+	from, to, ok := c.parseTimeWindow(ctx)
+	if !ok {
+		return
+	}
+
+	step, err := service.ParseStatusStep(ctx.Query("step"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	points, err := c.StatusHistory.GetHistory(ctx.Param("name"), from, to, step)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取状态历史失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(points))
+}
+
+// GetUptime 获取服务器在线率
+// @Summary 获取服务器在线率
+// @Description 统计时间窗口内的在线率和离线时段
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param from query string false "起始时间（RFC3339格式），默认为24小时前"
+// @Param to query string false "结束时间（RFC3339格式），默认为当前时间"
+// @Success 200 {object} model.Response{data=model.UptimeResponse} "获取成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Router /api/v1/servers/{name}/status/uptime [get]
+func (c *ServerController) GetUptime(ctx *gin.Context) {
+	from, to, ok := c.parseTimeWindow(ctx)
+	if !ok {
+		return
+	}
+
+	uptime, err := c.StatusHistory.GetUptime(ctx.Param("name"), from, to)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "统计在线率失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(uptime))
+}
+
+// parseTimeWindow 检查服务器是否存在并解析from、to查询参数
+func (c *ServerController) parseTimeWindow(ctx *gin.Context) (time.Time, time.Time, bool) {
+	if _, err := c.ServerService.GetServerByName(ctx.Param("name")); err != nil {
+		if errors.Is(err, service.ErrServerNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取服务器信息失败: "+err.Error()))
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	from, to, err := service.ParseStatusWindow(ctx.Query("from"), ctx.Query("to"), time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
+
+// ExecuteCommand 执行单条命令
+// @Summary 执行命令
+// @Description 使用自动选择的执行器向Minecraft服务器发送一条命令。
+// @Description RCON的响应与命令一一对应；attach和exec执行器的响应按时间窗口从服务器输出中收集，best_effort为true，
+// @Description 可能混入同时产生的其他日志或缺少输出较慢的部分，同一Pod上的收集依次进行
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param command body model.CommandRequest true "命令信息"
+// @Success 200 {object} model.Response{data=model.CommandResult} "执行成功"
+// @Failure 400 {object} model.Response "请求参数错误或命令包含换行等控制字符"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/command [post]
+func (c *ServerController) ExecuteCommand(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.CommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	actor := commandActor(ctx, model.CommandSourceHTTP)
+	result, err := c.Commands.ExecuteCommand(ctx.Request.Context(), actor, ctx.Param("name"), req.Command)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCommand) {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrCommandDenied) {
+			ctx.JSON(http.StatusForbidden, model.ErrorResponse(http.StatusForbidden, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "执行命令失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(model.CommandResult{
+		Command:      req.Command,
+		Response:     result.Response,
+		ExecutorType: string(result.ExecutorType),
+		BestEffort:   result.ExecutorType.BestEffortResponse(),
+	}))
+}
+
+// BroadcastCommand 在所有Pod上执行命令
+// @Summary 在所有Pod上执行命令
+// @Description 在所有匹配服务器Pod标签选择器、正在运行的Pod上同时执行一条命令，返回每个Pod的结果，单个Pod失败不影响其他Pod
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param command body model.BroadcastRequest true "命令内容"
+// @Success 200 {object} model.Response{data=model.BroadcastResponse} "执行完成，各Pod的结果见results"
+// @Failure 400 {object} model.Response "请求参数错误或命令包含换行等控制字符"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在或没有运行中的Pod"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/command/broadcast [post]
+func (c *ServerController) BroadcastCommand(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.BroadcastRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	actor := commandActor(ctx, model.CommandSourceBroadcast)
+	results, err := c.Commands.BroadcastCommand(ctx.Request.Context(), actor, ctx.Param("name"), req.Command, mccontrol.ExecutorType(req.ExecutorType))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCommand):
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		case errors.Is(err, service.ErrCommandDenied):
+			ctx.JSON(http.StatusForbidden, model.ErrorResponse(http.StatusForbidden, err.Error()))
+		case errors.Is(err, mccontrol.ErrPodNotFound):
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+		default:
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "执行命令失败: "+err.Error()))
+		}
+		return
+	}
+
+	response := model.BroadcastResponse{
+		Command: req.Command,
+		Success: true,
+		Results: make([]model.PodCommandResponse, 0, len(results)),
+	}
+	for _, result := range results {
+		podResponse := model.PodCommandResponse{
+			Pod:          result.Pod,
+			Success:      result.Err == nil,
+			Response:     result.Response,
+			ExecutorType: string(result.ExecutorType),
+			BestEffort:   result.ExecutorType.BestEffortResponse(),
+			DurationMs:   result.Duration.Milliseconds(),
+		}
+		if result.Err != nil {
+			podResponse.Error = result.Err.Error()
+			response.Success = false
+		}
+		response.Results = append(response.Results, podResponse)
+	}
+	ctx.JSON(http.StatusOK, model.SuccessResponse(response))
+}
+
+// ListPods 获取服务器的Pod列表
+// @Summary 获取服务器的Pod列表
+// @Description 列出所有匹配服务器Pod标签选择器的Pod及其状态，并标记当前使用和固定使用的Pod
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response{data=[]mccontrol.PodInfo} "获取成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/pods [get]
+func (c *ServerController) ListPods(ctx *gin.Context) {
+	controller, ok := c.getController(ctx)
+	if !ok {
+		return
+	}
+
+	pods, err := controller.ListPods()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取Pod列表失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(pods))
+}
+
+// PinPod 固定服务器使用的Pod
+// @Summary 固定服务器使用的Pod
+// @Description 将服务器固定到指定的Pod，之后的命令、日志和状态检测都使用该Pod，不再自动选择。
+// @Description 固定的Pod被删除后相关操作会失败，直到Pod以相同名称重建或取消固定；控制台重启后恢复自动选择。固定记录审计，来源为pod
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param pin body model.PodPinRequest true "要固定的Pod"
+// @Success 200 {object} model.Response{data=mccontrol.ServerStatus} "固定成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器或Pod不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/pods/pin [post]
+func (c *ServerController) PinPod(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.PodPinRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	controller, err := c.Pods.PinPod(commandActor(ctx, model.CommandSourcePod), ctx.Param("name"), req.Pod)
+	if err != nil {
+		if errors.Is(err, mccontrol.ErrPodNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "固定Pod失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(controller.LastStatus()))
+}
+
+// UnpinPod 取消固定服务器使用的Pod
+// @Summary 取消固定服务器使用的Pod
+// @Description 取消固定Pod，恢复自动选择正在运行的Pod。取消固定记录审计，来源为pod
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response{data=mccontrol.ServerStatus} "取消成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/pods/pin [delete]
+func (c *ServerController) UnpinPod(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	controller, err := c.Pods.UnpinPod(commandActor(ctx, model.CommandSourcePod), ctx.Param("name"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "取消固定Pod失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(controller.LastStatus()))
+}
+
+// ExecuteScript 执行命令脚本
+// @Summary 执行命令脚本
+// @Description 在同一个命令会话中按顺序执行一组命令，支持步骤之间的等待、失败后停止或继续、${name}变量替换；
+// @Description 执行前检查所有命令的权限，任一命令被拒绝时不执行任何命令。dry_run为true时只校验脚本和命令权限。
+// @Description 每个步骤最多等待10分钟，所有步骤的等待时间之和不能超过30分钟，脚本最多执行30分钟
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param script body model.ScriptRequest true "脚本内容"
+// @Success 200 {object} model.Response{data=model.ScriptResponse} "执行完成，各步骤的结果见steps"
+// @Failure 400 {object} model.Response "请求参数错误或命令包含换行等控制字符"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/script [post]
+func (c *ServerController) ExecuteScript(ctx *gin.Context) {
+	var req model.ScriptRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	script := service.NewScript(req.Steps, req.Variables, req.OnError, req.ExecutorType)
+	actor := commandActor(ctx, model.CommandSourceScript)
+	if req.DryRun {
+		c.checkScript(ctx, actor, script)
+		return
+	}
+
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	result, err := c.Commands.ExecuteScript(ctx.Request.Context(), actor, ctx.Param("name"), script)
+	if result == nil {
+		switch {
+		case errors.Is(err, mccontrol.ErrInvalidScript):
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		case errors.Is(err, service.ErrInvalidCommand):
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		case errors.Is(err, service.ErrCommandDenied):
+			ctx.JSON(http.StatusForbidden, model.ErrorResponse(http.StatusForbidden, err.Error()))
+		default:
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "执行脚本失败: "+err.Error()))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(model.ScriptResponse{
+		Success:    result.Success,
+		DurationMs: result.Duration.Milliseconds(),
+		Steps:      service.ScriptStepResponses(result),
+	}))
+}
+
+// checkScript 试运行脚本：校验脚本并检查每个命令的权限，不连接服务器
+func (c *ServerController) checkScript(ctx *gin.Context, actor service.CommandActor, script mccontrol.Script) {
+	if _, err := c.ServerService.GetServerByName(ctx.Param("name")); err != nil {
+		if errors.Is(err, service.ErrServerNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取服务器失败: "+err.Error()))
+		}
+		return
+	}
+
+	checks, err := c.Commands.CheckScript(actor, ctx.Param("name"), script)
+	if err != nil {
+		if errors.Is(err, mccontrol.ErrInvalidScript) {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "检查命令权限失败: "+err.Error()))
+		}
+		return
+	}
+
+	response := model.ScriptResponse{
+		DryRun:  true,
+		Success: true,
+		Steps:   make([]model.ScriptStepResponse, len(checks)),
+	}
+	for i, check := range checks {
+		response.Steps[i] = model.ScriptStepResponse{
+			Index:   i,
+			Command: check.Command,
+			Allowed: check.Allowed,
+			Skipped: true,
+		}
+		if !check.Allowed {
+			response.Success = false
+			response.Steps[i].Error = service.ErrCommandDenied.Error()
+		}
+	}
+	ctx.JSON(http.StatusOK, model.SuccessResponse(response))
+}
+
+// GetLogs 获取历史日志
+// @Summary 获取历史日志
+// @Description 一次性获取Minecraft服务器容器的日志
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param tailLines query int false "获取最近多少行日志" default(100)
+// @Param sinceTime query string false "起始时间（RFC3339格式）"
+// @Param container query string false "容器名称，为空则使用默认容器"
+// @Param previous query bool false "是否获取以前终止的容器的日志"
+// @Param pod query string false "Pod名称，为空则使用服务器当前的Pod"
+// @Success 200 {object} model.Response{data=[]string} "获取成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器或Pod不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/logs [get]
+func (c *ServerController) GetLogs(ctx *gin.Context) {
+	controller, ok := c.getController(ctx)
+	if !ok {
+		return
+	}
+
+	var options mccontrol.LogOptions
+
+	tailLines, err := strconv.ParseInt(ctx.DefaultQuery("tailLines", "100"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的行数"))
+		return
+	}
+	if tailLines > 0 {
+		options.TailLines = &tailLines
+	}
+
+	if since := ctx.Query("sinceTime"); since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的起始时间: "+err.Error()))
+			return
+		}
+		options.SinceTime = &sinceTime
+	}
+
+	options.Container = ctx.Query("container")
+	options.Previous, _ = strconv.ParseBool(ctx.DefaultQuery("previous", "false"))
+	options.PodName = ctx.Query("pod")
+
+	logs, err := controller.FetchLogs(options, nil)
+	if err != nil {
+		if errors.Is(err, mccontrol.ErrPodNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "获取日志失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(logs))
+}
+
+// StreamLogs 实时日志流
+// @Summary 实时日志流
+// @Description 通过SSE实时推送Minecraft服务器日志，每条日志的事件ID为其时间戳，断线重连时通过Last-Event-ID续传，未携带时先补发最近100行日志
+// @Tags 服务器管理
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param Last-Event-ID header string false "最后收到的事件ID"
+// @Param lastEventId query string false "最后收到的事件ID（无法设置请求头时使用）"
+// @Param pod query string false "Pod名称，为空则跟随服务器当前的Pod"
+// @Success 200 {string} string "SSE数据流，事件类型为log或status"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/logs/stream [get]
+func (c *ServerController) StreamLogs(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	sse.GlobalBroker.ServeTopic(ctx, service.LogTopic(ctx.Param("name"), ctx.Query("pod")))
+}
+
+// RestartServer 重启服务器
+// @Summary 重启服务器
+// @Description 在后台平滑重启服务器：倒计时提醒玩家，保存世界并停止服务器，然后删除Pod或滚动重启所属的StatefulSet/Deployment，
+// @Description 等待新Pod运行并且服务器可以Ping通。重启进度通过 /api/v1/servers/{name}/restart/stream 推送
+// @Description 重启结果和重启过程中发送的命令记录在命令审计中，来源为restart
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param restart body model.RestartRequest false "重启选项"
+// @Success 202 {object} model.Response "已开始重启"
+// @Failure 400 {object} model.Response "请求参数错误或重启原因包含换行等控制字符"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 409 {object} model.Response "服务器正在重启"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/restart [post]
+func (c *ServerController) RestartServer(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.RestartRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+			return
+		}
+	}
+
+	options := mccontrol.RestartOptions{
+		Countdown: time.Duration(req.Countdown) * time.Second,
+		Reason:    req.Reason,
+		Mode:      mccontrol.RestartMode(req.Mode),
+		Force:     req.Force,
+	}
+	if err := c.Restarts.Restart(commandActor(ctx, model.CommandSourceHTTP), ctx.Param("name"), options); err != nil {
+		if errors.Is(err, mccontrol.ErrRestartInProgress) {
+			ctx.JSON(http.StatusConflict, model.ErrorResponse(http.StatusConflict, err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRestartReason) {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusServiceUnavailable, model.ErrorResponse(http.StatusServiceUnavailable, "Minecraft控制器不可用: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, model.SuccessResponse(nil))
+}
+
+// StreamRestart 订阅服务器的重启进度
+// @Summary 订阅服务器的重启进度
+// @Description 通过SSE推送重启进度，事件类型为restart，数据中的phase为completed或failed时重启结束
+// @Tags 服务器管理
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {string} string "SSE数据流，事件类型为restart"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/restart/stream [get]
+func (c *ServerController) StreamRestart(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	sse.GlobalBroker.ServeTopic(ctx, service.RestartTopicPrefix+ctx.Param("name"))
+}
+
+// WakeServer 唤醒服务器
+// @Summary 唤醒服务器
+// @Description 将已缩容到0的服务器恢复到缩容前的副本数，等待Pod运行并且服务器可以Ping通后返回服务器状态；服务器未缩容时只等待服务器可用。
+// @Description 唤醒记录审计，来源为scale，响应为唤醒后的副本数
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param wake body model.WakeRequest false "唤醒选项"
+// @Success 200 {object} model.Response{data=mccontrol.ServerStatus} "唤醒成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/wake [post]
+func (c *ServerController) WakeServer(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.WakeRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+			return
+		}
+	}
+
+	actor := commandActor(ctx, model.CommandSourceScale)
+	controller, err := c.Scales.Wake(ctx.Request.Context(), actor, ctx.Param("name"), time.Duration(req.Timeout)*time.Second)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "唤醒服务器失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(controller.LastStatus()))
+}
+
+// SleepServer 将服务器缩容到0
+// @Summary 将服务器缩容到0
+// @Description 保存世界后将服务器所属的StatefulSet/Deployment缩容到0，之后可通过唤醒接口恢复。缩容记录审计，来源为scale
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response "缩容成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 409 {object} model.Response "服务器正在重启"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/sleep [post]
+func (c *ServerController) SleepServer(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	if err := c.Scales.Sleep(ctx.Request.Context(), commandActor(ctx, model.CommandSourceScale), ctx.Param("name")); err != nil {
+		if errors.Is(err, mccontrol.ErrRestartInProgress) {
+			ctx.JSON(http.StatusConflict, model.ErrorResponse(http.StatusConflict, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "缩容服务器失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(nil))
+}
+
+// CreateSession 创建命令会话
+// @Summary 创建命令会话
+// @Description 创建一个持久化的命令会话，空闲超时后自动关闭。指定pod时会话固定使用该Pod，否则跟随服务器当前的Pod。
+// @Description 会话只能由创建者使用和关闭
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param session body model.SessionCreate false "会话选项"
+// @Success 200 {object} model.Response{data=model.SessionResponse} "创建成功"
+// @Failure 400 {object} model.Response "请求参数错误"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器或Pod不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/sessions [post]
+func (c *ServerController) CreateSession(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.SessionCreate
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+			return
+		}
+	}
+
+	idleTimeout := c.Config.MCSessionIdleTimeout
+	if req.IdleTimeout > 0 {
+		idleTimeout = time.Duration(req.IdleTimeout) * time.Second
+	}
+
+	actor := commandActor(ctx, model.CommandSourceSession)
+	session, err := c.Sessions.CreateSession(actor, ctx.Param("name"), req.Pod, idleTimeout, mccontrol.ExecutorType(req.ExecutorType))
+	if err != nil {
+		if errors.Is(err, mccontrol.ErrPodNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "创建命令会话失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(model.SessionResponse{
+		SessionID:    session.GetID(),
+		ExecutorType: string(session.GetExecutorType()),
+		Pod:          session.GetPodName(),
+	}))
+}
+
+// ListSessions 获取命令会话列表
+// @Summary 获取命令会话列表
+// @Description 列出当前用户在服务器上创建的活跃命令会话ID
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Success 200 {object} model.Response{data=[]string} "获取成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器不存在"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/sessions [get]
+func (c *ServerController) ListSessions(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	sessions, err := c.Sessions.ListSessions(commandActor(ctx, model.CommandSourceSession), ctx.Param("name"))
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, model.ErrorResponse(http.StatusServiceUnavailable, "Minecraft控制器不可用: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(sessions))
+}
+
+// SessionExecuteCommand 在会话中执行命令
+// @Summary 在会话中执行命令
+// @Description 使用指定的命令会话向Minecraft服务器发送命令，attach和exec会话的响应是尽力收集的结果（best_effort为true）。
+// @Description 自动选择执行器的会话在执行器失效时切换执行器，只有只读的查询命令会自动重试，其他命令返回错误且可能已经执行。
+// @Description 只能使用当前用户创建的会话
+// @Tags 服务器管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param id path string true "会话ID"
+// @Param command body model.CommandRequest true "命令信息"
+// @Success 200 {object} model.Response{data=model.CommandResult} "执行成功"
+// @Failure 400 {object} model.Response "请求参数错误或命令包含换行等控制字符"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器或会话不存在"
+// @Failure 500 {object} model.Response "服务器内部错误"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/sessions/{id}/command [post]
+func (c *ServerController) SessionExecuteCommand(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	var req model.CommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, "无效的请求参数: "+err.Error()))
+		return
+	}
+
+	actor := commandActor(ctx, model.CommandSourceSession)
+	result, err := c.Sessions.ExecuteCommand(ctx.Request.Context(), actor, ctx.Param("name"), ctx.Param("id"), req.Command)
+	if err != nil {
+		if errors.Is(err, mccontrol.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCommand) {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse(http.StatusBadRequest, err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrCommandDenied) {
+			ctx.JSON(http.StatusForbidden, model.ErrorResponse(http.StatusForbidden, err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse(http.StatusInternalServerError, "执行命令失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(model.CommandResult{
+		Command:      req.Command,
+		Response:     result.Response,
+		ExecutorType: string(result.ExecutorType),
+		BestEffort:   result.ExecutorType.BestEffortResponse(),
+	}))
+}
+
+// CloseSession 关闭命令会话
+// @Summary 关闭命令会话
+// @Description 关闭当前用户创建的命令会话并释放连接
+// @Tags 服务器管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param name path string true "服务器名称"
+// @Param id path string true "会话ID"
+// @Success 200 {object} model.Response "关闭成功"
+// @Failure 401 {object} model.Response "未授权"
+// @Failure 403 {object} model.Response "权限不足"
+// @Failure 404 {object} model.Response "服务器或会话不存在"
+// @Failure 503 {object} model.Response "控制器不可用"
+// @Router /api/v1/servers/{name}/sessions/{id} [delete]
+func (c *ServerController) CloseSession(ctx *gin.Context) {
+	if _, ok := c.getController(ctx); !ok {
+		return
+	}
+
+	if err := c.Sessions.CloseSession(commandActor(ctx, model.CommandSourceSession), ctx.Param("name"), ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusNotFound, model.ErrorResponse(http.StatusNotFound, "关闭命令会话失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SuccessResponse(nil))
+}
@@ -1,133 +1,219 @@
-package config
-
-import (
-	"fmt"
-	"os"
-	"strconv"
-	"time"
-)
-
-// Config 存储应用程序配置
-type Config struct {
-	// 服务器配置
-	ServerPort     int
-	ServerHost     string
-	Mode           string
-	AllowedOrigins []string
-
-	// 数据库配置
-	DBType     string
-	DBHost     string
-	DBPort     int
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBPath     string // 用于SQLite
-
-	// JWT配置
-	JWTSecret         string
-	JWTExpireTime     time.Duration
-	JWTRefreshTime    time.Duration
-	JWTIssuer         string
-	JWTCookieSecure   bool
-	JWTCookieHTTPOnly bool
-
-	// 路径配置
-	CasbinModelPath string
-	LogPath         string
-	SwaggerPath     string
-}
-
-// GetEnv 从环境变量中获取字符串值，如果不存在则返回默认值
-func GetEnv(key, defaultValue string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	return value
-}
-
-// GetEnvInt 从环境变量中获取整数值，如果不存在或解析失败则返回默认值
-func GetEnvInt(key string, defaultValue int) int {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	intValue, err := strconv.Atoi(value)
-	if err != nil {
-		return defaultValue
-	}
-	return intValue
-}
-
-// GetEnvBool 从环境变量中获取布尔值，如果不存在则返回默认值
-func GetEnvBool(key string, defaultValue bool) bool {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	boolValue, err := strconv.ParseBool(value)
-	if err != nil {
-		return defaultValue
-	}
-	return boolValue
-}
-
-// GetEnvDuration 从环境变量中获取时间间隔，如果不存在则返回默认值
-func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	durationValue, err := time.ParseDuration(value)
-	if err != nil {
-		return defaultValue
-	}
-	return durationValue
-}
-
-// LoadConfig 从环境变量加载配置
-func LoadConfig() *Config {
-	return &Config{
-		// 服务器配置
-		ServerPort:     GetEnvInt("SERVER_PORT", 8080),
-		ServerHost:     GetEnv("SERVER_HOST", "0.0.0.0"),
-		Mode:           GetEnv("GIN_MODE", "debug"),
-		AllowedOrigins: []string{GetEnv("ALLOWED_ORIGINS", "*")},
-
-		// 数据库配置
-		DBType:     GetEnv("DB_TYPE", "sqlite"),
-		DBHost:     GetEnv("DB_HOST", "localhost"),
-		DBPort:     GetEnvInt("DB_PORT", 3306),
-		DBUser:     GetEnv("DB_USER", "root"),
-		DBPassword: GetEnv("DB_PASSWORD", "password"),
-		DBName:     GetEnv("DB_NAME", "k8sconsole"),
-		DBPath:     GetEnv("DB_PATH", "k8sconsole.db"),
-
-		// JWT配置
-		JWTSecret:         GetEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpireTime:     GetEnvDuration("JWT_EXPIRE_TIME", 24*time.Hour),
-		JWTRefreshTime:    GetEnvDuration("JWT_REFRESH_TIME", 7*24*time.Hour),
-		JWTIssuer:         GetEnv("JWT_ISSUER", "k8sconsole"),
-		JWTCookieSecure:   GetEnvBool("JWT_COOKIE_SECURE", false),
-		JWTCookieHTTPOnly: GetEnvBool("JWT_COOKIE_HTTP_ONLY", true),
-
-		// 路径配置
-		CasbinModelPath: GetEnv("CASBIN_MODEL_PATH", "config/rbac_model.conf"),
-		LogPath:         GetEnv("LOG_PATH", "logs"),
-		SwaggerPath:     GetEnv("SWAGGER_PATH", "docs/swagger"),
-	}
-}
-
-// GetDBConnString 根据数据库类型返回相应的连接字符串
-func (c *Config) GetDBConnString() string {
-	switch c.DBType {
-	case "mysql":
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-			c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
-	case "sqlite":
-		return c.DBPath
-	default:
-		return c.DBPath
-	}
-}
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config 存储应用程序配置
+type Config struct {
+	// 服务器配置
+	ServerPort     int
+	ServerHost     string
+	Mode           string
+	AllowedOrigins []string
+
+	// 数据库配置
+	DBType     string
+	DBHost     string
+	DBPort     int
+	DBUser     string
+	DBPassword string
+	DBName     string
+	DBPath     string // 用于SQLite
+
+	// JWT配置
+	JWTSecret         string
+	JWTExpireTime     time.Duration
+	JWTRefreshTime    time.Duration
+	JWTIssuer         string
+	JWTCookieSecure   bool
+	JWTCookieHTTPOnly bool
+
+	// 路径配置
+	CasbinModelPath        string
+	CasbinCommandModelPath string // 命令权限策略模型
+	LogPath                string
+	SwaggerPath            string
+
+	// Minecraft服务器配置
+	MCServerName            string        // 默认服务器名称，服务器列表为空时按以下配置创建
+	MCRunMode               string        // 运行模式：InCluster（集群内）或OutOfCluster（集群外）
+	MCKubeconfigPath        string        // OutOfCluster模式下使用的kubeconfig路径
+	MCNamespace             string        // 服务器所在命名空间
+	MCPodLabelSelector      string        // Pod标签选择器
+	MCServiceLabelSelector  string        // Service标签选择器，为空则使用Pod标签选择器
+	MCContainerName         string        // 容器名称
+	MCGamePort              int           // 游戏端口
+	MCRconPort              int           // RCON端口
+	MCRconPassword          string        // RCON密码
+	MCRconSecretName        string        // 保存RCON密码的Secret名称
+	MCRconSecretKey         string        // Secret中保存RCON密码的键名
+	MCRconPasswordEnv       string        // 保存RCON密码的容器环境变量名称
+	MCRconPropertiesPath    string        // 容器内server.properties的路径，从中读取RCON密码
+	MCStatusInterval        time.Duration // 状态监控间隔，为0则不启动后台监控
+	MCPodWatch              bool          // 是否通过watch跟踪Pod变化，需要Pod和Service的list/watch权限
+	MCIdleTimeout           time.Duration // 默认服务器没有玩家多久后缩容到0，为0则不缩容
+	MCSessionIdleTimeout    time.Duration // 命令会话默认空闲超时
+	MCOutputCaptureWindow   time.Duration // attach/exec执行器写入命令后从日志收集输出的最长时间，为0则不收集
+	MCOutputCaptureQuiet    time.Duration // 收集输出时，超过该时间没有新日志即认为输出结束
+	MCExecutorProbeInterval time.Duration // 自动选择执行器时重新探测优先级更高的执行器的间隔
+	MCRconReadTimeout       time.Duration // RCON等待命令响应的超时
+	MCRconWriteTimeout      time.Duration // RCON写入命令的超时
+	MCRconPoolSize          int           // 每个服务器最多同时使用的RCON连接数
+	MCRconPoolIdleTimeout   time.Duration // 空闲超过该时间的RCON连接被关闭
+	MCRconPoolMaxLifetime   time.Duration // RCON连接的最长存活时间
+	MCRconPoolHealthCheck   time.Duration // 检查空闲RCON连接是否可用的间隔
+
+	// 指标配置
+	MetricsEnabled bool   // 是否启用/metrics接口
+	MetricsToken   string // 访问/metrics接口的Bearer令牌，为空时不开放/metrics接口
+
+	// 状态历史配置
+	MCStatusRawRetention       time.Duration // 原始状态记录保留时长，超过后降采样
+	MCStatusRetention          time.Duration // 状态历史保留时长，为0则永久保留
+	MCStatusDownsampleInterval time.Duration // 降采样的时间粒度
+
+	// 定时任务配置
+	ScheduleEnabled      bool          // 是否执行定时任务
+	ScheduleRunRetention time.Duration // 定时任务执行记录保留时长，为0则永久保留
+}
+
+// GetEnv 从环境变量中获取字符串值，如果不存在则返回默认值
+func GetEnv(key, defaultValue string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvInt 从环境变量中获取整数值，如果不存在或解析失败则返回默认值
+func GetEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return intValue
+}
+
+// GetEnvBool 从环境变量中获取布尔值，如果不存在则返回默认值
+func GetEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return boolValue
+}
+
+// GetEnvDuration 从环境变量中获取时间间隔，如果不存在则返回默认值
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	durationValue, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return durationValue
+}
+
+// LoadConfig 从环境变量加载配置
+func LoadConfig() *Config {
+	return &Config{
+		// 服务器配置
+		ServerPort:     GetEnvInt("SERVER_PORT", 8080),
+		ServerHost:     GetEnv("SERVER_HOST", "0.0.0.0"),
+		Mode:           GetEnv("GIN_MODE", "debug"),
+		AllowedOrigins: []string{GetEnv("ALLOWED_ORIGINS", "*")},
+
+		// 数据库配置
+		DBType:     GetEnv("DB_TYPE", "sqlite"),
+		DBHost:     GetEnv("DB_HOST", "localhost"),
+		DBPort:     GetEnvInt("DB_PORT", 3306),
+		DBUser:     GetEnv("DB_USER", "root"),
+		DBPassword: GetEnv("DB_PASSWORD", "password"),
+		DBName:     GetEnv("DB_NAME", "k8sconsole"),
+		DBPath:     GetEnv("DB_PATH", "k8sconsole.db"),
+
+		// JWT配置
+		JWTSecret:         GetEnv("JWT_SECRET", "your-secret-key"),
+		JWTExpireTime:     GetEnvDuration("JWT_EXPIRE_TIME", 24*time.Hour),
+		JWTRefreshTime:    GetEnvDuration("JWT_REFRESH_TIME", 7*24*time.Hour),
+		JWTIssuer:         GetEnv("JWT_ISSUER", "k8sconsole"),
+		JWTCookieSecure:   GetEnvBool("JWT_COOKIE_SECURE", false),
+		JWTCookieHTTPOnly: GetEnvBool("JWT_COOKIE_HTTP_ONLY", true),
+
+		// 路径配置
+		CasbinModelPath:        GetEnv("CASBIN_MODEL_PATH", "config/rbac_model.conf"),
+		CasbinCommandModelPath: GetEnv("CASBIN_COMMAND_MODEL_PATH", "config/command_model.conf"),
+		LogPath:                GetEnv("LOG_PATH", "logs"),
+		SwaggerPath:            GetEnv("SWAGGER_PATH", "docs/swagger"),
+
+		// Minecraft服务器配置
+		MCServerName:            GetEnv("MC_SERVER_NAME", "default"),
+		MCRunMode:               GetEnv("MC_RUN_MODE", "InCluster"),
+		MCKubeconfigPath:        GetEnv("MC_KUBECONFIG", ""),
+		MCNamespace:             GetEnv("MC_NAMESPACE", "default"),
+		MCPodLabelSelector:      GetEnv("MC_POD_SELECTOR", "app=minecraft"),
+		MCServiceLabelSelector:  GetEnv("MC_SERVICE_SELECTOR", ""),
+		MCContainerName:         GetEnv("MC_CONTAINER", "minecraft-server"),
+		MCGamePort:              GetEnvInt("MC_GAME_PORT", 25565),
+		MCRconPort:              GetEnvInt("MC_RCON_PORT", 25575),
+		MCRconPassword:          GetEnv("MC_RCON_PASSWORD", ""),
+		MCRconSecretName:        GetEnv("MC_RCON_SECRET", ""),
+		MCRconSecretKey:         GetEnv("MC_RCON_SECRET_KEY", ""),
+		MCRconPasswordEnv:       GetEnv("MC_RCON_PASSWORD_ENV", ""),
+		MCRconPropertiesPath:    GetEnv("MC_RCON_PROPERTIES_PATH", ""),
+		MCStatusInterval:        GetEnvDuration("MC_STATUS_INTERVAL", 30*time.Second),
+		MCPodWatch:              GetEnvBool("MC_POD_WATCH", true),
+		MCIdleTimeout:           GetEnvDuration("MC_IDLE_TIMEOUT", 0),
+		MCSessionIdleTimeout:    GetEnvDuration("MC_SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		MCOutputCaptureWindow:   GetEnvDuration("MC_OUTPUT_CAPTURE_WINDOW", 2*time.Second),
+		MCOutputCaptureQuiet:    GetEnvDuration("MC_OUTPUT_CAPTURE_QUIET", 300*time.Millisecond),
+		MCExecutorProbeInterval: GetEnvDuration("MC_EXECUTOR_PROBE_INTERVAL", 5*time.Minute),
+		MCRconReadTimeout:       GetEnvDuration("MC_RCON_READ_TIMEOUT", 10*time.Second),
+		MCRconWriteTimeout:      GetEnvDuration("MC_RCON_WRITE_TIMEOUT", 5*time.Second),
+		MCRconPoolSize:          GetEnvInt("MC_RCON_POOL_SIZE", 4),
+		MCRconPoolIdleTimeout:   GetEnvDuration("MC_RCON_POOL_IDLE_TIMEOUT", 5*time.Minute),
+		MCRconPoolMaxLifetime:   GetEnvDuration("MC_RCON_POOL_MAX_LIFETIME", 30*time.Minute),
+		MCRconPoolHealthCheck:   GetEnvDuration("MC_RCON_POOL_HEALTH_CHECK", 30*time.Second),
+
+		// 指标配置
+		MetricsEnabled: GetEnvBool("METRICS_ENABLED", false),
+		MetricsToken:   GetEnv("METRICS_TOKEN", ""),
+
+		// 状态历史配置
+		MCStatusRawRetention:       GetEnvDuration("MC_STATUS_RAW_RETENTION", 24*time.Hour),
+		MCStatusRetention:          GetEnvDuration("MC_STATUS_RETENTION", 30*24*time.Hour),
+		MCStatusDownsampleInterval: GetEnvDuration("MC_STATUS_DOWNSAMPLE_INTERVAL", time.Hour),
+
+		// 定时任务配置
+		ScheduleEnabled:      GetEnvBool("SCHEDULE_ENABLED", true),
+		ScheduleRunRetention: GetEnvDuration("SCHEDULE_RUN_RETENTION", 30*24*time.Hour),
+	}
+}
+
+// GetDBConnString 根据数据库类型返回相应的连接字符串
+func (c *Config) GetDBConnString() string {
+	switch c.DBType {
+	case "mysql":
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			c.DBUser, c.DBPassword, c.DBHost, c.DBPort, c.DBName)
+	case "sqlite":
+		return c.DBPath
+	default:
+		return c.DBPath
+	}
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/internal/sse"
+	"city.newnan/k8s-console/internal/websocket"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// 指标名称前缀
+const namespace = "k8sconsole"
+
+// ControllerSource 提供当前已创建的Minecraft控制器
+type ControllerSource interface {
+	// Controllers 返回服务器名称到控制器的快照
+	Controllers() map[string]*mccontrol.MinecraftController
+}
+
+var (
+	// Registry 应用的Prometheus指标注册表
+	Registry = prometheus.NewRegistry()
+
+	commandsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "按执行器类型统计的命令执行次数",
+	}, []string{"server", "executor", "result"})
+
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "command_duration_seconds",
+		Help:      "按执行器类型统计的命令执行耗时",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"server", "executor"})
+
+	logStreamReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "log_stream_reconnects_total",
+		Help:      "流式日志重连次数",
+	}, []string{"server"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		commandsTotal,
+		commandDuration,
+		logStreamReconnects,
+	)
+}
+
+// Register 注册按需采集的服务器和实时连接指标
+func Register(source ControllerSource) error {
+	return Registry.Register(&collector{source: source})
+}
+
+// Handler 返回/metrics接口的处理函数，要求请求携带Bearer令牌，token为空时拒绝所有请求
+func Handler(token string) gin.HandlerFunc {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.JSON(http.StatusUnauthorized, model.ErrorResponse(http.StatusUnauthorized, "未授权: 无效的指标访问令牌"))
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// ServerObserver 记录指定服务器的命令和日志指标
+type ServerObserver struct {
+	server string
+}
+
+// NewServerObserver 创建服务器指标观察者
+func NewServerObserver(server string) *ServerObserver {
+	return &ServerObserver{server: server}
+}
+
+// ObserveCommand 记录一次命令执行
+func (o *ServerObserver) ObserveCommand(executorType mccontrol.ExecutorType, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	commandsTotal.WithLabelValues(o.server, string(executorType), result).Inc()
+	commandDuration.WithLabelValues(o.server, string(executorType)).Observe(duration.Seconds())
+}
+
+// ObserveLogReconnect 记录一次流式日志重连
+func (o *ServerObserver) ObserveLogReconnect() {
+	logStreamReconnects.WithLabelValues(o.server).Inc()
+}
+
+// collector 在采集时读取服务器状态和连接数量
+type collector struct {
+	source ControllerSource
+}
+
+var (
+	serverOnlineDesc = prometheus.NewDesc(namespace+"_server_online",
+		"服务器是否在线（1为在线）", []string{"server"}, nil)
+	serverPlayersDesc = prometheus.NewDesc(namespace+"_server_players",
+		"当前在线玩家数量", []string{"server"}, nil)
+	serverMaxPlayersDesc = prometheus.NewDesc(namespace+"_server_max_players",
+		"最大玩家数量", []string{"server"}, nil)
+	serverLatencyDesc = prometheus.NewDesc(namespace+"_server_latency_seconds",
+		"最近一次状态检测的延迟", []string{"server"}, nil)
+	serverLastCheckedDesc = prometheus.NewDesc(namespace+"_server_last_checked_timestamp_seconds",
+		"最近一次状态检测的时间", []string{"server"}, nil)
+	commandSessionsDesc = prometheus.NewDesc(namespace+"_command_sessions",
+		"活跃的命令会话数量", []string{"server"}, nil)
+	websocketClientsDesc = prometheus.NewDesc(namespace+"_websocket_clients",
+		"已连接的WebSocket客户端数量", nil, nil)
+	sseClientsDesc = prometheus.NewDesc(namespace+"_sse_clients",
+		"已连接的SSE客户端数量", nil, nil)
+)
+
+// Describe 实现prometheus.Collector接口
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serverOnlineDesc
+	ch <- serverPlayersDesc
+	ch <- serverMaxPlayersDesc
+	ch <- serverLatencyDesc
+	ch <- serverLastCheckedDesc
+	ch <- commandSessionsDesc
+	ch <- websocketClientsDesc
+	ch <- sseClientsDesc
+}
+
+// Collect 实现prometheus.Collector接口
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for name, controller := range c.source.Controllers() {
+		status := controller.LastStatus()
+
+		online := 0.0
+		if status.Online {
+			online = 1
+		}
+		ch <- prometheus.MustNewConstMetric(serverOnlineDesc, prometheus.GaugeValue, online, name)
+		ch <- prometheus.MustNewConstMetric(serverPlayersDesc, prometheus.GaugeValue, float64(status.Players), name)
+		ch <- prometheus.MustNewConstMetric(serverMaxPlayersDesc, prometheus.GaugeValue, float64(status.MaxPlayers), name)
+		ch <- prometheus.MustNewConstMetric(serverLatencyDesc, prometheus.GaugeValue, float64(status.Latency)/1000, name)
+		if !status.LastChecked.IsZero() {
+			ch <- prometheus.MustNewConstMetric(serverLastCheckedDesc, prometheus.GaugeValue, float64(status.LastChecked.Unix()), name)
+		}
+		ch <- prometheus.MustNewConstMetric(commandSessionsDesc, prometheus.GaugeValue, float64(len(controller.ListCommandSessions())), name)
+	}
+
+	ch <- prometheus.MustNewConstMetric(websocketClientsDesc, prometheus.GaugeValue, float64(websocket.GlobalManager.GetClientCount()))
+	ch <- prometheus.MustNewConstMetric(sseClientsDesc, prometheus.GaugeValue, float64(sse.GlobalBroker.GetClientCount()))
+}
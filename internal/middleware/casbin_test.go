@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// useTestEnforcer 使用内存中的HTTP权限策略替换全局的权限系统
+func useTestEnforcer(t *testing.T, policies ...[]string) *casbin.Enforcer {
+	t.Helper()
+
+	e, err := casbin.NewEnforcer("../../config/rbac_model.conf")
+	if err != nil {
+		t.Fatalf("创建权限策略失败: %v", err)
+	}
+	for _, policy := range policies {
+		if _, err := e.AddPolicy(policy[0], policy[1], policy[2]); err != nil {
+			t.Fatalf("添加权限策略失败: %v", err)
+		}
+	}
+
+	previous := enforcer
+	enforcer = e
+	t.Cleanup(func() { enforcer = previous })
+	return e
+}
+
+func TestCanAccessServer(t *testing.T) {
+	e := useTestEnforcer(t,
+		[]string{"admin", "*", "*"},
+		[]string{"user", "/api/v1/servers", "GET"},
+		[]string{"player", ServerResource("survival"), "GET"},
+		[]string{"player", ServerPath("survival"), "GET"},
+		// 带:name通配符的策略不代表被授权了服务器
+		[]string{"viewer", "/api/v1/servers/:name/status", "GET"},
+		// 通配符授权可以访问所有服务器的接口，也能看到所有服务器
+		[]string{"operator", "/api/v1/servers/*", "*"},
+	)
+	if _, err := e.AddRoleForUser("moderator", "player"); err != nil {
+		t.Fatalf("添加角色继承失败: %v", err)
+	}
+
+	tests := []struct {
+		role   string
+		server string
+		access bool
+	}{
+		{"admin", "survival", true},
+		{"admin", "creative", true},
+		{"user", "survival", false},
+		{"player", "survival", true},
+		{"player", "creative", false},
+		{"moderator", "survival", true},
+		{"viewer", "survival", false},
+		{"operator", "survival", true},
+		{"operator", "creative", true},
+	}
+	for _, test := range tests {
+		if access := CanAccessServer(test.role, test.server); access != test.access {
+			t.Errorf("CanAccessServer(%q, %q) = %v, 期望 %v", test.role, test.server, access, test.access)
+		}
+	}
+}
+
+func TestServerGrantCoversServerPath(t *testing.T) {
+	e := useTestEnforcer(t,
+		[]string{"player", ServerResource("survival"), "*"},
+		[]string{"player", ServerPath("survival"), "GET"},
+	)
+
+	tests := []struct {
+		path    string
+		method  string
+		allowed bool
+	}{
+		{"/api/v1/servers/survival", "GET", true},
+		{"/api/v1/servers/survival", "PUT", false},
+		{"/api/v1/servers/survival", "DELETE", false},
+		{"/api/v1/servers/survival/status", "GET", true},
+		{"/api/v1/servers/survival/command", "POST", true},
+		{"/api/v1/servers/creative", "GET", false},
+		{"/api/v1/servers/creative/status", "GET", false},
+	}
+	for _, test := range tests {
+		allowed, err := e.Enforce("player", test.path, test.method)
+		if err != nil {
+			t.Fatalf("权限检查失败: %v", err)
+		}
+		if allowed != test.allowed {
+			t.Errorf("%s %s = %v, 期望 %v", test.method, test.path, allowed, test.allowed)
+		}
+	}
+}
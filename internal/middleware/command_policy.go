@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/casbin/casbin/v2"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+
+	"city.newnan/k8s-console/internal/db"
+)
+
+// 命令策略效果
+const (
+	CommandEffectAllow = "allow"
+	CommandEffectDeny  = "deny"
+)
+
+// commandPolicyTable 命令策略表名，与HTTP路径策略分开存储
+const commandPolicyTable = "casbin_command_rule"
+
+var (
+	commandEnforcer *casbin.Enforcer
+
+	// commandPatterns 已编译的命令模式缓存
+	commandPatterns sync.Map
+)
+
+// InitCommandPolicy 初始化命令权限策略
+// 策略格式为 (角色, 服务器, 命令模式, allow/deny)，服务器为*时对所有服务器生效
+func InitCommandPolicy(modelPath string) error {
+	adapter, err := gormadapter.NewAdapterByDBUseTableName(db.DB, "", commandPolicyTable)
+	if err != nil {
+		return err
+	}
+
+	commandEnforcer, err = casbin.NewEnforcer(modelPath, adapter)
+	if err != nil {
+		return err
+	}
+	addCommandMatchFunction(commandEnforcer)
+
+	return commandEnforcer.LoadPolicy()
+}
+
+// addCommandMatchFunction 注册命令策略模型使用的commandMatch函数
+func addCommandMatchFunction(enforcer *casbin.Enforcer) {
+	enforcer.AddFunction("commandMatch", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, errors.New("commandMatch需要2个参数")
+		}
+		command, _ := args[0].(string)
+		pattern, _ := args[1].(string)
+		return CommandMatch(command, pattern), nil
+	})
+}
+
+// GetCommandPolicies 获取角色的所有命令策略
+func GetCommandPolicies(role string) ([][]string, error) {
+	if commandEnforcer == nil {
+		return nil, errors.New("命令权限系统未初始化")
+	}
+	return commandEnforcer.GetFilteredPolicy(0, role)
+}
+
+// AddCommandPolicy 添加命令策略
+func AddCommandPolicy(role, server, pattern, effect string) (bool, error) {
+	if commandEnforcer == nil {
+		return false, errors.New("命令权限系统未初始化")
+	}
+	return commandEnforcer.AddPolicy(role, server, NormalizeCommand(pattern), effect)
+}
+
+// RemoveCommandPolicy 移除命令策略
+func RemoveCommandPolicy(role, server, pattern, effect string) (bool, error) {
+	if commandEnforcer == nil {
+		return false, errors.New("命令权限系统未初始化")
+	}
+	return commandEnforcer.RemovePolicy(role, server, NormalizeCommand(pattern), effect)
+}
+
+// CanRunCommand 检查角色是否可以在指定服务器上执行命令内容
+// 包含控制字符的命令总是被拒绝；命令必须匹配允许策略且不匹配任何拒绝策略，
+// 角色没有任何命令策略时不能执行命令，不限制命令内容需要添加命令模式为*的允许策略。
+// execute命令中通过run嵌套的子命令也会逐一检查。
+func CanRunCommand(role, server, command string) (bool, error) {
+	if commandEnforcer == nil {
+		return false, errors.New("命令权限系统未初始化")
+	}
+	// 换行后的内容会被服务器当作另一条控制台命令执行，无论命令策略如何都拒绝
+	if !ValidCommand(command) {
+		return false, nil
+	}
+
+	for _, cmd := range expandCommand(command) {
+		ok, err := commandEnforcer.Enforce(role, server, cmd)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ValidCommand 检查命令是否不含换行等控制字符
+// attach和exec执行器将命令原样写入服务器的标准输入，换行符之后的内容会作为另一条命令执行，
+// 而NormalizeCommand会把换行当作空白合并，绕过命令策略
+func ValidCommand(command string) bool {
+	for _, r := range command {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeCommand 规范化命令：去掉开头的斜杠和命令的命名空间，合并多余的空白，命令名称转为小写
+// 插件命令可以加上插件名作为命名空间执行（如bukkit:op、essentials:ban），去掉命名空间后按同一命令检查
+func NormalizeCommand(command string) string {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(command), "/"))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.ToLower(fields[0])
+	if index := strings.LastIndex(name, ":"); index >= 0 {
+		name = name[index+1:]
+	}
+	fields[0] = name
+	return strings.Join(fields, " ")
+}
+
+// CommandMatch 检查命令是否匹配命令模式
+// 匹配不区分大小写，模式中的*匹配任意字符；以" *"结尾的模式同时匹配不带参数的命令，
+// 例如"kick *"匹配"kick"和"kick Steve 理由"，"*"匹配所有命令
+func CommandMatch(command, pattern string) bool {
+	command = NormalizeCommand(command)
+	pattern = NormalizeCommand(pattern)
+	if pattern == "*" {
+		return true
+	}
+
+	var re *regexp.Regexp
+	if cached, ok := commandPatterns.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		expr := pattern
+		optionalArgs := strings.HasSuffix(expr, " *")
+		if optionalArgs {
+			expr = strings.TrimSuffix(expr, " *")
+		}
+		expr = strings.ReplaceAll(regexp.QuoteMeta(expr), `\*`, `.*`)
+		if optionalArgs {
+			expr += `( .*)?`
+		}
+		re = regexp.MustCompile(`(?i)^` + expr + `$`)
+		commandPatterns.Store(pattern, re)
+	}
+
+	return re.MatchString(command)
+}
+
+// expandCommand 返回需要检查的命令列表，包括execute命令中run之后的子命令
+func expandCommand(command string) []string {
+	command = NormalizeCommand(command)
+	commands := []string{command}
+
+	for strings.HasPrefix(command, "execute ") {
+		index := strings.Index(command, " run ")
+		if index < 0 {
+			break
+		}
+		command = NormalizeCommand(command[index+len(" run "):])
+		commands = append(commands, command)
+	}
+	return commands
+}
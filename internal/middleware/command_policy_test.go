@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// useTestCommandPolicy 使用内存中的命令策略替换全局的命令权限系统
+func useTestCommandPolicy(t *testing.T, policies ...[]string) {
+	t.Helper()
+
+	enforcer, err := casbin.NewEnforcer("../../config/command_model.conf")
+	if err != nil {
+		t.Fatalf("创建命令策略失败: %v", err)
+	}
+	addCommandMatchFunction(enforcer)
+	for _, policy := range policies {
+		if _, err := enforcer.AddPolicy(policy[0], policy[1], NormalizeCommand(policy[2]), policy[3]); err != nil {
+			t.Fatalf("添加命令策略失败: %v", err)
+		}
+	}
+
+	previous := commandEnforcer
+	commandEnforcer = enforcer
+	t.Cleanup(func() { commandEnforcer = previous })
+}
+
+func TestValidCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		valid   bool
+	}{
+		{"say hi", true},
+		{"/tellraw @a {\"text\":\"你好\"}", true},
+		{"say hi\nop attacker", false},
+		{"say hi\rop attacker", false},
+		{"say hi\r\nop attacker", false},
+		{"say hi\top attacker", false},
+		{"say hi\x00", false},
+		{"say hi\u0085op attacker", false},
+	}
+	for _, test := range tests {
+		if valid := ValidCommand(test.command); valid != test.valid {
+			t.Errorf("ValidCommand(%q) = %v, 期望 %v", test.command, valid, test.valid)
+		}
+	}
+}
+
+func TestCanRunCommandRejectsNewlines(t *testing.T) {
+	useTestCommandPolicy(t,
+		[]string{"player", "*", "say *", CommandEffectAllow},
+		[]string{"player", "*", "execute *", CommandEffectAllow},
+		[]string{"admin", "*", "*", CommandEffectAllow},
+	)
+
+	tests := []struct {
+		role    string
+		command string
+		allowed bool
+	}{
+		{"player", "say hi", true},
+		{"player", "op attacker", false},
+		// 换行后的内容会作为第二条控制台命令执行
+		{"player", "say hi\nop attacker", false},
+		{"player", "say hi\rop attacker", false},
+		{"player", "say hi\r\nop attacker", false},
+		{"player", "execute as @a run say hi\nop attacker", false},
+		// 允许所有命令的角色仍拒绝控制字符
+		{"admin", "op attacker", true},
+		{"admin", "say hi\nop attacker", false},
+		// 没有命令策略的角色不能执行命令
+		{"guest", "say hi", false},
+		{"guest", "list", false},
+	}
+	for _, test := range tests {
+		allowed, err := CanRunCommand(test.role, "survival", test.command)
+		if err != nil {
+			t.Fatalf("CanRunCommand(%q, %q) 失败: %v", test.role, test.command, err)
+		}
+		if allowed != test.allowed {
+			t.Errorf("CanRunCommand(%q, %q) = %v, 期望 %v", test.role, test.command, allowed, test.allowed)
+		}
+	}
+}
+
+func TestNormalizeCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"  /Say   hi  there ", "say hi there"},
+		{"minecraft:op Steve", "op Steve"},
+		{"/bukkit:OP Steve", "op Steve"},
+		{"paper:stop", "stop"},
+		{"essentials:ban Steve 理由", "ban Steve 理由"},
+		{"a:b:op Steve", "op Steve"},
+		// 只去掉命令名称的命名空间，参数保持不变
+		{"give Steve minecraft:diamond", "give Steve minecraft:diamond"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := NormalizeCommand(test.command); got != test.want {
+			t.Errorf("NormalizeCommand(%q) = %q, 期望 %q", test.command, got, test.want)
+		}
+	}
+}
+
+func TestCanRunCommandNamespaces(t *testing.T) {
+	useTestCommandPolicy(t,
+		[]string{"moderator", "*", "*", CommandEffectAllow},
+		[]string{"moderator", "*", "op *", CommandEffectDeny},
+		[]string{"moderator", "*", "stop", CommandEffectDeny},
+		[]string{"moderator", "*", "ban *", CommandEffectDeny},
+		[]string{"player", "*", "say *", CommandEffectAllow},
+	)
+
+	tests := []struct {
+		role    string
+		command string
+		allowed bool
+	}{
+		// 加上命名空间不能绕过拒绝策略
+		{"moderator", "op Steve", false},
+		{"moderator", "bukkit:op Steve", false},
+		{"moderator", "/minecraft:op Steve", false},
+		{"moderator", "paper:stop", false},
+		{"moderator", "essentials:ban Steve", false},
+		{"moderator", "execute as @a run bukkit:op Steve", false},
+		{"moderator", "essentials:kick Steve", true},
+		// 允许策略同样匹配带命名空间的命令
+		{"player", "minecraft:say hi", true},
+		{"player", "essentials:say hi", true},
+		{"player", "bukkit:op Steve", false},
+	}
+	for _, test := range tests {
+		allowed, err := CanRunCommand(test.role, "survival", test.command)
+		if err != nil {
+			t.Fatalf("CanRunCommand(%q, %q) 失败: %v", test.role, test.command, err)
+		}
+		if allowed != test.allowed {
+			t.Errorf("CanRunCommand(%q, %q) = %v, 期望 %v", test.role, test.command, allowed, test.allowed)
+		}
+	}
+}
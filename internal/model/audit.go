@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// 命令来源
+const (
+	CommandSourceHTTP      = "http"      // REST命令接口
+	CommandSourceSession   = "session"   // REST命令会话接口
+	CommandSourceWebSocket = "websocket" // WebSocket控制台
+	CommandSourceScript    = "script"    // REST脚本接口
+	CommandSourceSchedule  = "schedule"  // 定时任务
+	CommandSourceBroadcast = "broadcast" // REST广播命令接口，每个Pod一条记录
+	CommandSourceRestart   = "restart"   // 重启服务器，包括重启过程中发送的提醒、save-all flush和stop命令
+	CommandSourceScale     = "scale"     // 手动唤醒或缩容服务器
+	CommandSourcePod       = "pod"       // 固定或取消固定服务器使用的Pod
+)
+
+// CommandAudit 命令审计记录，每条发送到服务器的命令对应一条记录
+type CommandAudit struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+	UserID       uint      `gorm:"index" json:"user_id"`
+	Username     string    `gorm:"size:50" json:"username"`
+	ServerName   string    `gorm:"size:50;index" json:"server_name"`
+	Source       string    `gorm:"size:20" json:"source"`        // 命令来源
+	SessionID    string    `gorm:"size:50" json:"session_id"`    // 命令会话ID，一次性执行时为空
+	PodName      string    `gorm:"size:100" json:"pod_name"`     // 固定在指定Pod上执行时的Pod名称
+	ExecutorType string    `gorm:"size:20" json:"executor_type"` // 实际使用的执行器类型
+	Command      string    `gorm:"size:1000;not null" json:"command"`
+	Response     string    `gorm:"size:2000" json:"response"` // 响应摘要
+	DurationMs   int64     `json:"duration_ms"`               // 执行耗时（毫秒）
+	Success      bool      `gorm:"index" json:"success"`
+	Error        string    `gorm:"size:500" json:"error"`
+}
+
+// CommandAuditQuery 命令审计记录查询条件
+type CommandAuditQuery struct {
+	UserID       uint
+	Username     string
+	ServerName   string
+	Source       string
+	ExecutorType string
+	Success      *bool
+	Keyword      string // 命令内容关键词
+	From         time.Time
+	To           time.Time
+}
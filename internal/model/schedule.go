@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+)
+
+// 定时任务执行状态
+const (
+	ScheduleRunSuccess = "success" // 所有步骤都执行成功
+	ScheduleRunFailed  = "failed"  // 有步骤失败或无法执行
+	ScheduleRunSkipped = "skipped" // 服务器的定时任务已暂停，未执行
+)
+
+// 定时任务触发方式
+const (
+	ScheduleTriggerCron   = "cron"   // 按cron表达式触发
+	ScheduleTriggerManual = "manual" // 通过API手动触发
+)
+
+// ScheduledTask 定时任务，按cron表达式在服务器上执行一组命令
+// 按cron触发时命令以创建或最后修改任务的用户的身份执行，执行时按该用户当前的角色检查命令权限；
+// 手动执行时以触发执行的用户的身份执行
+type ScheduledTask struct {
+	ID         uint                `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	Name       string              `gorm:"size:100;not null" json:"name"`
+	ServerName string              `gorm:"size:50;not null;index" json:"server_name"`
+	Cron       string              `gorm:"size:100;not null" json:"cron"` // cron表达式，如"0 4 * * *"、"@every 30m"
+	Steps      []ScriptStepRequest `gorm:"serializer:json;type:text" json:"steps"`
+	Variables  map[string]string   `gorm:"serializer:json;type:text" json:"variables"`
+	OnError    string              `gorm:"size:20" json:"on_error"` // 步骤失败后的默认处理方式，为空则停止执行
+	Enabled    bool                `json:"enabled"`
+	UserID     uint                `gorm:"index" json:"user_id"` // 执行命令的用户
+	Username   string              `gorm:"size:50" json:"username"`
+	LastRunAt  *time.Time          `json:"last_run_at"`
+	LastStatus string              `gorm:"size:20" json:"last_status"`
+	NextRunAt  *time.Time          `gorm:"-" json:"next_run_at"` // 下次执行时间，任务停用或服务器暂停时为空
+}
+
+// ScheduledTaskRun 定时任务的一次执行记录
+type ScheduledTaskRun struct {
+	ID         uint                 `gorm:"primarykey" json:"id"`
+	TaskID     uint                 `gorm:"not null;index:idx_schedule_run_task_time" json:"task_id"`
+	ServerName string               `gorm:"size:50;not null" json:"server_name"`
+	StartedAt  time.Time            `gorm:"not null;index:idx_schedule_run_task_time;index" json:"started_at"`
+	DurationMs int64                `json:"duration_ms"`
+	Trigger    string               `gorm:"size:20" json:"trigger"`  // 见ScheduleTrigger*
+	Username   string               `gorm:"size:50" json:"username"` // 执行命令的用户，cron触发时为任务用户，手动执行时为触发执行的用户
+	Status     string               `gorm:"size:20" json:"status"`   // 见ScheduleRun*
+	Error      string               `gorm:"size:500" json:"error"`
+	Steps      []ScriptStepResponse `gorm:"serializer:json;type:text" json:"steps"`
+}
+
+// ScheduledTaskCreate 创建定时任务请求
+type ScheduledTaskCreate struct {
+	Name       string              `json:"name" binding:"required,max=100"`
+	ServerName string              `json:"server_name" binding:"required"`
+	Cron       string              `json:"cron" binding:"required,max=100"`
+	Steps      []ScriptStepRequest `json:"steps" binding:"required,min=1,max=100,dive"`
+	Variables  map[string]string   `json:"variables"`
+	OnError    string              `json:"on_error" binding:"omitempty,oneof=stop continue"`
+	Enabled    *bool               `json:"enabled"` // 为空则启用
+}
+
+// ScheduledTaskUpdate 更新定时任务请求，空值字段保持不变
+type ScheduledTaskUpdate struct {
+	Name      string              `json:"name" binding:"max=100"`
+	Cron      string              `json:"cron" binding:"max=100"`
+	Steps     []ScriptStepRequest `json:"steps" binding:"omitempty,max=100,dive"`
+	Variables map[string]string   `json:"variables"`
+	OnError   string              `json:"on_error" binding:"omitempty,oneof=stop continue"`
+	Enabled   *bool               `json:"enabled"`
+}
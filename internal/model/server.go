@@ -0,0 +1,194 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Server Minecraft服务器注册信息
+type Server struct {
+	gorm.Model
+	Name        string `gorm:"size:50;not null;uniqueIndex" json:"name"`
+	Description string `gorm:"size:200" json:"description"`
+
+	// Kubernetes配置
+	RunMode              string `gorm:"size:20" json:"run_mode"`
+	KubeconfigPath       string `gorm:"size:200" json:"kubeconfig_path"`
+	Namespace            string `gorm:"size:100;not null" json:"namespace"`
+	PodLabelSelector     string `gorm:"size:200;not null" json:"pod_label_selector"`
+	ServiceLabelSelector string `gorm:"size:200" json:"service_label_selector"`
+	ContainerName        string `gorm:"size:100" json:"container_name"`
+
+	// 端口与RCON配置
+	GamePort       int    `json:"game_port"`
+	RconPort       int    `json:"rcon_port"`
+	RconPassword   string `gorm:"size:200" json:"-"`
+	RconSecretName string `gorm:"size:100" json:"rcon_secret_name"`
+	RconSecretKey  string `gorm:"size:100" json:"rcon_secret_key"`
+	// 从容器环境变量或server.properties读取RCON密码
+	RconPasswordEnv    string `gorm:"size:100" json:"rcon_password_env"`
+	RconPropertiesPath string `gorm:"size:200" json:"rcon_properties_path"`
+
+	// 定时任务
+	SchedulesPaused bool `json:"schedules_paused"` // 暂停该服务器的所有定时任务
+
+	// 闲置缩容
+	IdleTimeout int `json:"idle_timeout"` // 没有玩家多少分钟后将所属的StatefulSet/Deployment缩容到0，为0则不缩容
+}
+
+// ServerCreate 创建服务器请求
+type ServerCreate struct {
+	Name                 string `json:"name" binding:"required,max=50"`
+	Description          string `json:"description" binding:"max=200"`
+	RunMode              string `json:"run_mode" binding:"omitempty,oneof=InCluster OutOfCluster"`
+	KubeconfigPath       string `json:"kubeconfig_path"`
+	Namespace            string `json:"namespace" binding:"required"`
+	PodLabelSelector     string `json:"pod_label_selector" binding:"required"`
+	ServiceLabelSelector string `json:"service_label_selector"`
+	ContainerName        string `json:"container_name"`
+	GamePort             int    `json:"game_port" binding:"omitempty,min=1,max=65535"`
+	RconPort             int    `json:"rcon_port" binding:"omitempty,min=1,max=65535"`
+	RconPassword         string `json:"rcon_password"`
+	RconSecretName       string `json:"rcon_secret_name"`
+	RconSecretKey        string `json:"rcon_secret_key"`
+	RconPasswordEnv      string `json:"rcon_password_env"`
+	RconPropertiesPath   string `json:"rcon_properties_path"`
+	IdleTimeout          int    `json:"idle_timeout" binding:"omitempty,min=0,max=10080"` // 单位：分钟，为0则不缩容
+}
+
+// ServerUpdate 更新服务器请求，空值字段保持不变
+type ServerUpdate struct {
+	Description          string `json:"description" binding:"max=200"`
+	RunMode              string `json:"run_mode" binding:"omitempty,oneof=InCluster OutOfCluster"`
+	KubeconfigPath       string `json:"kubeconfig_path"`
+	Namespace            string `json:"namespace"`
+	PodLabelSelector     string `json:"pod_label_selector"`
+	ServiceLabelSelector string `json:"service_label_selector"`
+	ContainerName        string `json:"container_name"`
+	GamePort             int    `json:"game_port" binding:"omitempty,min=1,max=65535"`
+	RconPort             int    `json:"rcon_port" binding:"omitempty,min=1,max=65535"`
+	RconPassword         string `json:"rcon_password"`
+	RconSecretName       string `json:"rcon_secret_name"`
+	RconSecretKey        string `json:"rcon_secret_key"`
+	RconPasswordEnv      string `json:"rcon_password_env"`
+	RconPropertiesPath   string `json:"rcon_properties_path"`
+	IdleTimeout          *int   `json:"idle_timeout" binding:"omitempty,min=0,max=10080"` // 单位：分钟，为0则不缩容
+}
+
+// WakeRequest 唤醒服务器请求
+type WakeRequest struct {
+	Timeout int `json:"timeout" binding:"omitempty,min=0,max=1800"` // 等待服务器可用的时间，单位：秒，为0则使用默认值10分钟
+}
+
+// ServerPermission 服务器访问授权请求
+type ServerPermission struct {
+	Server string `json:"server" binding:"required"`
+	Method string `json:"method"` // 为空则允许所有方法
+}
+
+// CommandPermission 命令权限策略
+type CommandPermission struct {
+	Server  string `json:"server"`                                      // 服务器名称，为空或*表示所有服务器
+	Command string `json:"command" binding:"required"`                  // 命令模式，如"kick *"
+	Effect  string `json:"effect" binding:"omitempty,oneof=allow deny"` // 为空则为allow
+}
+
+// CommandRequest 执行命令请求
+type CommandRequest struct {
+	Command string `json:"command" binding:"required"`
+}
+
+// CommandResult 命令执行结果
+type CommandResult struct {
+	Command      string `json:"command"`
+	Response     string `json:"response"`
+	ExecutorType string `json:"executor_type,omitempty"` // 实际使用的执行器类型
+	BestEffort   bool   `json:"best_effort"`             // 响应按时间窗口从服务器输出中收集（attach/exec），可能混入其他日志或缺少部分输出
+}
+
+// BroadcastRequest 在所有Pod上执行命令请求
+type BroadcastRequest struct {
+	Command      string `json:"command" binding:"required"`
+	ExecutorType string `json:"executor_type" binding:"omitempty,oneof=auto rcon attach exec"` // 为空则自动选择
+}
+
+// BroadcastResponse 在所有Pod上执行命令的结果
+type BroadcastResponse struct {
+	Command string               `json:"command"`
+	Success bool                 `json:"success"` // 所有Pod都执行成功
+	Results []PodCommandResponse `json:"results"`
+}
+
+// PodCommandResponse 命令在一个Pod上的执行结果
+type PodCommandResponse struct {
+	Pod          string `json:"pod"`
+	Success      bool   `json:"success"`
+	Response     string `json:"response,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ExecutorType string `json:"executor_type,omitempty"`
+	BestEffort   bool   `json:"best_effort"` // 响应按时间窗口从服务器输出中收集（attach/exec），可能混入其他日志或缺少部分输出
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+// PodPinRequest 固定服务器使用的Pod请求
+type PodPinRequest struct {
+	Pod string `json:"pod" binding:"required"` // Pod名称，必须匹配服务器的Pod标签选择器
+}
+
+// ScriptRequest 执行命令脚本请求
+type ScriptRequest struct {
+	Steps        []ScriptStepRequest `json:"steps" binding:"required,min=1,max=100,dive"`
+	Variables    map[string]string   `json:"variables"`                                                     // 命令中${name}引用的变量
+	OnError      string              `json:"on_error" binding:"omitempty,oneof=stop continue"`              // 步骤失败后的默认处理方式，为空则停止执行
+	ExecutorType string              `json:"executor_type" binding:"omitempty,oneof=auto rcon attach exec"` // 为空则自动选择
+	DryRun       bool                `json:"dry_run"`                                                       // 只校验脚本和命令权限，不执行
+}
+
+// ScriptStepRequest 脚本中的一个步骤
+type ScriptStepRequest struct {
+	Command string `json:"command" binding:"required"`
+	Delay   int    `json:"delay" binding:"omitempty,min=0,max=600000"` // 执行前等待的时间，单位：毫秒，所有步骤之和不能超过30分钟
+	OnError string `json:"on_error" binding:"omitempty,oneof=stop continue"`
+}
+
+// ScriptResponse 脚本执行结果
+type ScriptResponse struct {
+	DryRun     bool                 `json:"dry_run"`
+	Success    bool                 `json:"success"` // 所有步骤都执行成功，试运行时表示所有命令都允许执行
+	DurationMs int64                `json:"duration_ms"`
+	Steps      []ScriptStepResponse `json:"steps"`
+}
+
+// ScriptStepResponse 脚本步骤的执行结果
+type ScriptStepResponse struct {
+	Index        int    `json:"index"`
+	Command      string `json:"command"` // 替换变量后的命令
+	Allowed      bool   `json:"allowed"` // 角色是否可以执行该命令
+	Skipped      bool   `json:"skipped"` // 之前的步骤失败或试运行，该步骤未执行
+	Response     string `json:"response,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ExecutorType string `json:"executor_type,omitempty"`
+	BestEffort   bool   `json:"best_effort"` // 响应按时间窗口从服务器输出中收集（attach/exec），可能混入其他日志或缺少部分输出
+	DurationMs   int64  `json:"duration_ms"`
+}
+
+// RestartRequest 重启服务器请求
+type RestartRequest struct {
+	Countdown int    `json:"countdown" binding:"omitempty,min=0,max=3600"`      // 重启前向玩家倒计时提醒的时间，单位：秒，为0则立即重启
+	Reason    string `json:"reason" binding:"max=200"`                          // 重启原因，附加在提醒消息后，不能包含换行等控制字符
+	Mode      string `json:"mode" binding:"omitempty,oneof=delete_pod rollout"` // 删除Pod或滚动重启所属的StatefulSet/Deployment，为空则删除Pod
+	Force     bool   `json:"force"`                                             // 保存世界失败时是否仍然重启
+}
+
+// SessionCreate 创建命令会话请求
+type SessionCreate struct {
+	ExecutorType string `json:"executor_type" binding:"omitempty,oneof=auto rcon attach exec"`
+	IdleTimeout  int    `json:"idle_timeout" binding:"omitempty,min=1"` // 空闲超时，单位：秒
+	Pod          string `json:"pod"`                                    // 固定使用的Pod名称，为空则跟随服务器当前的Pod
+}
+
+// SessionResponse 命令会话响应数据
+type SessionResponse struct {
+	SessionID    string `json:"session_id"`
+	ExecutorType string `json:"executor_type"`
+	Pod          string `json:"pod,omitempty"` // 固定使用的Pod名称
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// ServerStatusRecord 服务器状态历史记录
+// 原始记录对应一次状态检测，降采样后的记录汇总一个时间段内的多次检测
+type ServerStatusRecord struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	ServerName    string    `gorm:"size:50;not null;index:idx_status_server_time" json:"server_name"`
+	CheckedAt     time.Time `gorm:"not null;index:idx_status_server_time" json:"checked_at"`
+	Online        bool      `json:"online"`                                   // 是否在线，降采样记录中表示时间段内是否曾经在线
+	Players       float64   `json:"players"`                                  // 在线玩家数量，降采样记录中为平均值
+	PeakPlayers   int       `json:"peak_players"`                             // 峰值在线玩家数量
+	MaxPlayers    int       `json:"max_players"`                              // 最大玩家数量
+	Latency       float64   `json:"latency"`                                  // 延迟（毫秒），降采样记录中为在线时的平均值
+	PodName       string    `gorm:"size:100" json:"pod_name"`                 // Pod名称
+	PodPhase      string    `gorm:"size:20" json:"pod_phase"`                 // Pod状态
+	Error         string    `gorm:"size:500" json:"error"`                    // 错误信息
+	Samples       int       `gorm:"not null;default:1" json:"samples"`        // 包含的检测次数
+	OnlineSamples int       `gorm:"not null;default:0" json:"online_samples"` // 其中在线的检测次数
+	Downsampled   bool      `gorm:"index" json:"downsampled"`                 // 是否为降采样记录
+}
+
+// StatusPoint 状态时间序列中的一个数据点
+type StatusPoint struct {
+	Time        time.Time `json:"time"`         // 时间段起点
+	Samples     int       `json:"samples"`      // 检测次数
+	Uptime      float64   `json:"uptime"`       // 在线率（百分比）
+	Players     float64   `json:"players"`      // 平均在线玩家数量
+	PeakPlayers int       `json:"peak_players"` // 峰值在线玩家数量
+	MaxPlayers  int       `json:"max_players"`  // 最大玩家数量
+	Latency     float64   `json:"latency"`      // 平均延迟（毫秒）
+}
+
+// StatusOutage 一次离线时段
+type StatusOutage struct {
+	Start time.Time `json:"start"`           // 首次检测到离线的时间
+	End   time.Time `json:"end"`             // 恢复在线的时间，仍离线则为最后一次检测时间
+	Error string    `json:"error,omitempty"` // 离线期间的错误信息
+}
+
+// UptimeResponse 在线率统计结果
+type UptimeResponse struct {
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	Samples       int            `json:"samples"`        // 检测次数
+	OnlineSamples int            `json:"online_samples"` // 在线的检测次数
+	Uptime        float64        `json:"uptime"`         // 在线率（百分比），没有检测记录时为0
+	Outages       []StatusOutage `json:"outages"`        // 离线时段
+}
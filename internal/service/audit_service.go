@@ -0,0 +1,187 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/model"
+)
+
+// 审计记录导出格式
+const (
+	AuditExportCSV   = "csv"
+	AuditExportJSONL = "jsonl"
+)
+
+// ErrUnsupportedExportFormat 不支持的导出格式
+var ErrUnsupportedExportFormat = errors.New("不支持的导出格式，可选值为 csv 或 jsonl")
+
+// 审计记录中命令和响应的最大长度，与数据库字段长度一致
+const (
+	auditCommandLimit  = 1000
+	auditResponseLimit = 2000
+	auditErrorLimit    = 500
+)
+
+// auditExportBatchSize 导出时每批读取的记录数量
+const auditExportBatchSize = 500
+
+// AuditService 提供命令审计相关功能
+type AuditService struct {
+	Config *config.Config
+}
+
+// NewAuditService 创建审计服务实例
+func NewAuditService(cfg *config.Config) *AuditService {
+	return &AuditService{
+		Config: cfg,
+	}
+}
+
+// Record 记录一条命令审计，写入失败只记录日志，不影响命令执行
+func (s *AuditService) Record(audit model.CommandAudit) {
+	audit.Command = truncateString(audit.Command, auditCommandLimit)
+	audit.Response = truncateString(audit.Response, auditResponseLimit)
+	audit.Error = truncateString(audit.Error, auditErrorLimit)
+
+	if err := db.DB.Create(&audit).Error; err != nil {
+		log.Printf("记录命令审计失败: %v", err)
+	}
+}
+
+// ListAudits 按条件分页获取审计记录，按时间倒序排列
+func (s *AuditService) ListAudits(query model.CommandAuditQuery, page, pageSize int) ([]model.CommandAudit, int64, error) {
+	var audits []model.CommandAudit
+	var total int64
+
+	tx := s.buildQuery(query)
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&audits).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return audits, total, nil
+}
+
+// ExportAudits 按条件导出审计记录，分批读取数据库，避免一次性加载全部记录
+func (s *AuditService) ExportAudits(query model.CommandAuditQuery, format string, w io.Writer) error {
+	var writeBatch func([]model.CommandAudit) error
+	var flush func() error
+
+	switch format {
+	case AuditExportCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{
+			"id", "time", "user_id", "username", "server", "source", "session_id",
+			"pod", "executor", "command", "response", "duration_ms", "success", "error",
+		}); err != nil {
+			return err
+		}
+		writeBatch = func(audits []model.CommandAudit) error {
+			for _, audit := range audits {
+				if err := writer.Write([]string{
+					strconv.FormatUint(uint64(audit.ID), 10),
+					audit.CreatedAt.Format(time.RFC3339),
+					strconv.FormatUint(uint64(audit.UserID), 10),
+					csvCell(audit.Username),
+					csvCell(audit.ServerName),
+					csvCell(audit.Source),
+					csvCell(audit.SessionID),
+					csvCell(audit.PodName),
+					csvCell(audit.ExecutorType),
+					csvCell(audit.Command),
+					csvCell(audit.Response),
+					strconv.FormatInt(audit.DurationMs, 10),
+					strconv.FormatBool(audit.Success),
+					csvCell(audit.Error),
+				}); err != nil {
+					return err
+				}
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case AuditExportJSONL:
+		encoder := json.NewEncoder(w)
+		writeBatch = func(audits []model.CommandAudit) error {
+			for _, audit := range audits {
+				if err := encoder.Encode(audit); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		flush = func() error { return nil }
+	default:
+		return ErrUnsupportedExportFormat
+	}
+
+	var audits []model.CommandAudit
+	err := s.buildQuery(query).Order("id").FindInBatches(&audits, auditExportBatchSize, func(tx *gorm.DB, batch int) error {
+		return writeBatch(audits)
+	}).Error
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// csvCell 转义可能被电子表格软件当作公式执行的单元格（CSV注入），在以=、+、-、@、制表符或回车开头的内容前加单引号
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// buildQuery 根据查询条件构建数据库查询
+func (s *AuditService) buildQuery(query model.CommandAuditQuery) *gorm.DB {
+	tx := db.DB.Model(&model.CommandAudit{})
+
+	if query.UserID != 0 {
+		tx = tx.Where("user_id = ?", query.UserID)
+	}
+	if query.Username != "" {
+		tx = tx.Where("username = ?", query.Username)
+	}
+	if query.ServerName != "" {
+		tx = tx.Where("server_name = ?", query.ServerName)
+	}
+	if query.Source != "" {
+		tx = tx.Where("source = ?", query.Source)
+	}
+	if query.ExecutorType != "" {
+		tx = tx.Where("executor_type = ?", query.ExecutorType)
+	}
+	if query.Success != nil {
+		tx = tx.Where("success = ?", *query.Success)
+	}
+	if query.Keyword != "" {
+		tx = tx.Where("command LIKE ?", "%"+query.Keyword+"%")
+	}
+	if !query.From.IsZero() {
+		tx = tx.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		tx = tx.Where("created_at < ?", query.To)
+	}
+
+	return tx
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/model"
+)
+
+func TestExportAuditsCSVEscapesFormulas(t *testing.T) {
+	setupTestDB(t)
+	audit := model.CommandAudit{
+		Username:   "@admin",
+		ServerName: "survival",
+		Source:     model.CommandSourceHTTP,
+		Command:    `=HYPERLINK("http://example.com","x")`,
+		Response:   "+1",
+		Success:    false,
+		Error:      "-error",
+	}
+	if err := db.DB.Create(&audit).Error; err != nil {
+		t.Fatalf("创建审计记录失败: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewAuditService(nil).ExportAudits(model.CommandAuditQuery{}, AuditExportCSV, &buf); err != nil {
+		t.Fatalf("导出审计记录失败: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("解析导出的CSV失败: %v %v", err, records)
+	}
+
+	row := make(map[string]string)
+	for i, column := range records[0] {
+		row[column] = records[1][i]
+	}
+	for column, want := range map[string]string{
+		"username": "'@admin",
+		"server":   "survival",
+		"command":  `'=HYPERLINK("http://example.com","x")`,
+		"response": "'+1",
+		"error":    "'-error",
+		"success":  "false",
+	} {
+		if row[column] != want {
+			t.Errorf("%s列应为 %q, 实际 %q", column, want, row[column])
+		}
+	}
+}
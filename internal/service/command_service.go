@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/middleware"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// ErrCommandDenied 角色的命令策略不允许执行该命令
+var ErrCommandDenied = errors.New("权限不足: 角色无权执行此命令")
+
+// ErrInvalidCommand 命令包含换行等控制字符
+var ErrInvalidCommand = errors.New("无效的命令: 不能包含换行等控制字符")
+
+// CommandActor 发起命令的用户
+type CommandActor struct {
+	UserID   uint
+	Username string
+	RoleName string
+	Source   string // 命令来源，见model.CommandSource*
+}
+
+// CommandService 向服务器发送命令并记录审计
+// 所有面向用户的命令入口都应通过此服务执行
+type CommandService struct {
+	Config   *config.Config
+	Registry *ServerRegistry
+	Audit    *AuditService
+}
+
+// NewCommandService 创建命令服务实例
+func NewCommandService(cfg *config.Config, registry *ServerRegistry) *CommandService {
+	return &CommandService{
+		Config:   cfg,
+		Registry: registry,
+		Audit:    NewAuditService(cfg),
+	}
+}
+
+// ExecuteCommand 使用自动选择的执行器执行一条命令，ctx取消时中止执行
+func (s *CommandService) ExecuteCommand(ctx context.Context, actor CommandActor, server, command string) (*mccontrol.CommandResult, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(actor, server, command); err != nil {
+		return nil, err
+	}
+
+	result, err := controller.ExecuteCommandDetailed(ctx, command)
+	s.record(actor, server, command, result, err)
+	return result, err
+}
+
+// SessionExecuteCommand 在指定的命令会话中执行一条命令，ctx取消时中止执行
+func (s *CommandService) SessionExecuteCommand(ctx context.Context, actor CommandActor, server, sessionID, command string) (*mccontrol.CommandResult, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(actor, server, command); err != nil {
+		return nil, err
+	}
+
+	result, err := controller.SessionExecuteCommandDetailed(ctx, sessionID, command)
+	s.record(actor, server, command, result, err)
+	return result, err
+}
+
+// BroadcastCommand 在服务器所有运行中的Pod上同时执行一条命令，每个Pod的结果分别记录审计
+func (s *CommandService) BroadcastCommand(ctx context.Context, actor CommandActor, server, command string, executorType mccontrol.ExecutorType) ([]mccontrol.PodCommandResult, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(actor, server, command); err != nil {
+		return nil, err
+	}
+
+	results, err := controller.BroadcastCommand(ctx, command, executorType)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		s.record(actor, server, command, &mccontrol.CommandResult{
+			Response:     result.Response,
+			ExecutorType: result.ExecutorType,
+			PodName:      result.Pod,
+			Duration:     result.Duration,
+		}, result.Err)
+	}
+	return results, nil
+}
+
+// ScriptCheck 脚本步骤的权限检查结果
+type ScriptCheck struct {
+	Command string // 替换变量后的命令
+	Allowed bool   // 角色是否可以执行该命令
+}
+
+// CheckScript 校验脚本并检查每个步骤的命令是否允许执行，不执行任何命令
+func (s *CommandService) CheckScript(actor CommandActor, server string, script mccontrol.Script) ([]ScriptCheck, error) {
+	commands, err := script.Commands()
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]ScriptCheck, len(commands))
+	for i, command := range commands {
+		ok, err := s.CanRunCommand(actor, server, command)
+		if err != nil {
+			return nil, err
+		}
+		checks[i] = ScriptCheck{Command: command, Allowed: ok}
+	}
+	return checks, nil
+}
+
+// ExecuteScript 在一个命令会话中按顺序执行脚本，每个步骤分别记录审计
+// 执行前检查所有步骤的命令策略，任一步骤被拒绝时不执行任何命令
+func (s *CommandService) ExecuteScript(ctx context.Context, actor CommandActor, server string, script mccontrol.Script) (*mccontrol.ScriptResult, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := script.Commands()
+	if err != nil {
+		return nil, err
+	}
+	for i, command := range commands {
+		if err := s.authorize(actor, server, command); err != nil {
+			return nil, fmt.Errorf("第%d步 '%s': %w", i+1, command, err)
+		}
+	}
+
+	return controller.ExecuteScript(ctx, script, func(step mccontrol.ScriptStepResult) {
+		s.record(actor, server, step.Command, &step.CommandResult, step.Err)
+	})
+}
+
+// NewScript 将脚本请求转换为控制器执行的脚本
+func NewScript(steps []model.ScriptStepRequest, variables map[string]string, onError, executorType string) mccontrol.Script {
+	script := mccontrol.Script{
+		Steps:        make([]mccontrol.ScriptStep, len(steps)),
+		Variables:    variables,
+		OnError:      mccontrol.ScriptErrorPolicy(onError),
+		ExecutorType: mccontrol.ExecutorType(executorType),
+	}
+	for i, step := range steps {
+		script.Steps[i] = mccontrol.ScriptStep{
+			Command: step.Command,
+			Delay:   time.Duration(step.Delay) * time.Millisecond,
+			OnError: mccontrol.ScriptErrorPolicy(step.OnError),
+		}
+	}
+	return script
+}
+
+// ScriptStepResponses 将脚本的执行结果转换为每个步骤的响应数据
+func ScriptStepResponses(result *mccontrol.ScriptResult) []model.ScriptStepResponse {
+	steps := make([]model.ScriptStepResponse, len(result.Steps))
+	for i, step := range result.Steps {
+		steps[i] = model.ScriptStepResponse{
+			Index:        step.Index,
+			Command:      step.Command,
+			Allowed:      true, // 执行前已检查所有步骤的权限
+			Skipped:      step.Skipped,
+			Response:     step.Response,
+			ExecutorType: string(step.ExecutorType),
+			BestEffort:   step.ExecutorType.BestEffortResponse(),
+			DurationMs:   step.Duration.Milliseconds(),
+		}
+		if step.Err != nil {
+			steps[i].Error = step.Err.Error()
+		}
+	}
+	return steps
+}
+
+// CanRunCommand 检查用户的角色是否可以在服务器上执行命令，不实际执行
+func (s *CommandService) CanRunCommand(actor CommandActor, server, command string) (bool, error) {
+	return middleware.CanRunCommand(actor.RoleName, server, command)
+}
+
+// authorize 在执行器运行前检查命令内容和命令策略，被拒绝的命令也会记录审计
+func (s *CommandService) authorize(actor CommandActor, server, command string) error {
+	if !middleware.ValidCommand(command) {
+		s.record(actor, server, command, &mccontrol.CommandResult{}, ErrInvalidCommand)
+		return ErrInvalidCommand
+	}
+
+	ok, err := s.CanRunCommand(actor, server, command)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.record(actor, server, command, &mccontrol.CommandResult{}, ErrCommandDenied)
+		return ErrCommandDenied
+	}
+	return nil
+}
+
+// record 记录命令审计
+func (s *CommandService) record(actor CommandActor, server, command string, result *mccontrol.CommandResult, err error) {
+	// 会话不存在时命令没有发送到服务器，不记录
+	if errors.Is(err, mccontrol.ErrSessionNotFound) {
+		return
+	}
+
+	audit := model.CommandAudit{
+		UserID:       actor.UserID,
+		Username:     actor.Username,
+		ServerName:   server,
+		Source:       actor.Source,
+		SessionID:    result.SessionID,
+		PodName:      result.PodName,
+		ExecutorType: string(result.ExecutorType),
+		Command:      command,
+		Response:     result.Response,
+		DurationMs:   result.Duration.Milliseconds(),
+		Success:      err == nil,
+	}
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	s.Audit.Record(audit)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/middleware"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/internal/websocket"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// ErrCommandForbidden 无权在服务器上执行命令
+var ErrCommandForbidden = errors.New("权限不足: 无权在此服务器上执行命令")
+
+// errConsoleClosed 连接已注销（或未登记），不再执行命令
+var errConsoleClosed = errors.New("连接已关闭")
+
+// consoleSession WebSocket连接在某个服务器上的命令会话
+type consoleSession struct {
+	controller *mccontrol.MinecraftController
+	sessionID  string
+}
+
+// consoleClient WebSocket连接的控制台状态
+type consoleClient struct {
+	sessions map[string]*consoleSession // 服务器名称 -> 命令会话
+	closed   bool
+	ctx      context.Context // 连接注销时取消，中止执行中的命令
+	cancel   context.CancelFunc
+	mutex    sync.Mutex
+}
+
+// ConsoleService 通过WebSocket提供RCON控制台功能
+// 每个WebSocket连接在每个服务器上持有独立的命令会话，连接注销时关闭
+type ConsoleService struct {
+	Config   *config.Config
+	Registry *ServerRegistry
+	Commands *CommandService
+	clients  map[string]*consoleClient // 只包含已登记且未注销的连接，连接ID不会重复使用
+	mutex    sync.Mutex
+}
+
+// NewConsoleService 创建控制台服务实例
+func NewConsoleService(cfg *config.Config, registry *ServerRegistry) *ConsoleService {
+	return &ConsoleService{
+		Config:   cfg,
+		Registry: registry,
+		Commands: NewCommandService(cfg, registry),
+		clients:  make(map[string]*consoleClient),
+	}
+}
+
+// RegisterClient 登记连接的控制台状态，连接注销前执行的命令使用该状态
+func (s *ConsoleService) RegisterClient(client *websocket.Client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.clients[client.ID]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.clients[client.ID] = &consoleClient{
+		sessions: make(map[string]*consoleSession),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// ExecuteCommand 在客户端的命令会话中执行命令
+// 连接已注销时返回"连接已关闭"，获取控制器期间连接注销则不再创建会话
+func (s *ConsoleService) ExecuteCommand(client *websocket.Client, server, command string) (string, error) {
+	if !middleware.CanExecuteCommand(client.RoleName, server) {
+		return "", ErrCommandForbidden
+	}
+
+	cc, err := s.getClient(client.ID)
+	if err != nil {
+		return "", err
+	}
+
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID, err := s.getSession(cc, server, controller)
+	if err != nil {
+		return "", err
+	}
+
+	actor := CommandActor{
+		UserID:   client.UserID,
+		Username: client.Username,
+		RoleName: client.RoleName,
+		Source:   model.CommandSourceWebSocket,
+	}
+
+	result, err := s.Commands.SessionExecuteCommand(cc.ctx, actor, server, sessionID, command)
+	if errors.Is(err, mccontrol.ErrSessionNotFound) {
+		// 会话已因空闲超时被清理，重新创建后重试
+		s.dropSession(cc, server, sessionID)
+		if sessionID, err = s.getSession(cc, server, controller); err != nil {
+			return "", err
+		}
+		result, err = s.Commands.SessionExecuteCommand(cc.ctx, actor, server, sessionID, command)
+	}
+	if result == nil {
+		return "", err
+	}
+	return result.Response, err
+}
+
+// ReleaseClient 中止客户端执行中的命令并关闭其所有命令会话
+// 移除的登记不会恢复，之后的命令返回"连接已关闭"
+func (s *ConsoleService) ReleaseClient(client *websocket.Client) {
+	s.mutex.Lock()
+	cc, ok := s.clients[client.ID]
+	delete(s.clients, client.ID)
+	s.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// 先取消上下文，使执行中的命令尽快返回并释放会话
+	cc.cancel()
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.closed = true
+	for _, session := range cc.sessions {
+		session.controller.CloseCommandSession(session.sessionID)
+	}
+	cc.sessions = nil
+}
+
+// getClient 获取连接的控制台状态，连接已注销时返回errConsoleClosed
+// 控制台状态只在连接时创建，注销后执行的命令不会重新创建状态和会话
+func (s *ConsoleService) getClient(clientID string) (*consoleClient, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cc, ok := s.clients[clientID]
+	if !ok {
+		return nil, errConsoleClosed
+	}
+	return cc, nil
+}
+
+// getSession 获取客户端在服务器上的命令会话，不存在则创建
+func (s *ConsoleService) getSession(cc *consoleClient, server string, controller *mccontrol.MinecraftController) (string, error) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if cc.closed {
+		return "", errConsoleClosed
+	}
+
+	// 服务器配置变更后控制器会重新创建，旧控制器上的会话已失效
+	if session, ok := cc.sessions[server]; ok && session.controller == controller {
+		return session.sessionID, nil
+	}
+
+	session, err := controller.CreateCommandSession(s.Config.MCSessionIdleTimeout, mccontrol.ExecutorAuto)
+	if err != nil {
+		return "", err
+	}
+	cc.sessions[server] = &consoleSession{
+		controller: controller,
+		sessionID:  session.GetID(),
+	}
+	return session.GetID(), nil
+}
+
+// dropSession 移除客户端已失效的命令会话
+func (s *ConsoleService) dropSession(cc *consoleClient, server, sessionID string) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if session, ok := cc.sessions[server]; ok && session.sessionID == sessionID {
+		delete(cc.sessions, server)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/websocket"
+)
+
+func newTestConsole(t *testing.T) (*ConsoleService, *fakeControllerBuilds) {
+	t.Helper()
+
+	registry, builds := newTestRegistry(t)
+	if _, err := NewRoleService().AddRolePermission("admin", "*", "*"); err != nil {
+		t.Fatalf("授予角色权限失败: %v", err)
+	}
+	return NewConsoleService(&config.Config{}, registry), builds
+}
+
+// clientCount 返回已登记的连接数量
+func clientCount(s *ConsoleService) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.clients)
+}
+
+func TestConsoleCommandAfterRelease(t *testing.T) {
+	console, _ := newTestConsole(t)
+	client := &websocket.Client{ID: "client-1", RoleName: "admin"}
+
+	// 未登记的连接不能执行命令
+	if _, err := console.ExecuteCommand(client, "creative", "list"); !errors.Is(err, errConsoleClosed) {
+		t.Fatalf("未登记的连接应返回连接已关闭: %v", err)
+	}
+
+	console.RegisterClient(client)
+	if _, err := console.ExecuteCommand(client, "creative", "list"); !errors.Is(err, errClusterUnavailable) {
+		t.Fatalf("已登记的连接应获取控制器: %v", err)
+	}
+
+	// 注销后到达的命令不重新登记连接
+	console.ReleaseClient(client)
+	if _, err := console.ExecuteCommand(client, "creative", "list"); !errors.Is(err, errConsoleClosed) {
+		t.Fatalf("注销后应返回连接已关闭: %v", err)
+	}
+	if count := clientCount(console); count != 0 {
+		t.Fatalf("注销后不应保留连接状态, 实际%d个", count)
+	}
+}
+
+func TestConsoleReleaseDuringCommand(t *testing.T) {
+	console, builds := newTestConsole(t)
+	client := &websocket.Client{ID: "client-1", RoleName: "admin"}
+	console.RegisterClient(client)
+
+	// 命令等待控制器创建期间连接注销
+	result := make(chan error, 1)
+	go func() {
+		_, err := console.ExecuteCommand(client, "survival", "list")
+		result <- err
+	}()
+	<-builds.started
+	console.ReleaseClient(client)
+	close(builds.release)
+
+	if err := waitResult(t, result); !errors.Is(err, errClusterUnavailable) {
+		t.Fatalf("应返回创建控制器的结果: %v", err)
+	}
+	if count := clientCount(console); count != 0 {
+		t.Fatalf("注销后执行完成的命令不应重新登记连接, 实际%d个", count)
+	}
+	if _, err := console.ExecuteCommand(client, "survival", "list"); !errors.Is(err, errConsoleClosed) {
+		t.Fatalf("注销后应返回连接已关闭: %v", err)
+	}
+}
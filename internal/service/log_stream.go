@@ -0,0 +1,333 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"city.newnan/k8s-console/internal/sse"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// LogTopicPrefix 服务器日志SSE主题前缀，完整主题为 logs:<服务器名称>，
+// 只订阅指定Pod的日志时为 logs:<服务器名称>/<Pod名称>
+const LogTopicPrefix = "logs:"
+
+// logBacklogLines 新订阅者没有断点信息时补发的最近日志行数
+const logBacklogLines int64 = 100
+
+// defaultLogStreamRetryDelay 日志流结束或启动失败后，仍有订阅者时重新启动的等待时间
+const defaultLogStreamRetryDelay = 5 * time.Second
+
+// LogLine 推送给客户端的日志行
+type LogLine struct {
+	Line string    `json:"line"`
+	Time time.Time `json:"time"`
+}
+
+// logStream 单个日志主题的日志流状态
+type logStream struct {
+	stop    chan struct{}
+	clients map[string]*logSubscriber // 订阅者（由LogStreamService.mutex保护）
+
+	// publishMutex 保证实时日志与补发结束时转发的暂存日志按顺序发布
+	publishMutex sync.Mutex
+}
+
+// logSubscriber 日志主题的一个订阅者
+// 补发历史日志期间收到的实时日志先暂存，补发结束后再发送，保证客户端按时间顺序收到日志，
+// 断线重连时携带的Last-Event-ID不会跳过尚未补发的日志
+type logSubscriber struct {
+	catchingUp bool      // 正在补发历史日志
+	until      time.Time // 补发的截止时间，暂存的实时日志中早于该时间的已经补发
+	pending    []string  // 补发期间收到的实时日志
+}
+
+// LogStreamService 按需为服务器日志主题启动和停止Kubernetes日志流
+// 第一个订阅者连接时开始跟踪Pod日志，最后一个订阅者断开时停止；
+// 日志流因出错结束时，只要仍有订阅者就会从最后一条日志之后重新开始
+type LogStreamService struct {
+	Registry *ServerRegistry
+	Broker   *sse.Broker
+	streams  map[string]*logStream
+	mutex    sync.Mutex
+
+	retryDelay time.Duration // 日志流重新启动前的等待时间
+	// fetch 获取服务器日志，参数与mccontrol.MinecraftController.FetchLogs相同
+	fetch func(server string, options mccontrol.LogOptions, callback func([]string, string)) ([]string, error)
+}
+
+// NewLogStreamService 创建日志流服务实例
+func NewLogStreamService(registry *ServerRegistry, broker *sse.Broker) *LogStreamService {
+	s := &LogStreamService{
+		Registry:   registry,
+		Broker:     broker,
+		streams:    make(map[string]*logStream),
+		retryDelay: defaultLogStreamRetryDelay,
+	}
+	s.fetch = s.fetchLogs
+	return s
+}
+
+// OnSubscribe 客户端订阅日志主题
+func (s *LogStreamService) OnSubscribe(topic string, client *sse.Client, first bool) {
+	subscribedAt := time.Now()
+
+	s.mutex.Lock()
+	stream, ok := s.streams[topic]
+	if first || !ok {
+		stream = s.startStream(topic, subscribedAt)
+	}
+	stream.clients[client.ID] = &logSubscriber{catchingUp: true, until: subscribedAt}
+	s.mutex.Unlock()
+
+	// 补发历史日志可能耗时较长，不阻塞代理的事件处理
+	go s.catchUp(topic, stream, client, subscribedAt)
+}
+
+// OnUnsubscribe 客户端取消订阅日志主题
+func (s *LogStreamService) OnUnsubscribe(topic string, client *sse.Client, last bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stream, ok := s.streams[topic]
+	if !ok {
+		return
+	}
+	delete(stream.clients, client.ID)
+	if last {
+		delete(s.streams, topic)
+		close(stream.stop)
+	}
+}
+
+// startStream 创建主题的日志流并在后台开始跟踪服务器日志，调用方需持有锁
+// 主题已有日志流时停止原来的日志流
+func (s *LogStreamService) startStream(topic string, since time.Time) *logStream {
+	if existing, ok := s.streams[topic]; ok {
+		close(existing.stop)
+	}
+	stream := &logStream{
+		stop:    make(chan struct{}),
+		clients: make(map[string]*logSubscriber),
+	}
+	s.streams[topic] = stream
+
+	go s.runStream(topic, stream, since)
+	return stream
+}
+
+// runStream 跟踪服务器日志直到日志流被停止，日志流结束或启动失败后等待一段时间重新启动
+// 退出时移除主题的日志流，新的订阅者会重新启动日志流
+func (s *LogStreamService) runStream(topic string, stream *logStream, since time.Time) {
+	defer func() {
+		s.mutex.Lock()
+		if s.streams[topic] == stream {
+			delete(s.streams, topic)
+		}
+		s.mutex.Unlock()
+	}()
+
+	for {
+		if err := s.follow(topic, stream, &since); err != nil {
+			log.Printf("跟踪日志主题 %s 失败: %v", topic, err)
+		}
+
+		select {
+		case <-stream.stop:
+			return
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// follow 跟踪服务器日志并发布给订阅者，日志流结束时返回
+// since为日志流的起始时间，发布日志时更新为最后一条日志的时间，重新启动时从该时间之后继续
+func (s *LogStreamService) follow(topic string, stream *logStream, since *time.Time) error {
+	server, pod := parseLogTopic(topic)
+	closed := make(chan struct{})
+	start := *since
+	options := mccontrol.LogOptions{
+		SinceTime:  &start,
+		PodName:    pod,
+		Timestamps: true,
+		StopSignal: stream.stop,
+		OnClose:    func() { close(closed) },
+	}
+
+	_, err := s.fetch(server, options, func(lines []string, errMsg string) {
+		for _, line := range lines {
+			if _, ts, ok := mccontrol.SplitLogTimestamp(line); ok {
+				// SinceTime精度为秒，订阅之前和已经发布的日志需要过滤掉
+				if !ts.After(*since) {
+					continue
+				}
+				*since = ts
+			}
+			s.publishLive(topic, stream, line)
+		}
+		if errMsg != "" {
+			s.publishStatus(topic, "", errMsg)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-closed:
+		return fmt.Errorf("日志流已结束")
+	case <-stream.stop:
+		<-closed
+		return nil
+	}
+}
+
+// fetchLogs 使用服务器的控制器获取日志
+func (s *LogStreamService) fetchLogs(server string, options mccontrol.LogOptions, callback func([]string, string)) ([]string, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		if callback != nil {
+			callback(nil, "获取服务器控制器失败: "+err.Error())
+		}
+		return nil, err
+	}
+	return controller.FetchLogs(options, callback)
+}
+
+// publishLive 向订阅者发布一行实时日志，正在补发历史日志的订阅者暂存到补发结束
+func (s *LogStreamService) publishLive(topic string, stream *logStream, line string) {
+	stream.publishMutex.Lock()
+	defer stream.publishMutex.Unlock()
+
+	var ready []string
+	s.mutex.Lock()
+	for id, subscriber := range stream.clients {
+		if subscriber.catchingUp {
+			subscriber.pending = append(subscriber.pending, line)
+		} else {
+			ready = append(ready, id)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, id := range ready {
+		s.publishLine(topic, id, line)
+	}
+}
+
+// catchUp 向新订阅者补发订阅前的日志，结束后发送补发期间暂存的实时日志
+// 如果客户端携带了有效的Last-Event-ID，则从该时间点续传，否则补发最近的日志
+func (s *LogStreamService) catchUp(topic string, stream *logStream, client *sse.Client, until time.Time) {
+	defer s.finishCatchUp(topic, stream, client.ID)
+
+	server, pod := parseLogTopic(topic)
+	options := mccontrol.LogOptions{PodName: pod, Timestamps: true}
+	lastEventTime, resume := parseLogEventID(client.LastEventID)
+	if resume {
+		options.SinceTime = &lastEventTime
+	} else {
+		tailLines := logBacklogLines
+		options.TailLines = &tailLines
+	}
+
+	lines, err := s.fetch(server, options, nil)
+	if err != nil {
+		s.publishStatus(topic, client.ID, err.Error())
+		return
+	}
+
+	for _, line := range lines {
+		_, ts, ok := mccontrol.SplitLogTimestamp(line)
+		if ok {
+			// SinceTime精度为秒，需要过滤掉客户端已收到的日志；订阅之后的日志由实时流负责
+			if resume && !ts.After(lastEventTime) {
+				continue
+			}
+			if ts.After(until) {
+				continue
+			}
+		}
+		s.publishLine(topic, client.ID, line)
+	}
+}
+
+// finishCatchUp 结束订阅者的补发，按顺序发送补发期间暂存的实时日志，已经补发的日志不重复发送
+func (s *LogStreamService) finishCatchUp(topic string, stream *logStream, clientID string) {
+	stream.publishMutex.Lock()
+	defer stream.publishMutex.Unlock()
+
+	s.mutex.Lock()
+	subscriber, ok := stream.clients[clientID]
+	if !ok {
+		s.mutex.Unlock()
+		return
+	}
+	pending := subscriber.pending
+	subscriber.pending = nil
+	subscriber.catchingUp = false
+	s.mutex.Unlock()
+
+	for _, line := range pending {
+		if _, ts, ok := mccontrol.SplitLogTimestamp(line); ok && !ts.After(subscriber.until) {
+			continue
+		}
+		s.publishLine(topic, clientID, line)
+	}
+}
+
+// publishLine 发布一行日志，事件ID为日志时间戳，供客户端断线重连时续传
+func (s *LogStreamService) publishLine(topic, clientID, line string) {
+	content, ts, _ := mccontrol.SplitLogTimestamp(line)
+	message := &sse.Message{
+		Topic:    topic,
+		Event:    "log",
+		Data:     LogLine{Line: content, Time: ts},
+		ClientID: clientID,
+	}
+	if !ts.IsZero() {
+		message.ID = ts.Format(time.RFC3339Nano)
+	}
+	s.Broker.Publish(message)
+}
+
+// publishStatus 发布日志流状态信息（如错误、重连等）
+func (s *LogStreamService) publishStatus(topic, clientID, status string) {
+	s.Broker.Publish(&sse.Message{
+		Topic:    topic,
+		Event:    "status",
+		Data:     map[string]string{"message": status},
+		ClientID: clientID,
+	})
+}
+
+// LogTopic 返回服务器日志的SSE主题，pod为空则跟随服务器当前的Pod
+func LogTopic(server, pod string) string {
+	if pod == "" {
+		return LogTopicPrefix + server
+	}
+	return LogTopicPrefix + server + "/" + pod
+}
+
+// parseLogTopic 从日志主题中解析服务器名称和Pod名称，Pod名称不含"/"
+func parseLogTopic(topic string) (server, pod string) {
+	name := strings.TrimPrefix(topic, LogTopicPrefix)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// parseLogEventID 解析作为事件ID的日志时间戳
+func parseLogEventID(id string) (time.Time, bool) {
+	if id == "" {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, id)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return ts, true
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/sse"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// fakeLogSource 代替控制器提供日志，由测试控制补发何时返回以及实时日志的内容
+type fakeLogSource struct {
+	backlog  []string
+	release  chan struct{}       // 不为nil时，补发在通道关闭后才返回
+	started  chan *fakeLogFollow // 每次启动日志流时发送
+	mutex    sync.Mutex
+	failures int // 之后启动日志流失败的次数
+}
+
+// fakeLogFollow 一次启动的日志流
+type fakeLogFollow struct {
+	since    time.Time
+	callback func([]string, string)
+	end      chan struct{} // 关闭后日志流结束
+}
+
+func newFakeLogSource() *fakeLogSource {
+	return &fakeLogSource{started: make(chan *fakeLogFollow, 10)}
+}
+
+// fetch 与mccontrol.MinecraftController.FetchLogs的行为一致：有回调时在后台跟踪日志，结束时调用OnClose
+func (f *fakeLogSource) fetch(server string, options mccontrol.LogOptions, callback func([]string, string)) ([]string, error) {
+	if callback == nil {
+		if f.release != nil {
+			<-f.release
+		}
+		return f.backlog, nil
+	}
+
+	f.mutex.Lock()
+	if f.failures > 0 {
+		f.failures--
+		f.mutex.Unlock()
+		callback(nil, "启动日志流失败")
+		return nil, errors.New("启动日志流失败")
+	}
+	f.mutex.Unlock()
+
+	follow := &fakeLogFollow{since: *options.SinceTime, callback: callback, end: make(chan struct{})}
+	go func() {
+		select {
+		case <-follow.end:
+		case <-options.StopSignal:
+		}
+		options.OnClose()
+	}()
+	f.started <- follow
+	return nil, nil
+}
+
+// next 等待下一次启动的日志流
+func (f *fakeLogSource) next(t *testing.T) *fakeLogFollow {
+	t.Helper()
+
+	select {
+	case follow := <-f.started:
+		return follow
+	case <-time.After(5 * time.Second):
+		t.Fatal("日志流未启动")
+		return nil
+	}
+}
+
+// sseEvent 客户端收到的SSE事件
+type sseEvent struct {
+	event string
+	id    string
+	data  string
+}
+
+// logTestClient 订阅日志主题的SSE客户端
+type logTestClient struct {
+	body   func() error
+	events chan sseEvent
+}
+
+// newLogTestServer 启动订阅服务器survival日志主题的SSE接口
+func newLogTestServer(t *testing.T, service *LogStreamService) *httptest.Server {
+	t.Helper()
+
+	broker := sse.NewBroker()
+	broker.Start()
+	broker.RegisterTopicHandler(LogTopicPrefix, service)
+	service.Broker = broker
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/logs", func(ctx *gin.Context) {
+		broker.ServeTopic(ctx, LogTopic("survival", ""))
+	})
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// subscribeLogs 连接SSE接口并在后台解析事件
+func subscribeLogs(t *testing.T, server *httptest.Server) *logTestClient {
+	t.Helper()
+
+	resp, err := http.Get(server.URL + "/logs")
+	if err != nil {
+		t.Fatalf("订阅日志失败: %v", err)
+	}
+	client := &logTestClient{body: resp.Body.Close, events: make(chan sseEvent, 100)}
+	t.Cleanup(func() { client.body() })
+
+	go func() {
+		defer close(client.events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				client.events <- event
+				event = sseEvent{}
+			case strings.HasPrefix(line, "event: "):
+				event.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return client
+}
+
+// next 等待下一个指定类型的事件
+func (c *logTestClient) next(t *testing.T, eventType string) sseEvent {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-c.events:
+			if !ok {
+				t.Fatalf("等待%s事件时连接已关闭", eventType)
+			}
+			if event.event == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("等待%s事件超时", eventType)
+		}
+	}
+}
+
+// nextLines 依次读取n行日志的内容
+func (c *logTestClient) nextLines(t *testing.T, n int) []string {
+	t.Helper()
+
+	lines := make([]string, n)
+	for i := range lines {
+		var line LogLine
+		if err := json.Unmarshal([]byte(c.next(t, "log").data), &line); err != nil {
+			t.Fatalf("解析日志事件失败: %v", err)
+		}
+		lines[i] = line.Line
+	}
+	return lines
+}
+
+// logAt 生成带Kubernetes时间戳的日志行
+func logAt(ts time.Time, content string) string {
+	return ts.UTC().Format(time.RFC3339Nano) + " " + content
+}
+
+// waitStreamsStopped 等待所有日志流被移除
+func waitStreamsStopped(t *testing.T, service *LogStreamService) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		service.mutex.Lock()
+		n := len(service.streams)
+		service.mutex.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("最后一个订阅者断开后日志流应被移除")
+}
+
+func TestLogStreamCatchUpBeforeLive(t *testing.T) {
+	source := newFakeLogSource()
+	now := time.Now()
+	source.backlog = []string{logAt(now.Add(-2*time.Minute), "old-1"), logAt(now.Add(-time.Minute), "old-2")}
+	source.release = make(chan struct{})
+
+	service := NewLogStreamService(nil, nil)
+	service.fetch = source.fetch
+	client := subscribeLogs(t, newLogTestServer(t, service))
+	follow := source.next(t)
+
+	// 补发历史日志期间收到的实时日志在补发结束后发送
+	follow.callback([]string{logAt(time.Now().Add(time.Second), "live-1")}, "")
+	close(source.release)
+	follow.callback([]string{logAt(time.Now().Add(2*time.Second), "live-2")}, "")
+
+	lines := client.nextLines(t, 4)
+	if want := []string{"old-1", "old-2", "live-1", "live-2"}; strings.Join(lines, ",") != strings.Join(want, ",") {
+		t.Fatalf("日志顺序错误: 期望 %v, 实际 %v", want, lines)
+	}
+
+	client.body()
+	waitStreamsStopped(t, service)
+}
+
+func TestLogStreamRestartsWhileSubscribed(t *testing.T) {
+	source := newFakeLogSource()
+	source.failures = 1
+
+	service := NewLogStreamService(nil, nil)
+	service.fetch = source.fetch
+	service.retryDelay = 10 * time.Millisecond
+	client := subscribeLogs(t, newLogTestServer(t, service))
+
+	// 第一次启动失败后重新启动
+	if status := client.next(t, "status"); !strings.Contains(status.data, "启动日志流失败") {
+		t.Fatalf("应通知启动失败: %s", status.data)
+	}
+	follow := source.next(t)
+
+	last := time.Now().Add(time.Second)
+	follow.callback([]string{logAt(last, "live-1")}, "")
+	if lines := client.nextLines(t, 1); lines[0] != "live-1" {
+		t.Fatalf("日志错误: %v", lines)
+	}
+
+	// 日志流结束后从最后一条日志之后重新开始，重复收到的日志被过滤
+	close(follow.end)
+	follow = source.next(t)
+	if !follow.since.Equal(last) {
+		t.Fatalf("重新启动的起始时间应为最后一条日志的时间: 期望 %v, 实际 %v", last, follow.since)
+	}
+	follow.callback([]string{logAt(last, "live-1"), logAt(last.Add(time.Second), "live-2")}, "")
+	if lines := client.nextLines(t, 1); lines[0] != "live-2" {
+		t.Fatalf("重新启动后的日志错误: %v", lines)
+	}
+
+	client.body()
+	waitStreamsStopped(t, service)
+	select {
+	case <-source.started:
+		t.Fatal("没有订阅者后不应重新启动日志流")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestLogStreamControllerUnavailable(t *testing.T) {
+	setupTestDB(t)
+	cfg := &config.Config{}
+	service := NewLogStreamService(NewServerRegistry(cfg), nil)
+	service.retryDelay = 10 * time.Millisecond
+	client := subscribeLogs(t, newLogTestServer(t, service))
+
+	// 服务器不存在时补发和跟踪日志都失败，仍有订阅者时不断重试
+	for failures := 0; failures < 2; {
+		if status := client.next(t, "status"); strings.Contains(status.data, "获取服务器控制器失败") {
+			failures++
+		}
+	}
+
+	client.body()
+	waitStreamsStopped(t, service)
+}
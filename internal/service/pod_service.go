@@ -0,0 +1,71 @@
+package service
+
+import (
+	"log"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// PodService 固定或取消固定服务器使用的Pod
+// 每次操作记录一条审计，来源为model.CommandSourcePod
+type PodService struct {
+	Registry *ServerRegistry
+	Audit    *AuditService
+}
+
+// NewPodService 创建Pod服务实例
+func NewPodService(cfg *config.Config, registry *ServerRegistry) *PodService {
+	return &PodService{
+		Registry: registry,
+		Audit:    NewAuditService(cfg),
+	}
+}
+
+// PinPod 将服务器固定到指定的Pod，Pod不存在或不匹配标签选择器时返回mccontrol.ErrPodNotFound
+func (s *PodService) PinPod(actor CommandActor, server, pod string) (*mccontrol.MinecraftController, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+
+	err = controller.PinPod(pod)
+	s.record(actor, server, "pin pod="+pod, err)
+	if err == nil {
+		log.Printf("用户 %s 将服务器 %s 固定到Pod %s", actor.Username, server, pod)
+	}
+	return controller, err
+}
+
+// UnpinPod 取消固定服务器使用的Pod，恢复自动选择
+func (s *PodService) UnpinPod(actor CommandActor, server string) (*mccontrol.MinecraftController, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := controller.PinnedPod()
+	err = controller.UnpinPod()
+	s.record(actor, server, "unpin pod="+pinned, err)
+	if err == nil {
+		log.Printf("用户 %s 取消固定服务器 %s 的Pod", actor.Username, server)
+	}
+	return controller, err
+}
+
+// record 记录固定或取消固定Pod的审计
+func (s *PodService) record(actor CommandActor, server, command string, err error) {
+	audit := model.CommandAudit{
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		ServerName: server,
+		Source:     model.CommandSourcePod,
+		Command:    command,
+		Success:    err == nil,
+	}
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	s.Audit.Record(audit)
+}
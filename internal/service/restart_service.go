@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/internal/sse"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// RestartTopicPrefix 服务器重启进度SSE主题前缀，完整主题为 restart:<服务器名称>
+const RestartTopicPrefix = "restart:"
+
+// ErrInvalidRestartReason 重启原因包含换行等控制字符
+var ErrInvalidRestartReason = errors.New("无效的重启原因: 不能包含换行等控制字符")
+
+// RestartService 在后台重启服务器，并将重启进度发布到SSE主题
+// 重启本身和重启过程中发送的命令都记录审计，来源为model.CommandSourceRestart
+type RestartService struct {
+	Registry *ServerRegistry
+	Broker   *sse.Broker
+	Audit    *AuditService
+}
+
+// NewRestartService 创建重启服务实例
+func NewRestartService(cfg *config.Config, registry *ServerRegistry, broker *sse.Broker) *RestartService {
+	return &RestartService{
+		Registry: registry,
+		Broker:   broker,
+		Audit:    NewAuditService(cfg),
+	}
+}
+
+// Restart 在后台开始重启服务器，重启可能持续数分钟，不受发起请求的生命周期约束
+// 服务器正在重启时返回mccontrol.ErrRestartInProgress，重启原因包含控制字符时返回ErrInvalidRestartReason
+func (s *RestartService) Restart(actor CommandActor, server string, options mccontrol.RestartOptions) error {
+	// 重启原因会拼接到say命令中，换行会被当作另一条命令执行
+	if strings.IndexFunc(options.Reason, unicode.IsControl) >= 0 {
+		return ErrInvalidRestartReason
+	}
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return err
+	}
+	if controller.Restarting() {
+		return mccontrol.ErrRestartInProgress
+	}
+
+	// 在开始重启前订阅，避免错过第一个进度事件
+	events := controller.Subscribe(mccontrol.EventFilter{
+		Types: []mccontrol.EventType{mccontrol.EventRestart},
+	})
+	go s.forward(server, events)
+
+	actor.Source = model.CommandSourceRestart
+	options.OnCommand = func(command string, result *mccontrol.CommandResult, err error) {
+		s.record(actor, server, command, result, err)
+	}
+
+	log.Printf("用户 %s 开始重启服务器 %s", actor.Username, server)
+	go func() {
+		defer controller.Unsubscribe(events)
+
+		start := time.Now()
+		err := controller.RestartServer(context.Background(), options)
+		s.record(actor, server, restartAuditCommand(options), &mccontrol.CommandResult{Duration: time.Since(start)}, err)
+		if err != nil {
+			log.Printf("重启服务器 %s 失败: %v", server, err)
+			return
+		}
+		log.Printf("服务器 %s 重启完成", server)
+	}()
+	return nil
+}
+
+// record 记录重启或重启过程中发送的命令的审计
+func (s *RestartService) record(actor CommandActor, server, command string, result *mccontrol.CommandResult, err error) {
+	audit := model.CommandAudit{
+		UserID:       actor.UserID,
+		Username:     actor.Username,
+		ServerName:   server,
+		Source:       actor.Source,
+		ExecutorType: string(result.ExecutorType),
+		Command:      command,
+		Response:     result.Response,
+		DurationMs:   result.Duration.Milliseconds(),
+		Success:      err == nil,
+	}
+	if err != nil {
+		audit.Error = err.Error()
+	}
+	s.Audit.Record(audit)
+}
+
+// restartAuditCommand 返回审计记录中表示重启本身的命令文本，包含重启选项
+func restartAuditCommand(options mccontrol.RestartOptions) string {
+	mode := options.Mode
+	if mode == "" {
+		mode = mccontrol.RestartDeletePod
+	}
+	command := fmt.Sprintf("restart mode=%s countdown=%s force=%t", mode, options.Countdown, options.Force)
+	if options.Reason != "" {
+		command += fmt.Sprintf(" reason=%q", options.Reason)
+	}
+	return command
+}
+
+// forward 将重启进度事件发布到服务器的重启主题，事件通道关闭时结束
+func (s *RestartService) forward(server string, events <-chan mccontrol.Event) {
+	for event := range events {
+		s.Broker.Publish(&sse.Message{
+			Topic: RestartTopicPrefix + server,
+			Event: "restart",
+			Data:  event,
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// scaleAuditTimeout 记录审计时获取副本数的超时
+const scaleAuditTimeout = 5 * time.Second
+
+// ScaleService 手动唤醒和缩容服务器
+// 每次操作记录一条审计，来源为model.CommandSourceScale，响应中记录操作后工作负载的副本数
+type ScaleService struct {
+	Registry *ServerRegistry
+	Audit    *AuditService
+}
+
+// NewScaleService 创建唤醒和缩容服务实例
+func NewScaleService(cfg *config.Config, registry *ServerRegistry) *ScaleService {
+	return &ScaleService{
+		Registry: registry,
+		Audit:    NewAuditService(cfg),
+	}
+}
+
+// Wake 唤醒服务器并等待服务器可用，timeout为0则使用默认值
+func (s *ScaleService) Wake(ctx context.Context, actor CommandActor, server string, timeout time.Duration) (*mccontrol.MinecraftController, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("用户 %s 唤醒服务器 %s", actor.Username, server)
+	start := time.Now()
+	err = controller.WakeServer(ctx, timeout)
+	command := "wake"
+	if timeout > 0 {
+		command += " timeout=" + timeout.String()
+	}
+	s.record(actor, server, command, controller, time.Since(start), err)
+	return controller, err
+}
+
+// Sleep 保存世界后将服务器缩容到0，服务器正在重启时返回mccontrol.ErrRestartInProgress
+func (s *ScaleService) Sleep(ctx context.Context, actor CommandActor, server string) error {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("用户 %s 将服务器 %s 缩容到0", actor.Username, server)
+	start := time.Now()
+	err = controller.ScaleToZero(ctx)
+	s.record(actor, server, "sleep", controller, time.Since(start), err)
+	return err
+}
+
+// record 记录唤醒或缩容的审计，响应为操作后的副本数
+// 请求的上下文可能已经取消（如唤醒超时），获取副本数使用独立的上下文
+func (s *ScaleService) record(actor CommandActor, server, command string, controller *mccontrol.MinecraftController, duration time.Duration, err error) {
+	audit := model.CommandAudit{
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+		ServerName: server,
+		Source:     model.CommandSourceScale,
+		Command:    command,
+		DurationMs: duration.Milliseconds(),
+		Success:    err == nil,
+	}
+	if err != nil {
+		audit.Error = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scaleAuditTimeout)
+	defer cancel()
+	if replicas, replicasErr := controller.Replicas(ctx); replicasErr == nil {
+		audit.Response = fmt.Sprintf("replicas=%d", replicas)
+	} else {
+		log.Printf("获取服务器 %s 的副本数失败: %v", server, replicasErr)
+	}
+	s.Audit.Record(audit)
+}
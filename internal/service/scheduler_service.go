@@ -0,0 +1,539 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/middleware"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// scheduledRunTimeout 定时任务执行的超时，不包括步骤之间的等待时间
+const scheduledRunTimeout = 10 * time.Minute
+
+var (
+	// ErrScheduleNotFound 定时任务不存在
+	ErrScheduleNotFound = errors.New("定时任务不存在")
+	// ErrInvalidCron cron表达式无法解析
+	ErrInvalidCron = errors.New("无效的cron表达式")
+	// ErrScheduleForbidden 角色无权访问定时任务所属的服务器
+	ErrScheduleForbidden = errors.New("权限不足: 无权访问此服务器的定时任务")
+)
+
+// scheduleParser 解析标准的5段cron表达式（分 时 日 月 周）和@daily、@every 30m等描述符
+// 表达式前可以加CRON_TZ=Asia/Shanghai指定时区，否则使用服务器的本地时区
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// SchedulerService 定时任务服务
+// 按数据库中保存的cron表达式在服务器上执行命令，记录每次执行的结果
+type SchedulerService struct {
+	Config   *config.Config
+	Registry *ServerRegistry
+	Commands *CommandService
+	Servers  *ServerService
+	Users    *UserService
+
+	cron    *cron.Cron
+	entries map[uint]cron.EntryID // 任务ID -> cron条目
+	mutex   sync.Mutex            // 保护entries
+}
+
+// NewSchedulerService 创建定时任务服务实例
+func NewSchedulerService(cfg *config.Config, registry *ServerRegistry) *SchedulerService {
+	logger := cron.PrintfLogger(log.Default())
+	return &SchedulerService{
+		Config:   cfg,
+		Registry: registry,
+		Commands: NewCommandService(cfg, registry),
+		Servers:  NewServerService(cfg, registry),
+		Users:    NewUserService(cfg),
+		// 同一个任务上次执行尚未结束时跳过本次执行
+		cron:    cron.New(cron.WithParser(scheduleParser), cron.WithChain(cron.Recover(logger), cron.SkipIfStillRunning(logger))),
+		entries: make(map[uint]cron.EntryID),
+	}
+}
+
+// Start 加载所有启用的定时任务并开始调度
+func (s *SchedulerService) Start() error {
+	var tasks []model.ScheduledTask
+	if err := db.DB.Where("enabled = ?", true).Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	for i := range tasks {
+		if err := s.schedule(&tasks[i]); err != nil {
+			log.Printf("调度定时任务 %d (%s) 失败: %v", tasks[i].ID, tasks[i].Name, err)
+		}
+	}
+	s.mutex.Unlock()
+
+	if s.Config.ScheduleRunRetention > 0 {
+		s.cron.AddFunc("@hourly", func() {
+			if err := s.CleanupRuns(); err != nil {
+				log.Printf("清理定时任务执行记录失败: %v", err)
+			}
+		})
+	}
+
+	s.cron.Start()
+	return nil
+}
+
+// Stop 停止调度，正在执行的任务不会被中断
+func (s *SchedulerService) Stop() {
+	s.cron.Stop()
+}
+
+// ListTasks 分页获取actor有权访问的服务器的定时任务，server不为空时只返回该服务器的任务
+func (s *SchedulerService) ListTasks(actor CommandActor, server string, page, pageSize int) ([]model.ScheduledTask, int64, error) {
+	servers, err := s.accessibleServers(actor)
+	if err != nil {
+		return nil, 0, err
+	}
+	if server != "" {
+		if !middleware.CanAccessServer(actor.RoleName, server) {
+			return []model.ScheduledTask{}, 0, nil
+		}
+		servers = []string{server}
+	}
+	if len(servers) == 0 {
+		return []model.ScheduledTask{}, 0, nil
+	}
+	query := db.DB.Model(&model.ScheduledTask{}).Where("server_name IN ?", servers)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tasks []model.ScheduledTask
+	offset := (page - 1) * pageSize
+	if err := query.Order("id").Offset(offset).Limit(pageSize).Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+
+	paused, err := s.pausedServers()
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range tasks {
+		s.fillNextRun(&tasks[i], paused)
+	}
+	return tasks, total, nil
+}
+
+// GetTask 获取定时任务，actor需有权访问任务所属的服务器
+func (s *SchedulerService) GetTask(actor CommandActor, id uint) (*model.ScheduledTask, error) {
+	task, err := s.loadAccessibleTask(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withNextRun(task)
+}
+
+// withNextRun 填充任务的下次执行时间
+func (s *SchedulerService) withNextRun(task *model.ScheduledTask) (*model.ScheduledTask, error) {
+	paused, err := s.pausedServers()
+	if err != nil {
+		return nil, err
+	}
+	s.fillNextRun(task, paused)
+	return task, nil
+}
+
+// CreateTask 创建定时任务，任务以actor的身份执行
+// 创建前校验cron表达式、脚本和actor执行每条命令的权限
+func (s *SchedulerService) CreateTask(actor CommandActor, req model.ScheduledTaskCreate) (*model.ScheduledTask, error) {
+	task := model.ScheduledTask{
+		Name:       req.Name,
+		ServerName: req.ServerName,
+		Cron:       req.Cron,
+		Steps:      req.Steps,
+		Variables:  req.Variables,
+		OnError:    req.OnError,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		UserID:     actor.UserID,
+		Username:   actor.Username,
+	}
+	if err := s.validate(actor, &task); err != nil {
+		return nil, err
+	}
+
+	if err := db.DB.Create(&task).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.reschedule(&task); err != nil {
+		return nil, err
+	}
+	return s.withNextRun(&task)
+}
+
+// UpdateTask 更新定时任务，之后任务以actor的身份执行
+func (s *SchedulerService) UpdateTask(actor CommandActor, id uint, update model.ScheduledTaskUpdate) (*model.ScheduledTask, error) {
+	task, err := s.loadAccessibleTask(actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != "" {
+		task.Name = update.Name
+	}
+	if update.Cron != "" {
+		task.Cron = update.Cron
+	}
+	if len(update.Steps) > 0 {
+		task.Steps = update.Steps
+	}
+	if update.Variables != nil {
+		task.Variables = update.Variables
+	}
+	if update.OnError != "" {
+		task.OnError = update.OnError
+	}
+	if update.Enabled != nil {
+		task.Enabled = *update.Enabled
+	}
+	task.UserID = actor.UserID
+	task.Username = actor.Username
+
+	if err := s.validate(actor, task); err != nil {
+		return nil, err
+	}
+
+	// 执行记录相关的字段由执行过程更新，这里不覆盖
+	fields := []string{"name", "cron", "steps", "variables", "on_error", "enabled", "user_id", "username"}
+	if err := db.DB.Model(task).Select(fields).Updates(task).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.reschedule(task); err != nil {
+		return nil, err
+	}
+	return s.withNextRun(task)
+}
+
+// DeleteTask 删除定时任务及其执行记录，actor需有权访问任务所属的服务器
+func (s *SchedulerService) DeleteTask(actor CommandActor, id uint) error {
+	if _, err := s.loadAccessibleTask(actor, id); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	if entryID, ok := s.entries[id]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, id)
+	}
+	s.mutex.Unlock()
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.ScheduledTask{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrScheduleNotFound
+		}
+		return tx.Where("task_id = ?", id).Delete(&model.ScheduledTaskRun{}).Error
+	})
+}
+
+// RunTask 以actor的身份立即执行一次定时任务并返回执行记录，服务器的定时任务暂停时也会执行
+// 执行前检查actor执行每条命令的权限，任一命令被拒绝时不执行也不记录
+func (s *SchedulerService) RunTask(ctx context.Context, actor CommandActor, id uint) (*model.ScheduledTaskRun, error) {
+	task, err := s.loadAccessibleTask(actor, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(actor, task); err != nil {
+		return nil, err
+	}
+	return s.run(ctx, task, model.ScheduleTriggerManual, &actor), nil
+}
+
+// ListRuns 分页获取定时任务的执行记录，按时间倒序排列，actor需有权访问任务所属的服务器
+func (s *SchedulerService) ListRuns(actor CommandActor, taskID uint, page, pageSize int) ([]model.ScheduledTaskRun, int64, error) {
+	if _, err := s.loadAccessibleTask(actor, taskID); err != nil {
+		return nil, 0, err
+	}
+
+	query := db.DB.Model(&model.ScheduledTaskRun{}).Where("task_id = ?", taskID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []model.ScheduledTaskRun
+	offset := (page - 1) * pageSize
+	if err := query.Order("started_at DESC").Offset(offset).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// SetServerPaused 暂停或恢复服务器的所有定时任务
+// 暂停期间到达执行时间的任务记录为跳过，不会在恢复后补执行
+func (s *SchedulerService) SetServerPaused(server string, paused bool) error {
+	if _, err := s.Servers.GetServerByName(server); err != nil {
+		return err
+	}
+	return db.DB.Model(&model.Server{}).Where("name = ?", server).Update("schedules_paused", paused).Error
+}
+
+// CleanupRuns 删除超过保留时长的执行记录
+func (s *SchedulerService) CleanupRuns() error {
+	if s.Config.ScheduleRunRetention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.Config.ScheduleRunRetention)
+	return db.DB.Where("started_at < ?", cutoff).Delete(&model.ScheduledTaskRun{}).Error
+}
+
+// validate 校验任务的服务器、cron表达式、脚本和执行命令的权限
+func (s *SchedulerService) validate(actor CommandActor, task *model.ScheduledTask) error {
+	if _, err := s.Servers.GetServerByName(task.ServerName); err != nil {
+		return err
+	}
+	if !middleware.CanAccessServer(actor.RoleName, task.ServerName) {
+		return ErrScheduleForbidden
+	}
+	if _, err := scheduleParser.Parse(task.Cron); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCron, err)
+	}
+	return s.authorize(actor, task)
+}
+
+// authorize 检查actor是否可以执行任务的每条命令
+func (s *SchedulerService) authorize(actor CommandActor, task *model.ScheduledTask) error {
+	checks, err := s.Commands.CheckScript(actor, task.ServerName, s.script(task))
+	if err != nil {
+		return err
+	}
+	for i, check := range checks {
+		if !check.Allowed {
+			return fmt.Errorf("第%d步 '%s': %w", i+1, check.Command, ErrCommandDenied)
+		}
+	}
+	return nil
+}
+
+// reschedule 按任务当前的配置重新注册cron条目
+func (s *SchedulerService) reschedule(task *model.ScheduledTask) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.schedule(task)
+}
+
+// schedule 移除任务已有的cron条目，任务启用时按cron表达式重新注册，调用方需持有锁
+func (s *SchedulerService) schedule(task *model.ScheduledTask) error {
+	if entryID, ok := s.entries[task.ID]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, task.ID)
+	}
+	if !task.Enabled || !s.Config.ScheduleEnabled {
+		return nil
+	}
+
+	// 执行时重新读取任务，使用最新的配置
+	taskID := task.ID
+	entryID, err := s.cron.AddFunc(task.Cron, func() {
+		task, err := s.loadTask(taskID)
+		if err != nil {
+			log.Printf("读取定时任务 %d 失败: %v", taskID, err)
+			return
+		}
+		s.run(context.Background(), task, model.ScheduleTriggerCron, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCron, err)
+	}
+	s.entries[task.ID] = entryID
+	return nil
+}
+
+// run 执行一次定时任务，记录执行结果并更新任务的最后执行状态
+// actor为nil时以任务用户的身份执行
+func (s *SchedulerService) run(ctx context.Context, task *model.ScheduledTask, trigger string, actor *CommandActor) *model.ScheduledTaskRun {
+	run := &model.ScheduledTaskRun{
+		TaskID:     task.ID,
+		ServerName: task.ServerName,
+		StartedAt:  time.Now(),
+		Trigger:    trigger,
+		Username:   task.Username,
+		Status:     model.ScheduleRunSuccess,
+	}
+	if actor != nil {
+		run.Username = actor.Username
+	}
+
+	if err := s.execute(ctx, task, trigger, actor, run); err != nil {
+		if run.Status == model.ScheduleRunSuccess {
+			run.Status = model.ScheduleRunFailed
+		}
+		run.Error = truncateString(err.Error(), 500)
+	}
+	run.DurationMs = time.Since(run.StartedAt).Milliseconds()
+
+	if err := db.DB.Create(run).Error; err != nil {
+		log.Printf("记录定时任务 %d 执行结果失败: %v", task.ID, err)
+	}
+	err := db.DB.Model(&model.ScheduledTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"last_run_at": run.StartedAt,
+		"last_status": run.Status,
+	}).Error
+	if err != nil {
+		log.Printf("更新定时任务 %d 执行状态失败: %v", task.ID, err)
+	}
+	return run
+}
+
+// execute 以actor的身份执行任务的脚本，actor为nil时使用任务用户，结果写入run
+// 按cron触发且服务器的定时任务已暂停时跳过执行
+func (s *SchedulerService) execute(ctx context.Context, task *model.ScheduledTask, trigger string, actor *CommandActor, run *model.ScheduledTaskRun) error {
+	server, err := s.Servers.GetServerByName(task.ServerName)
+	if err != nil {
+		return err
+	}
+	if trigger == model.ScheduleTriggerCron && server.SchedulesPaused {
+		run.Status = model.ScheduleRunSkipped
+		return errors.New("服务器的定时任务已暂停")
+	}
+
+	if actor == nil {
+		owner, err := s.taskActor(task)
+		if err != nil {
+			return err
+		}
+		actor = owner
+	}
+
+	script := s.script(task)
+	timeout := scheduledRunTimeout
+	for _, step := range script.Steps {
+		timeout += step.Delay
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := s.Commands.ExecuteScript(ctx, *actor, task.ServerName, script)
+	if result != nil {
+		run.Steps = ScriptStepResponses(result)
+	}
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		for _, step := range result.Steps {
+			if step.Err != nil {
+				return fmt.Errorf("第%d步 '%s' 执行失败: %v", step.Index+1, step.Command, step.Err)
+			}
+		}
+	}
+	return nil
+}
+
+// taskActor 返回任务用户，按用户当前的角色检查权限，用户被禁用或删除后任务不再执行
+func (s *SchedulerService) taskActor(task *model.ScheduledTask) (*CommandActor, error) {
+	user, err := s.Users.GetUserByID(task.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("任务用户 '%s' 不可用: %v", task.Username, err)
+	}
+	if user.Status == 0 {
+		return nil, fmt.Errorf("任务用户 '%s' 已被禁用", task.Username)
+	}
+	return &CommandActor{
+		UserID:   user.ID,
+		Username: user.Username,
+		RoleName: user.Role.Name,
+		Source:   model.CommandSourceSchedule,
+	}, nil
+}
+
+// script 将任务的步骤转换为控制器执行的脚本
+func (s *SchedulerService) script(task *model.ScheduledTask) mccontrol.Script {
+	return NewScript(task.Steps, task.Variables, task.OnError, "")
+}
+
+// loadTask 从数据库读取定时任务
+func (s *SchedulerService) loadTask(id uint) (*model.ScheduledTask, error) {
+	var task model.ScheduledTask
+	if err := db.DB.First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// loadAccessibleTask 读取定时任务并检查actor是否有权访问任务所属的服务器
+func (s *SchedulerService) loadAccessibleTask(actor CommandActor, id uint) (*model.ScheduledTask, error) {
+	task, err := s.loadTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if !middleware.CanAccessServer(actor.RoleName, task.ServerName) {
+		return nil, ErrScheduleForbidden
+	}
+	return task, nil
+}
+
+// accessibleServers 获取actor有权访问的服务器名称
+func (s *SchedulerService) accessibleServers(actor CommandActor) ([]string, error) {
+	servers, err := s.Servers.ListServers()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(servers))
+	for _, server := range servers {
+		if middleware.CanAccessServer(actor.RoleName, server.Name) {
+			names = append(names, server.Name)
+		}
+	}
+	return names, nil
+}
+
+// pausedServers 获取定时任务已暂停的服务器
+func (s *SchedulerService) pausedServers() (map[string]bool, error) {
+	var names []string
+	if err := db.DB.Model(&model.Server{}).Where("schedules_paused = ?", true).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+
+	paused := make(map[string]bool, len(names))
+	for _, name := range names {
+		paused[name] = true
+	}
+	return paused, nil
+}
+
+// fillNextRun 填充任务的下次执行时间
+func (s *SchedulerService) fillNextRun(task *model.ScheduledTask, paused map[string]bool) {
+	if paused[task.ServerName] {
+		return
+	}
+
+	s.mutex.Lock()
+	entryID, ok := s.entries[task.ID]
+	s.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	if next := s.cron.Entry(entryID).Next; !next.IsZero() {
+		task.NextRunAt = &next
+	}
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/middleware"
+	"city.newnan/k8s-console/internal/model"
+)
+
+// setupTestDB 使用临时的SQLite数据库替换全局数据库，并初始化权限系统
+func setupTestDB(t *testing.T) {
+	t.Helper()
+
+	previous := db.DB
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	db.DB = database
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+		db.DB = previous
+	})
+
+	err = db.AutoMigrate(&model.User{}, &model.Role{}, &model.Server{}, &model.ServerStatusRecord{}, &model.CommandAudit{}, &model.ScheduledTask{}, &model.ScheduledTaskRun{})
+	if err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+	if err := middleware.InitCasbin("../../config/rbac_model.conf"); err != nil {
+		t.Fatalf("初始化Casbin失败: %v", err)
+	}
+	if err := middleware.InitCommandPolicy("../../config/command_model.conf"); err != nil {
+		t.Fatalf("初始化命令权限策略失败: %v", err)
+	}
+}
+
+// createTestUser 创建角色（如不存在）和属于该角色的用户，返回用户对应的命令发起者
+func createTestUser(t *testing.T, username, roleName string) CommandActor {
+	t.Helper()
+
+	role := model.Role{Name: roleName}
+	if err := db.DB.Where("name = ?", roleName).FirstOrCreate(&role).Error; err != nil {
+		t.Fatalf("创建角色失败: %v", err)
+	}
+	user := model.User{Username: username, Password: "x", Email: username + "@example.com", RoleID: role.ID, Status: 1}
+	if err := db.DB.Create(&user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return CommandActor{UserID: user.ID, Username: username, RoleName: roleName, Source: model.CommandSourceSchedule}
+}
+
+// createTestServer 注册服务器
+func createTestServer(t *testing.T, name string) {
+	t.Helper()
+
+	if err := db.DB.Create(&model.Server{Name: name, Namespace: "minecraft", PodLabelSelector: "app=" + name}).Error; err != nil {
+		t.Fatalf("创建服务器失败: %v", err)
+	}
+}
+
+// newTestScheduler 创建不调度任务的定时任务服务，包含服务器survival和creative，
+// admin角色可以访问所有服务器并执行所有命令，player角色只被授权访问survival且只能执行say命令
+func newTestScheduler(t *testing.T) (*SchedulerService, CommandActor, CommandActor) {
+	t.Helper()
+
+	setupTestDB(t)
+	createTestServer(t, "survival")
+	createTestServer(t, "creative")
+	admin := createTestUser(t, "admin", "admin")
+	player := createTestUser(t, "steve", "player")
+
+	roles := NewRoleService()
+	if _, err := roles.AddRolePermission("admin", "*", "*"); err != nil {
+		t.Fatalf("添加权限失败: %v", err)
+	}
+	if _, err := roles.AddRoleCommand("admin", model.CommandPermission{Command: "*"}); err != nil {
+		t.Fatalf("添加命令策略失败: %v", err)
+	}
+	if _, err := roles.AddRoleServer("player", "survival", "*"); err != nil {
+		t.Fatalf("授权服务器失败: %v", err)
+	}
+	if _, err := roles.AddRoleCommand("player", model.CommandPermission{Command: "say *"}); err != nil {
+		t.Fatalf("添加命令策略失败: %v", err)
+	}
+
+	cfg := &config.Config{ScheduleEnabled: false}
+	return NewSchedulerService(cfg, NewServerRegistry(cfg)), admin, player
+}
+
+// createTestTask 创建定时任务
+func createTestTask(t *testing.T, scheduler *SchedulerService, actor CommandActor, server string, commands ...string) *model.ScheduledTask {
+	t.Helper()
+
+	steps := make([]model.ScriptStepRequest, len(commands))
+	for i, command := range commands {
+		steps[i] = model.ScriptStepRequest{Command: command}
+	}
+	task, err := scheduler.CreateTask(actor, model.ScheduledTaskCreate{
+		Name:       server + " task",
+		ServerName: server,
+		Cron:       "@daily",
+		Steps:      steps,
+	})
+	if err != nil {
+		t.Fatalf("创建定时任务失败: %v", err)
+	}
+	return task
+}
+
+func TestSchedulerCreateTaskChecksAccess(t *testing.T) {
+	scheduler, _, player := newTestScheduler(t)
+
+	createTestTask(t, scheduler, player, "survival", "say 备份开始")
+
+	_, err := scheduler.CreateTask(player, model.ScheduledTaskCreate{
+		Name: "task", ServerName: "creative", Cron: "@daily",
+		Steps: []model.ScriptStepRequest{{Command: "say hi"}},
+	})
+	if !errors.Is(err, ErrScheduleForbidden) {
+		t.Fatalf("在未授权的服务器上创建任务应被拒绝: %v", err)
+	}
+
+	_, err = scheduler.CreateTask(player, model.ScheduledTaskCreate{
+		Name: "task", ServerName: "survival", Cron: "@daily",
+		Steps: []model.ScriptStepRequest{{Command: "op steve"}},
+	})
+	if !errors.Is(err, ErrCommandDenied) {
+		t.Fatalf("包含无权执行的命令的任务应被拒绝: %v", err)
+	}
+
+	_, err = scheduler.CreateTask(player, model.ScheduledTaskCreate{
+		Name: "task", ServerName: "survival", Cron: "every day",
+		Steps: []model.ScriptStepRequest{{Command: "say hi"}},
+	})
+	if !errors.Is(err, ErrInvalidCron) {
+		t.Fatalf("无效的cron表达式应被拒绝: %v", err)
+	}
+}
+
+func TestSchedulerObjectAccess(t *testing.T) {
+	scheduler, admin, player := newTestScheduler(t)
+	survival := createTestTask(t, scheduler, admin, "survival", "say hi")
+	creative := createTestTask(t, scheduler, admin, "creative", "say hi")
+
+	// 列表只包含有权访问的服务器的任务
+	tasks, total, err := scheduler.ListTasks(player, "", 1, 10)
+	if err != nil {
+		t.Fatalf("获取任务列表失败: %v", err)
+	}
+	if total != 1 || len(tasks) != 1 || tasks[0].ID != survival.ID {
+		t.Fatalf("任务列表应只包含survival的任务: total=%d tasks=%+v", total, tasks)
+	}
+	if tasks, total, _ := scheduler.ListTasks(player, "creative", 1, 10); total != 0 || len(tasks) != 0 {
+		t.Fatalf("按未授权的服务器过滤应返回空列表: total=%d", total)
+	}
+	if _, total, _ := scheduler.ListTasks(admin, "", 1, 10); total != 2 {
+		t.Fatalf("管理员应看到所有任务: total=%d", total)
+	}
+
+	// 未授权服务器的任务不能读取、修改、执行或删除
+	if _, err := scheduler.GetTask(player, creative.ID); !errors.Is(err, ErrScheduleForbidden) {
+		t.Errorf("GetTask应被拒绝: %v", err)
+	}
+	if _, err := scheduler.UpdateTask(player, creative.ID, model.ScheduledTaskUpdate{Name: "x"}); !errors.Is(err, ErrScheduleForbidden) {
+		t.Errorf("UpdateTask应被拒绝: %v", err)
+	}
+	if _, err := scheduler.RunTask(context.Background(), player, creative.ID); !errors.Is(err, ErrScheduleForbidden) {
+		t.Errorf("RunTask应被拒绝: %v", err)
+	}
+	if _, _, err := scheduler.ListRuns(player, creative.ID, 1, 10); !errors.Is(err, ErrScheduleForbidden) {
+		t.Errorf("ListRuns应被拒绝: %v", err)
+	}
+	if err := scheduler.DeleteTask(player, creative.ID); !errors.Is(err, ErrScheduleForbidden) {
+		t.Errorf("DeleteTask应被拒绝: %v", err)
+	}
+	if _, err := scheduler.GetTask(admin, creative.ID); err != nil {
+		t.Errorf("被拒绝的删除不应删除任务: %v", err)
+	}
+
+	// 有权访问的服务器的任务可以读取和删除
+	if _, err := scheduler.GetTask(player, survival.ID); err != nil {
+		t.Errorf("GetTask失败: %v", err)
+	}
+	if err := scheduler.DeleteTask(player, survival.ID); err != nil {
+		t.Errorf("DeleteTask失败: %v", err)
+	}
+	if _, err := scheduler.GetTask(admin, survival.ID); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("任务应已删除: %v", err)
+	}
+}
+
+func TestSchedulerRunTaskAsCaller(t *testing.T) {
+	scheduler, admin, player := newTestScheduler(t)
+	task := createTestTask(t, scheduler, admin, "survival", "say 重启", "op steve")
+
+	// 任务用户是管理员，但手动执行按调用者的权限检查
+	if _, err := scheduler.RunTask(context.Background(), player, task.ID); !errors.Is(err, ErrCommandDenied) {
+		t.Fatalf("调用者无权执行的命令应被拒绝: %v", err)
+	}
+	if _, total, _ := scheduler.ListRuns(admin, task.ID, 1, 10); total != 0 {
+		t.Fatalf("被拒绝的执行不应记录: total=%d", total)
+	}
+
+	// 测试环境没有Kubernetes集群，执行失败但记录执行者
+	run, err := scheduler.RunTask(context.Background(), admin, task.ID)
+	if err != nil {
+		t.Fatalf("执行任务失败: %v", err)
+	}
+	if run.Username != "admin" || run.Trigger != model.ScheduleTriggerManual || run.Status != model.ScheduleRunFailed {
+		t.Fatalf("执行记录错误: %+v", run)
+	}
+}
+
+func TestSchedulerCronRunSkipsPausedServer(t *testing.T) {
+	scheduler, admin, _ := newTestScheduler(t)
+	task := createTestTask(t, scheduler, admin, "survival", "say hi")
+
+	if err := scheduler.SetServerPaused("survival", true); err != nil {
+		t.Fatalf("暂停定时任务失败: %v", err)
+	}
+	run := scheduler.run(context.Background(), task, model.ScheduleTriggerCron, nil)
+	if run.Status != model.ScheduleRunSkipped || run.Username != "admin" {
+		t.Fatalf("服务器暂停时按cron触发应跳过: %+v", run)
+	}
+
+	// 任务用户被禁用后不再执行
+	if err := scheduler.SetServerPaused("survival", false); err != nil {
+		t.Fatalf("恢复定时任务失败: %v", err)
+	}
+	if err := db.DB.Model(&model.User{}).Where("id = ?", admin.UserID).Update("status", 0).Error; err != nil {
+		t.Fatalf("禁用用户失败: %v", err)
+	}
+	run = scheduler.run(context.Background(), task, model.ScheduleTriggerCron, nil)
+	if run.Status != model.ScheduleRunFailed || run.Error == "" {
+		t.Fatalf("任务用户被禁用时应执行失败: %+v", run)
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/metrics"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// errRegistryClosed 注册表已关闭，不再创建控制器
+var errRegistryClosed = errors.New("服务器注册表已关闭")
+
+// ServerRegistry 管理已注册服务器对应的Minecraft控制器
+// 控制器在首次访问时按数据库中的配置创建，并在配置变更或删除时释放
+type ServerRegistry struct {
+	config        *config.Config
+	serverService *ServerService
+	statusHistory *StatusHistoryService
+	controllers   map[string]*mccontrol.MinecraftController
+	builds        map[string]*controllerBuild // 正在创建的控制器，同一服务器同时只创建一个
+	closed        bool
+	mutex         sync.Mutex
+
+	// newController 创建控制器，测试时替换
+	newController func(config mccontrol.K8sConfig, gamePort, rconPort int, rconPassword string) (*mccontrol.MinecraftController, error)
+}
+
+// controllerBuild 一次控制器创建，完成后关闭done
+type controllerBuild struct {
+	done       chan struct{}
+	controller *mccontrol.MinecraftController
+	err        error
+	superseded bool // 创建期间服务器被释放（配置变更或删除），结果已丢弃
+}
+
+// NewServerRegistry 创建服务器注册表
+func NewServerRegistry(cfg *config.Config) *ServerRegistry {
+	registry := &ServerRegistry{
+		config:        cfg,
+		controllers:   make(map[string]*mccontrol.MinecraftController),
+		builds:        make(map[string]*controllerBuild),
+		newController: mccontrol.NewMinecraftController,
+	}
+	registry.serverService = NewServerService(cfg, registry)
+	registry.statusHistory = NewStatusHistoryService(cfg)
+	return registry
+}
+
+// Get 获取指定服务器的控制器，如果尚未创建则按配置创建
+// 创建控制器需要访问Kubernetes，可能耗时较长，创建期间不持有注册表的锁，其他服务器的请求不受影响；
+// 同一服务器的并发请求等待同一次创建的结果
+func (r *ServerRegistry) Get(name string) (*mccontrol.MinecraftController, error) {
+	for {
+		r.mutex.Lock()
+		if r.closed {
+			r.mutex.Unlock()
+			return nil, errRegistryClosed
+		}
+		if controller, ok := r.controllers[name]; ok {
+			r.mutex.Unlock()
+			return controller, nil
+		}
+		if build, ok := r.builds[name]; ok {
+			r.mutex.Unlock()
+			<-build.done
+			if build.superseded {
+				continue // 按新的配置重新创建
+			}
+			return build.controller, build.err
+		}
+		build := &controllerBuild{done: make(chan struct{})}
+		r.builds[name] = build
+		r.mutex.Unlock()
+
+		build.controller, build.err = r.build(name)
+
+		r.mutex.Lock()
+		if r.builds[name] == build {
+			delete(r.builds, name)
+			if build.controller != nil {
+				r.controllers[name] = build.controller
+			}
+		} else {
+			// 创建期间服务器被释放，丢弃按旧配置创建的控制器
+			build.superseded = true
+		}
+		r.mutex.Unlock()
+		close(build.done)
+
+		if !build.superseded {
+			return build.controller, build.err
+		}
+		if build.controller != nil {
+			build.controller.Close()
+		}
+	}
+}
+
+// build 按数据库中的配置创建并配置控制器
+func (r *ServerRegistry) build(name string) (*mccontrol.MinecraftController, error) {
+	server, err := r.serverService.GetServerByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sConfig := k8sConfigForServer(server)
+	k8sConfig.DisablePodWatch = !r.config.MCPodWatch
+	controller, err := r.newController(k8sConfig, server.GamePort, server.RconPort, server.RconPassword)
+	if controller == nil {
+		return nil, err
+	}
+	if err != nil {
+		// 控制器创建成功但Pod信息或RCON密码初始化失败时仍可使用，后续操作会重新读取
+		log.Printf("服务器 %s 初始化失败: %v", name, err)
+	}
+
+	controller.SetMetricsObserver(metrics.NewServerObserver(name))
+	controller.SetOutputCapture(r.config.MCOutputCaptureWindow, r.config.MCOutputCaptureQuiet)
+	controller.SetExecutorProbeInterval(r.config.MCExecutorProbeInterval)
+	controller.SetRconTimeouts(r.config.MCRconReadTimeout, r.config.MCRconWriteTimeout)
+	controller.SetRconPoolOptions(mccontrol.RconPoolOptions{
+		MaxSize:             r.config.MCRconPoolSize,
+		IdleTimeout:         r.config.MCRconPoolIdleTimeout,
+		MaxLifetime:         r.config.MCRconPoolMaxLifetime,
+		HealthCheckInterval: r.config.MCRconPoolHealthCheck,
+	})
+
+	// 记录Pod切换，控制器关闭时通道关闭
+	podChanges := controller.SubscribePodChanges()
+	go func() {
+		for change := range podChanges {
+			if change.Current != "" {
+				log.Printf("服务器 %s 切换到Pod %s", name, change.Current)
+			}
+		}
+	}()
+
+	// 记录定期状态监控的检测结果，按需的状态检测不写入历史
+	controller.SetStatusRecorder(func(status mccontrol.ServerStatus) {
+		r.statusHistory.Record(name, status)
+	})
+
+	if r.config.MCStatusInterval > 0 {
+		controller.StartStatusMonitoring(r.config.MCStatusInterval)
+	}
+	// 闲置时间根据状态检测结果计算，未启动状态监控时不会自动缩容
+	controller.SetIdleTimeout(time.Duration(server.IdleTimeout) * time.Minute)
+	return controller, nil
+}
+
+// Preload 在后台创建指定服务器的控制器，使状态监控无需等待首次访问即可开始
+func (r *ServerRegistry) Preload(name string) {
+	if r.config.MCStatusInterval <= 0 {
+		return
+	}
+	go func() {
+		if _, err := r.Get(name); err != nil {
+			log.Printf("创建服务器 %s 的控制器失败: %v", name, err)
+		}
+	}()
+}
+
+// PreloadAll 在后台创建所有已注册服务器的控制器
+func (r *ServerRegistry) PreloadAll() error {
+	servers, err := r.serverService.ListServers()
+	if err != nil {
+		return err
+	}
+	for _, server := range servers {
+		r.Preload(server.Name)
+	}
+	return nil
+}
+
+// Controllers 返回已创建的控制器快照
+func (r *ServerRegistry) Controllers() map[string]*mccontrol.MinecraftController {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	controllers := make(map[string]*mccontrol.MinecraftController, len(r.controllers))
+	for name, controller := range r.controllers {
+		controllers[name] = controller
+	}
+	return controllers
+}
+
+// Evict 释放指定服务器的控制器
+func (r *ServerRegistry) Evict(name string) {
+	r.mutex.Lock()
+	controller, ok := r.controllers[name]
+	delete(r.controllers, name)
+	delete(r.builds, name) // 正在进行的创建完成后丢弃结果
+	r.mutex.Unlock()
+
+	if ok {
+		controller.CloseAllCommandSessions()
+		controller.Close()
+	}
+}
+
+// Close 释放所有控制器
+func (r *ServerRegistry) Close() {
+	r.mutex.Lock()
+	controllers := r.controllers
+	r.controllers = make(map[string]*mccontrol.MinecraftController)
+	r.builds = make(map[string]*controllerBuild)
+	r.closed = true
+	r.mutex.Unlock()
+
+	for _, controller := range controllers {
+		controller.CloseAllCommandSessions()
+		controller.Close()
+	}
+}
+
+// k8sConfigForServer 将服务器注册信息转换为K8s配置
+func k8sConfigForServer(server *model.Server) mccontrol.K8sConfig {
+	return mccontrol.K8sConfig{
+		RunMode:              server.RunMode,
+		KubeconfigPath:       server.KubeconfigPath,
+		Namespace:            server.Namespace,
+		PodLabelSelector:     server.PodLabelSelector,
+		ServiceLabelSelector: server.ServiceLabelSelector,
+		ContainerName:        server.ContainerName,
+		RconSecretName:       server.RconSecretName,
+		RconSecretKey:        server.RconSecretKey,
+		RconPasswordEnv:      server.RconPasswordEnv,
+		RconPropertiesPath:   server.RconPropertiesPath,
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// fakeControllerBuilds 代替创建控制器，survival服务器的创建阻塞到release关闭，所有创建都返回errClusterUnavailable
+type fakeControllerBuilds struct {
+	started chan struct{}
+	release chan struct{}
+	mutex   sync.Mutex
+	calls   map[string]int
+}
+
+var errClusterUnavailable = errors.New("连接集群失败")
+
+func newTestRegistry(t *testing.T) (*ServerRegistry, *fakeControllerBuilds) {
+	t.Helper()
+
+	setupTestDB(t)
+	createTestServer(t, "survival")
+	createTestServer(t, "creative")
+
+	builds := &fakeControllerBuilds{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+		calls:   make(map[string]int),
+	}
+	registry := NewServerRegistry(&config.Config{})
+	registry.newController = func(cfg mccontrol.K8sConfig, gamePort, rconPort int, rconPassword string) (*mccontrol.MinecraftController, error) {
+		builds.mutex.Lock()
+		builds.calls[cfg.PodLabelSelector]++
+		builds.mutex.Unlock()
+		if cfg.PodLabelSelector == "app=survival" {
+			builds.started <- struct{}{}
+			<-builds.release
+		}
+		return nil, errClusterUnavailable
+	}
+	return registry, builds
+}
+
+// count 返回创建指定服务器控制器的次数
+func (b *fakeControllerBuilds) count(server string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.calls["app="+server]
+}
+
+// getAsync 在后台获取控制器，返回接收结果的通道
+func getAsync(registry *ServerRegistry, name string) chan error {
+	result := make(chan error, 1)
+	go func() {
+		_, err := registry.Get(name)
+		result <- err
+	}()
+	return result
+}
+
+func waitResult(t *testing.T, result chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("获取控制器超时")
+		return nil
+	}
+}
+
+func TestServerRegistryBuildsOutsideLock(t *testing.T) {
+	registry, builds := newTestRegistry(t)
+
+	first := getAsync(registry, "survival")
+	<-builds.started
+	second := getAsync(registry, "survival")
+
+	// 一个服务器的控制器创建期间，其他服务器和控制器快照不被阻塞
+	if err := waitResult(t, getAsync(registry, "creative")); !errors.Is(err, errClusterUnavailable) {
+		t.Fatalf("其他服务器应直接创建: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		registry.Controllers()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("创建控制器期间获取快照不应等待")
+	}
+
+	// 同一服务器的并发请求共享一次创建的结果
+	select {
+	case err := <-second:
+		t.Fatalf("创建完成前不应返回: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(builds.release)
+	for _, result := range []chan error{first, second} {
+		if err := waitResult(t, result); !errors.Is(err, errClusterUnavailable) {
+			t.Fatalf("应返回创建失败的原因: %v", err)
+		}
+	}
+	if calls := builds.count("survival"); calls != 1 {
+		t.Fatalf("同一服务器的并发请求应只创建一次控制器, 实际%d次", calls)
+	}
+}
+
+func TestServerRegistryEvictDuringBuild(t *testing.T) {
+	registry, builds := newTestRegistry(t)
+
+	// 创建期间服务器配置变更，丢弃按旧配置创建的结果并重新创建
+	result := getAsync(registry, "survival")
+	<-builds.started
+	registry.Evict("survival")
+	close(builds.release)
+	if err := waitResult(t, result); !errors.Is(err, errClusterUnavailable) {
+		t.Fatalf("应返回重新创建的结果: %v", err)
+	}
+	if calls := builds.count("survival"); calls != 2 {
+		t.Fatalf("创建期间被释放后应按新配置重新创建, 实际创建%d次", calls)
+	}
+
+	// 注册表关闭后不再创建
+	registry.Close()
+	if _, err := registry.Get("creative"); !errors.Is(err, errRegistryClosed) {
+		t.Fatalf("注册表关闭后应返回错误: %v", err)
+	}
+}
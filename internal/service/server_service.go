@@ -0,0 +1,251 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/middleware"
+	"city.newnan/k8s-console/internal/model"
+)
+
+// ErrServerNotFound 服务器不存在
+var ErrServerNotFound = errors.New("服务器不存在")
+
+// serverNamePattern 服务器名称格式，名称会出现在API路径和权限策略中
+var serverNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ServerService 提供服务器注册信息相关功能
+type ServerService struct {
+	Config   *config.Config
+	Registry *ServerRegistry
+}
+
+// NewServerService 创建服务器服务实例
+func NewServerService(cfg *config.Config, registry *ServerRegistry) *ServerService {
+	return &ServerService{
+		Config:   cfg,
+		Registry: registry,
+	}
+}
+
+// CreateServer 注册新服务器
+func (s *ServerService) CreateServer(req model.ServerCreate) (*model.Server, error) {
+	if !serverNamePattern.MatchString(req.Name) {
+		return nil, errors.New("服务器名称只能包含小写字母、数字和连字符，且必须以字母或数字开头和结尾")
+	}
+
+	// 检查名称是否已存在
+	var existingServer model.Server
+	if err := db.DB.Where("name = ?", req.Name).First(&existingServer).Error; err == nil {
+		return nil, errors.New("服务器名称已存在")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	server := model.Server{
+		Name:                 req.Name,
+		Description:          req.Description,
+		RunMode:              req.RunMode,
+		KubeconfigPath:       req.KubeconfigPath,
+		Namespace:            req.Namespace,
+		PodLabelSelector:     req.PodLabelSelector,
+		ServiceLabelSelector: req.ServiceLabelSelector,
+		ContainerName:        req.ContainerName,
+		GamePort:             req.GamePort,
+		RconPort:             req.RconPort,
+		RconPassword:         req.RconPassword,
+		RconSecretName:       req.RconSecretName,
+		RconSecretKey:        req.RconSecretKey,
+		RconPasswordEnv:      req.RconPasswordEnv,
+		RconPropertiesPath:   req.RconPropertiesPath,
+		IdleTimeout:          req.IdleTimeout,
+	}
+	s.applyDefaults(&server)
+
+	if err := db.DB.Create(&server).Error; err != nil {
+		return nil, err
+	}
+
+	s.Registry.Preload(server.Name)
+	return &server, nil
+}
+
+// GetServerByName 根据名称获取服务器
+func (s *ServerService) GetServerByName(name string) (*model.Server, error) {
+	var server model.Server
+	if err := db.DB.Where("name = ?", name).First(&server).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServerNotFound
+		}
+		return nil, err
+	}
+	return &server, nil
+}
+
+// UpdateServer 更新服务器信息
+// 更新后会丢弃已创建的控制器，并按新配置重新创建
+func (s *ServerService) UpdateServer(name string, update model.ServerUpdate) (*model.Server, error) {
+	server, err := s.GetServerByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Description != "" {
+		server.Description = update.Description
+	}
+	if update.RunMode != "" {
+		server.RunMode = update.RunMode
+	}
+	if update.KubeconfigPath != "" {
+		server.KubeconfigPath = update.KubeconfigPath
+	}
+	if update.Namespace != "" {
+		server.Namespace = update.Namespace
+	}
+	if update.PodLabelSelector != "" {
+		server.PodLabelSelector = update.PodLabelSelector
+	}
+	if update.ServiceLabelSelector != "" {
+		server.ServiceLabelSelector = update.ServiceLabelSelector
+	}
+	if update.ContainerName != "" {
+		server.ContainerName = update.ContainerName
+	}
+	if update.GamePort != 0 {
+		server.GamePort = update.GamePort
+	}
+	if update.RconPort != 0 {
+		server.RconPort = update.RconPort
+	}
+	if update.RconPassword != "" {
+		server.RconPassword = update.RconPassword
+	}
+	if update.RconSecretName != "" {
+		server.RconSecretName = update.RconSecretName
+	}
+	if update.RconSecretKey != "" {
+		server.RconSecretKey = update.RconSecretKey
+	}
+	if update.RconPasswordEnv != "" {
+		server.RconPasswordEnv = update.RconPasswordEnv
+	}
+	if update.RconPropertiesPath != "" {
+		server.RconPropertiesPath = update.RconPropertiesPath
+	}
+	if update.IdleTimeout != nil {
+		server.IdleTimeout = *update.IdleTimeout
+	}
+
+	// 保存更新
+	if err := db.DB.Save(server).Error; err != nil {
+		return nil, err
+	}
+
+	s.Registry.Evict(name)
+	s.Registry.Preload(name)
+	return server, nil
+}
+
+// ListServers 获取所有服务器
+func (s *ServerService) ListServers() ([]model.Server, error) {
+	var servers []model.Server
+	if err := db.DB.Order("name").Find(&servers).Error; err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// ListAccessibleServers 获取角色有权访问的服务器（分页）
+func (s *ServerService) ListAccessibleServers(roleName string, page, pageSize int) ([]model.Server, int64, error) {
+	servers, err := s.ListServers()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 按权限过滤，服务器数量通常很少，因此在内存中分页
+	accessible := make([]model.Server, 0, len(servers))
+	for _, server := range servers {
+		if middleware.CanAccessServer(roleName, server.Name) {
+			accessible = append(accessible, server)
+		}
+	}
+
+	total := int64(len(accessible))
+	start := (page - 1) * pageSize
+	if start < 0 || start >= len(accessible) {
+		return []model.Server{}, total, nil
+	}
+	end := start + pageSize
+	if end > len(accessible) {
+		end = len(accessible)
+	}
+
+	return accessible[start:end], total, nil
+}
+
+// DeleteServer 删除服务器
+func (s *ServerService) DeleteServer(name string) error {
+	server, err := s.GetServerByName(name)
+	if err != nil {
+		return err
+	}
+
+	if err := db.DB.Unscoped().Delete(server).Error; err != nil {
+		return err
+	}
+
+	s.Registry.Evict(name)
+	return nil
+}
+
+// SeedDefaultServer 如果还没有注册任何服务器，则根据配置文件创建默认服务器
+func (s *ServerService) SeedDefaultServer() error {
+	var count int64
+	if err := db.DB.Model(&model.Server{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err := s.CreateServer(model.ServerCreate{
+		Name:                 s.Config.MCServerName,
+		Description:          "默认服务器",
+		RunMode:              s.Config.MCRunMode,
+		KubeconfigPath:       s.Config.MCKubeconfigPath,
+		Namespace:            s.Config.MCNamespace,
+		PodLabelSelector:     s.Config.MCPodLabelSelector,
+		ServiceLabelSelector: s.Config.MCServiceLabelSelector,
+		ContainerName:        s.Config.MCContainerName,
+		GamePort:             s.Config.MCGamePort,
+		RconPort:             s.Config.MCRconPort,
+		RconPassword:         s.Config.MCRconPassword,
+		RconSecretName:       s.Config.MCRconSecretName,
+		RconSecretKey:        s.Config.MCRconSecretKey,
+		RconPasswordEnv:      s.Config.MCRconPasswordEnv,
+		RconPropertiesPath:   s.Config.MCRconPropertiesPath,
+		IdleTimeout:          int(s.Config.MCIdleTimeout / time.Minute),
+	})
+	return err
+}
+
+// applyDefaults 为未填写的字段设置默认值
+func (s *ServerService) applyDefaults(server *model.Server) {
+	if server.RunMode == "" {
+		server.RunMode = s.Config.MCRunMode
+	}
+	if server.ContainerName == "" {
+		server.ContainerName = s.Config.MCContainerName
+	}
+	if server.GamePort == 0 {
+		server.GamePort = 25565
+	}
+	if server.RconPort == 0 {
+		server.RconPort = 25575
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+// sessionKey 标识一个服务器上的命令会话
+type sessionKey struct {
+	server    string
+	sessionID string
+}
+
+// SessionService 管理通过REST接口创建的命令会话
+// 会话只能由创建者列出、使用和关闭，其他用户访问时视为会话不存在；
+// WebSocket控制台等其他入口创建的会话不通过此服务访问
+type SessionService struct {
+	Config   *config.Config
+	Registry *ServerRegistry
+	Commands *CommandService
+	owners   map[sessionKey]uint // 会话创建者的用户ID
+	mutex    sync.Mutex
+}
+
+// NewSessionService 创建命令会话服务实例
+func NewSessionService(cfg *config.Config, registry *ServerRegistry) *SessionService {
+	return &SessionService{
+		Config:   cfg,
+		Registry: registry,
+		Commands: NewCommandService(cfg, registry),
+		owners:   make(map[sessionKey]uint),
+	}
+}
+
+// CreateSession 为用户创建命令会话，pod不为空时会话固定使用该Pod
+func (s *SessionService) CreateSession(actor CommandActor, server, pod string, idleTimeout time.Duration, executorType mccontrol.ExecutorType) (*mccontrol.CommandSession, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+
+	var session *mccontrol.CommandSession
+	if pod != "" {
+		session, err = controller.CreatePodCommandSession(pod, idleTimeout, executorType)
+	} else {
+		session, err = controller.CreateCommandSession(idleTimeout, executorType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(server, controller)
+	s.owners[sessionKey{server: server, sessionID: session.GetID()}] = actor.UserID
+	return session, nil
+}
+
+// ListSessions 列出用户在服务器上创建的活跃命令会话
+func (s *SessionService) ListSessions(actor CommandActor, server string) ([]string, error) {
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.prune(server, controller)
+	ids := make([]string, 0)
+	for key, owner := range s.owners {
+		if key.server == server && owner == actor.UserID {
+			ids = append(ids, key.sessionID)
+		}
+	}
+	return ids, nil
+}
+
+// ExecuteCommand 在用户的命令会话中执行一条命令，ctx取消时中止执行
+func (s *SessionService) ExecuteCommand(ctx context.Context, actor CommandActor, server, sessionID, command string) (*mccontrol.CommandResult, error) {
+	if err := s.checkOwner(actor, server, sessionID); err != nil {
+		return nil, err
+	}
+	return s.Commands.SessionExecuteCommand(ctx, actor, server, sessionID, command)
+}
+
+// CloseSession 关闭用户的命令会话
+func (s *SessionService) CloseSession(actor CommandActor, server, sessionID string) error {
+	if err := s.checkOwner(actor, server, sessionID); err != nil {
+		return err
+	}
+	controller, err := s.Registry.Get(server)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	delete(s.owners, sessionKey{server: server, sessionID: sessionID})
+	s.mutex.Unlock()
+
+	return controller.CloseCommandSession(sessionID)
+}
+
+// checkOwner 检查会话是否由用户创建，不是时返回mccontrol.ErrSessionNotFound，不暴露其他用户的会话是否存在
+func (s *SessionService) checkOwner(actor CommandActor, server, sessionID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if owner, ok := s.owners[sessionKey{server: server, sessionID: sessionID}]; !ok || owner != actor.UserID {
+		return fmt.Errorf("%w: %s", mccontrol.ErrSessionNotFound, sessionID)
+	}
+	return nil
+}
+
+// prune 移除服务器上已关闭（如空闲超时、控制器重建）的会话记录，调用方需持有锁
+func (s *SessionService) prune(server string, controller *mccontrol.MinecraftController) {
+	active := make(map[string]bool)
+	for _, id := range controller.ListCommandSessions() {
+		active[id] = true
+	}
+	for key := range s.owners {
+		if key.server == server && !active[key.sessionID] {
+			delete(s.owners, key)
+		}
+	}
+}
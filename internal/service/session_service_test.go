@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"city.newnan/k8s-console/pkg/mccontrol"
+)
+
+func TestSessionServiceOwnership(t *testing.T) {
+	registry, builds := newTestRegistry(t)
+	close(builds.release)
+	owner := createTestUser(t, "owner", "admin")
+	other := createTestUser(t, "other", "admin")
+
+	sessions := NewSessionService(registry.config, registry)
+	sessions.owners[sessionKey{server: "survival", sessionID: "session-1"}] = owner.UserID
+
+	// 其他用户的会话和不存在的会话一样返回ErrSessionNotFound，且不访问控制器
+	for _, key := range []struct{ server, sessionID string }{
+		{"survival", "session-1"},
+		{"survival", "missing"},
+	} {
+		if _, err := sessions.ExecuteCommand(context.Background(), other, key.server, key.sessionID, "list"); !errors.Is(err, mccontrol.ErrSessionNotFound) {
+			t.Fatalf("执行其他用户的会话 %q 应返回ErrSessionNotFound: %v", key.sessionID, err)
+		}
+		if err := sessions.CloseSession(other, key.server, key.sessionID); !errors.Is(err, mccontrol.ErrSessionNotFound) {
+			t.Fatalf("关闭其他用户的会话 %q 应返回ErrSessionNotFound: %v", key.sessionID, err)
+		}
+	}
+	// 同名会话在其他服务器上不属于该用户
+	if err := sessions.CloseSession(owner, "creative", "session-1"); !errors.Is(err, mccontrol.ErrSessionNotFound) {
+		t.Fatalf("会话应按服务器区分: %v", err)
+	}
+	if calls := builds.count("survival") + builds.count("creative"); calls != 0 {
+		t.Fatalf("检查会话所有者前不应创建控制器, 实际%d次", calls)
+	}
+
+	// 创建者可以使用自己的会话
+	if _, err := sessions.ExecuteCommand(context.Background(), owner, "survival", "session-1", "list"); !errors.Is(err, errClusterUnavailable) {
+		t.Fatalf("创建者执行命令应访问控制器: %v", err)
+	}
+	if err := sessions.CloseSession(owner, "survival", "session-1"); !errors.Is(err, errClusterUnavailable) {
+		t.Fatalf("创建者关闭会话应访问控制器: %v", err)
+	}
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/model"
+	"city.newnan/k8s-console/pkg/mccontrol"
+	"gorm.io/gorm"
+)
+
+// maxStatusPoints 未指定时间粒度时，时间序列最多包含的数据点数量
+const maxStatusPoints = 500
+
+// statusHistoryBatchSize 统计和降采样时每批读取的记录数量
+const statusHistoryBatchSize = 500
+
+// defaultStatusWindow 未指定起始时间时的时间窗口长度
+const defaultStatusWindow = 24 * time.Hour
+
+// errInvalidStatusStep 时间粒度无效
+var errInvalidStatusStep = errors.New("无效的时间粒度，最小为1m")
+
+// StatusHistoryService 提供服务器状态历史相关功能
+type StatusHistoryService struct {
+	Config *config.Config
+}
+
+// NewStatusHistoryService 创建状态历史服务实例
+func NewStatusHistoryService(cfg *config.Config) *StatusHistoryService {
+	return &StatusHistoryService{
+		Config: cfg,
+	}
+}
+
+// Record 记录一次状态检测结果
+func (s *StatusHistoryService) Record(serverName string, status mccontrol.ServerStatus) {
+	checkedAt := status.LastChecked
+	if checkedAt.IsZero() {
+		checkedAt = time.Now()
+	}
+
+	record := model.ServerStatusRecord{
+		ServerName:  serverName,
+		CheckedAt:   checkedAt,
+		Online:      status.Online,
+		PodName:     status.PodName,
+		PodPhase:    status.PodStatus,
+		Error:       truncateString(status.LastError, 500),
+		Samples:     1,
+		Downsampled: false,
+	}
+	if status.Online {
+		record.Players = float64(status.Players)
+		record.PeakPlayers = status.Players
+		record.MaxPlayers = status.MaxPlayers
+		record.Latency = float64(status.Latency)
+		record.OnlineSamples = 1
+	}
+
+	if err := db.DB.Create(&record).Error; err != nil {
+		log.Printf("记录服务器 %s 状态失败: %v", serverName, err)
+	}
+}
+
+// ParseStatusWindow 解析状态历史的时间窗口（RFC3339格式）
+// 结束时间默认为now，起始时间默认为结束时间之前24小时
+func ParseStatusWindow(fromValue, toValue string, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if toValue != "" {
+		parsed, err := time.Parse(time.RFC3339, toValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("无效的结束时间: %w", err)
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultStatusWindow)
+	if fromValue != "" {
+		parsed, err := time.Parse(time.RFC3339, fromValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("无效的起始时间: %w", err)
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("起始时间必须早于结束时间")
+	}
+	return from, to, nil
+}
+
+// ParseStatusStep 解析时间粒度（如5m、1h），为空返回0表示自动选择
+func ParseStatusStep(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	step, err := time.ParseDuration(value)
+	if err != nil || step < time.Minute {
+		return 0, errInvalidStatusStep
+	}
+	return step, nil
+}
+
+// statusBucket 时间序列中一个数据点的累计值
+type statusBucket struct {
+	point         model.StatusPoint
+	onlineSamples int
+	playerSum     float64
+	latencySum    float64
+}
+
+// add 将一条记录计入数据点
+func (b *statusBucket) add(record model.ServerStatusRecord) {
+	b.point.Samples += record.Samples
+	b.onlineSamples += record.OnlineSamples
+	b.playerSum += record.Players * float64(record.Samples)
+	b.latencySum += record.Latency * float64(record.OnlineSamples)
+	if record.PeakPlayers > b.point.PeakPlayers {
+		b.point.PeakPlayers = record.PeakPlayers
+	}
+	if record.MaxPlayers > b.point.MaxPlayers {
+		b.point.MaxPlayers = record.MaxPlayers
+	}
+}
+
+// result 计算数据点的在线率和平均值
+func (b *statusBucket) result() model.StatusPoint {
+	point := b.point
+	point.Uptime = percentage(float64(b.onlineSamples), point.Samples)
+	if point.Samples > 0 {
+		point.Players = round2(b.playerSum / float64(point.Samples))
+	}
+	if b.onlineSamples > 0 {
+		point.Latency = round2(b.latencySum / float64(b.onlineSamples))
+	}
+	return point
+}
+
+// GetHistory 获取时间窗口内的状态时间序列
+// step为数据点的时间粒度，为0则自动选择，使数据点数量不超过maxStatusPoints
+// 记录分批读取并累计到所属的数据点，内存占用只与数据点数量有关
+func (s *StatusHistoryService) GetHistory(serverName string, from, to time.Time, step time.Duration) ([]model.StatusPoint, error) {
+	if step <= 0 {
+		step = autoStep(to.Sub(from))
+	}
+
+	buckets := make(map[int64]*statusBucket)
+	var records []model.ServerStatusRecord
+	err := db.DB.Where("server_name = ? AND checked_at >= ? AND checked_at < ?", serverName, from, to).
+		FindInBatches(&records, statusHistoryBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, record := range records {
+				index := int64(record.CheckedAt.Sub(from) / step)
+				bucket, ok := buckets[index]
+				if !ok {
+					bucket = &statusBucket{point: model.StatusPoint{Time: from.Add(time.Duration(index) * step)}}
+					buckets[index] = bucket
+				}
+				bucket.add(record)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int64, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	points := make([]model.StatusPoint, 0, len(indexes))
+	for _, index := range indexes {
+		points = append(points, buckets[index].result())
+	}
+	return points, nil
+}
+
+// GetUptime 统计时间窗口内的在线率和离线时段
+func (s *StatusHistoryService) GetUptime(serverName string, from, to time.Time) (*model.UptimeResponse, error) {
+	records, err := s.listRecords(serverName, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.UptimeResponse{
+		From:    from,
+		To:      to,
+		Outages: make([]model.StatusOutage, 0),
+	}
+
+	var outage *model.StatusOutage
+	for _, record := range records {
+		result.Samples += record.Samples
+		result.OnlineSamples += record.OnlineSamples
+
+		if record.OnlineSamples == 0 {
+			// 开始或延续离线时段
+			if outage == nil {
+				outage = &model.StatusOutage{Start: record.CheckedAt, Error: record.Error}
+			}
+			outage.End = record.CheckedAt
+			continue
+		}
+
+		if outage != nil {
+			outage.End = record.CheckedAt
+			result.Outages = append(result.Outages, *outage)
+			outage = nil
+		}
+	}
+	if outage != nil {
+		result.Outages = append(result.Outages, *outage)
+	}
+
+	result.Uptime = percentage(float64(result.OnlineSamples), result.Samples)
+	return result, nil
+}
+
+// Cleanup 执行保留策略：将超过原始保留时长的记录降采样，删除超过保留时长的记录
+// 原始记录分批降采样，每批在一个事务中写入汇总记录并删除对应的原始记录
+func (s *StatusHistoryService) Cleanup() error {
+	now := time.Now()
+
+	if s.Config.MCStatusRetention > 0 {
+		if err := db.DB.Where("checked_at < ?", now.Add(-s.Config.MCStatusRetention)).
+			Delete(&model.ServerStatusRecord{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if s.Config.MCStatusRawRetention <= 0 || s.Config.MCStatusDownsampleInterval <= 0 {
+		return nil
+	}
+
+	// 只处理完整的时间段，避免同一时间段被多次降采样
+	interval := s.Config.MCStatusDownsampleInterval
+	cutoff := now.Add(-s.Config.MCStatusRawRetention).Truncate(interval)
+
+	for {
+		var records []model.ServerStatusRecord
+		if err := db.DB.Where("downsampled = ? AND checked_at < ?", false, cutoff).
+			Order("server_name, checked_at, id").Limit(statusHistoryBatchSize).Find(&records).Error; err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		if len(records) == statusHistoryBatchSize {
+			records = completeBuckets(records, interval)
+		}
+		if err := s.downsample(records, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// downsample 在一个事务中写入记录的汇总记录并删除原始记录
+func (s *StatusHistoryService) downsample(records []model.ServerStatusRecord, interval time.Duration) error {
+	summaries := downsampleRecords(records, interval)
+	ids := make([]uint, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+
+	tx := db.DB.Begin()
+	if err := tx.CreateInBatches(summaries, 100).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Delete(&model.ServerStatusRecord{}, ids).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// completeBuckets 去掉末尾可能延续到下一批的时间段，使同一时间段的记录在同一批中汇总
+// records需按服务器名称和时间排序，全部属于同一时间段时原样返回
+func completeBuckets(records []model.ServerStatusRecord, interval time.Duration) []model.ServerStatusRecord {
+	last := records[len(records)-1]
+	bucket := last.CheckedAt.Truncate(interval)
+	end := len(records)
+	for end > 0 && records[end-1].ServerName == last.ServerName && records[end-1].CheckedAt.Truncate(interval).Equal(bucket) {
+		end--
+	}
+	if end == 0 {
+		return records
+	}
+	return records[:end]
+}
+
+// StartCleanup 定期执行保留策略
+func (s *StatusHistoryService) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Cleanup(); err != nil {
+				log.Printf("清理服务器状态历史失败: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// listRecords 获取时间窗口内的状态记录
+func (s *StatusHistoryService) listRecords(serverName string, from, to time.Time) ([]model.ServerStatusRecord, error) {
+	var records []model.ServerStatusRecord
+	err := db.DB.Where("server_name = ? AND checked_at >= ? AND checked_at < ?", serverName, from, to).
+		Order("checked_at").Find(&records).Error
+	return records, err
+}
+
+// downsampleRecords 将原始记录按服务器和时间段汇总，records需按服务器名称和时间排序
+func downsampleRecords(records []model.ServerStatusRecord, interval time.Duration) []model.ServerStatusRecord {
+	var summaries []model.ServerStatusRecord
+	var current *model.ServerStatusRecord
+	var latencySum float64
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Players = round2(current.Players / float64(current.Samples))
+		if current.OnlineSamples > 0 {
+			current.Latency = round2(latencySum / float64(current.OnlineSamples))
+		}
+		summaries = append(summaries, *current)
+	}
+
+	for _, record := range records {
+		bucket := record.CheckedAt.Truncate(interval)
+		if current == nil || current.ServerName != record.ServerName || !current.CheckedAt.Equal(bucket) {
+			flush()
+			current = &model.ServerStatusRecord{
+				ServerName:  record.ServerName,
+				CheckedAt:   bucket,
+				Downsampled: true,
+			}
+			latencySum = 0
+		}
+
+		current.Samples += record.Samples
+		current.OnlineSamples += record.OnlineSamples
+		current.Online = current.Online || record.Online
+		current.Players += record.Players * float64(record.Samples)
+		latencySum += record.Latency * float64(record.OnlineSamples)
+		if record.PeakPlayers > current.PeakPlayers {
+			current.PeakPlayers = record.PeakPlayers
+		}
+		if record.MaxPlayers > current.MaxPlayers {
+			current.MaxPlayers = record.MaxPlayers
+		}
+		// 保留时间段内最后的Pod信息和错误
+		if record.PodName != "" {
+			current.PodName = record.PodName
+			current.PodPhase = record.PodPhase
+		}
+		if record.Error != "" {
+			current.Error = record.Error
+		}
+	}
+	flush()
+
+	return summaries
+}
+
+// autoStep 根据时间窗口长度选择时间粒度
+func autoStep(window time.Duration) time.Duration {
+	steps := []time.Duration{
+		time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+	}
+	for _, step := range steps {
+		if window/step <= maxStatusPoints {
+			return step
+		}
+	}
+	return 7 * 24 * time.Hour
+}
+
+// percentage 计算百分比，保留两位小数
+func percentage(part float64, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round2(part / float64(total) * 100)
+}
+
+// round2 保留两位小数
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// truncateString 截断过长的字符串
+func truncateString(value string, maxLen int) string {
+	runes := []rune(value)
+	if len(runes) <= maxLen {
+		return value
+	}
+	return string(runes[:maxLen])
+}
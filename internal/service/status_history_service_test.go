@@ -0,0 +1,371 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"city.newnan/k8s-console/internal/config"
+	"city.newnan/k8s-console/internal/db"
+	"city.newnan/k8s-console/internal/model"
+)
+
+var statusBase = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// statusRecord 创建一次原始状态检测记录，offline为true时表示离线
+func statusRecord(server string, at time.Duration, players int, latency float64, offline bool) model.ServerStatusRecord {
+	record := model.ServerStatusRecord{
+		ServerName: server,
+		CheckedAt:  statusBase.Add(at),
+		Samples:    1,
+	}
+	if offline {
+		record.Error = "连接超时"
+		return record
+	}
+	record.Online = true
+	record.Players = float64(players)
+	record.PeakPlayers = players
+	record.MaxPlayers = 20
+	record.Latency = latency
+	record.OnlineSamples = 1
+	return record
+}
+
+func insertStatusRecords(t *testing.T, records ...model.ServerStatusRecord) {
+	t.Helper()
+
+	if err := db.DB.CreateInBatches(records, 100).Error; err != nil {
+		t.Fatalf("创建状态记录失败: %v", err)
+	}
+}
+
+func TestParseStatusWindow(t *testing.T) {
+	now := statusBase.Add(48 * time.Hour)
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{name: "默认最近24小时", wantFrom: now.Add(-24 * time.Hour), wantTo: now},
+		{name: "只指定结束时间", to: "2026-01-02T00:00:00Z", wantFrom: statusBase, wantTo: statusBase.Add(24 * time.Hour)},
+		{name: "只指定起始时间", from: "2026-01-01T12:00:00Z", wantFrom: statusBase.Add(12 * time.Hour), wantTo: now},
+		{name: "指定时区", from: "2026-01-01T08:00:00+08:00", to: "2026-01-01T01:00:00Z", wantFrom: statusBase, wantTo: statusBase.Add(time.Hour)},
+		{name: "无效的起始时间", from: "yesterday", wantErr: true},
+		{name: "无效的结束时间", to: "2026-01-01", wantErr: true},
+		{name: "起始时间等于结束时间", from: "2026-01-01T00:00:00Z", to: "2026-01-01T00:00:00Z", wantErr: true},
+		{name: "起始时间晚于结束时间", from: "2026-01-02T00:00:00Z", to: "2026-01-01T00:00:00Z", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseStatusWindow(tt.from, tt.to, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("应返回错误, 实际 %v - %v", from, to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析时间窗口失败: %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Fatalf("时间窗口应为 %v - %v, 实际 %v - %v", tt.wantFrom, tt.wantTo, from, to)
+			}
+		})
+	}
+}
+
+func TestParseStatusStep(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "1m", want: time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "30s", wantErr: true},
+		{value: "-5m", wantErr: true},
+		{value: "hourly", wantErr: true},
+	}
+	for _, tt := range tests {
+		step, err := ParseStatusStep(tt.value)
+		if tt.wantErr {
+			if !errors.Is(err, errInvalidStatusStep) {
+				t.Errorf("%q 应返回无效的时间粒度: %v", tt.value, err)
+			}
+			continue
+		}
+		if err != nil || step != tt.want {
+			t.Errorf("%q 应解析为 %v, 实际 %v %v", tt.value, tt.want, step, err)
+		}
+	}
+}
+
+func TestGetHistoryBuckets(t *testing.T) {
+	setupTestDB(t)
+	insertStatusRecords(t,
+		statusRecord("survival", 0, 2, 10, false),
+		statusRecord("survival", 20*time.Minute, 4, 30, false),
+		statusRecord("survival", 40*time.Minute, 0, 0, true),
+		// 第二个小时没有记录
+		statusRecord("survival", 2*time.Hour+10*time.Minute, 6, 20, false),
+		// 窗口外和其他服务器的记录不计入
+		statusRecord("survival", -time.Minute, 100, 100, false),
+		statusRecord("survival", 3*time.Hour, 100, 100, false),
+		statusRecord("creative", 10*time.Minute, 100, 100, false),
+	)
+	// 降采样记录按包含的检测次数加权
+	insertStatusRecords(t, model.ServerStatusRecord{
+		ServerName: "survival", CheckedAt: statusBase.Add(2*time.Hour + 30*time.Minute), Online: true,
+		Players: 2, PeakPlayers: 9, MaxPlayers: 30, Latency: 40, Samples: 3, OnlineSamples: 1, Downsampled: true,
+	})
+
+	history := NewStatusHistoryService(&config.Config{})
+	tests := []struct {
+		name string
+		step time.Duration
+		want []model.StatusPoint
+	}{
+		{
+			name: "按小时汇总并跳过没有记录的时间段",
+			step: time.Hour,
+			want: []model.StatusPoint{
+				{Time: statusBase, Samples: 3, Uptime: 66.67, Players: 2, PeakPlayers: 4, MaxPlayers: 20, Latency: 20},
+				{Time: statusBase.Add(2 * time.Hour), Samples: 4, Uptime: 50, Players: 3, PeakPlayers: 9, MaxPlayers: 30, Latency: 30},
+			},
+		},
+		{
+			name: "自动选择粒度",
+			step: 0,
+			want: []model.StatusPoint{
+				{Time: statusBase, Samples: 1, Uptime: 100, Players: 2, PeakPlayers: 2, MaxPlayers: 20, Latency: 10},
+				{Time: statusBase.Add(20 * time.Minute), Samples: 1, Uptime: 100, Players: 4, PeakPlayers: 4, MaxPlayers: 20, Latency: 30},
+				{Time: statusBase.Add(40 * time.Minute), Samples: 1, Uptime: 0},
+				{Time: statusBase.Add(2*time.Hour + 10*time.Minute), Samples: 1, Uptime: 100, Players: 6, PeakPlayers: 6, MaxPlayers: 20, Latency: 20},
+				{Time: statusBase.Add(2*time.Hour + 30*time.Minute), Samples: 3, Uptime: 33.33, Players: 2, PeakPlayers: 9, MaxPlayers: 30, Latency: 40},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := history.GetHistory("survival", statusBase, statusBase.Add(3*time.Hour), tt.step)
+			if err != nil {
+				t.Fatalf("获取状态历史失败: %v", err)
+			}
+			if len(points) != len(tt.want) {
+				t.Fatalf("应有%d个数据点, 实际 %+v", len(tt.want), points)
+			}
+			for i, want := range tt.want {
+				got := points[i]
+				got.Time = got.Time.UTC()
+				if got != want {
+					t.Errorf("第%d个数据点应为 %+v, 实际 %+v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetHistoryManyRecords(t *testing.T) {
+	setupTestDB(t)
+
+	// 超过一批的记录，倒序插入验证数据点按时间排序
+	var records []model.ServerStatusRecord
+	for i := 2*statusHistoryBatchSize + 10; i > 0; i-- {
+		records = append(records, statusRecord("survival", time.Duration(i)*time.Second, i%5, 10, false))
+	}
+	insertStatusRecords(t, records...)
+
+	points, err := NewStatusHistoryService(&config.Config{}).GetHistory("survival", statusBase, statusBase.Add(time.Hour), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("获取状态历史失败: %v", err)
+	}
+	samples := 0
+	for i, point := range points {
+		if !point.Time.Equal(statusBase.Add(time.Duration(i) * 5 * time.Minute)) {
+			t.Fatalf("数据点应按时间排序: %+v", points)
+		}
+		samples += point.Samples
+	}
+	if len(points) != 4 || samples != len(records) {
+		t.Fatalf("应汇总所有记录到4个数据点, 实际%d个数据点%d次检测", len(points), samples)
+	}
+}
+
+func TestGetUptime(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     []model.ServerStatusRecord
+		wantUptime  float64
+		wantSamples int
+		wantOutages []model.StatusOutage
+	}{
+		{
+			name:        "没有记录",
+			wantOutages: []model.StatusOutage{},
+		},
+		{
+			name: "记录之间的空白不计入在线率",
+			records: []model.ServerStatusRecord{
+				statusRecord("survival", 0, 1, 10, false),
+				statusRecord("survival", 5*time.Hour, 1, 10, false),
+			},
+			wantUptime:  100,
+			wantSamples: 2,
+			wantOutages: []model.StatusOutage{},
+		},
+		{
+			name: "离线后恢复",
+			records: []model.ServerStatusRecord{
+				statusRecord("survival", 0, 1, 10, false),
+				statusRecord("survival", time.Minute, 0, 0, true),
+				statusRecord("survival", 2*time.Minute, 0, 0, true),
+				statusRecord("survival", 3*time.Minute, 1, 10, false),
+			},
+			wantUptime:  50,
+			wantSamples: 4,
+			wantOutages: []model.StatusOutage{
+				{Start: statusBase.Add(time.Minute), End: statusBase.Add(3 * time.Minute), Error: "连接超时"},
+			},
+		},
+		{
+			name: "多次离线且仍离线",
+			records: []model.ServerStatusRecord{
+				statusRecord("survival", 0, 0, 0, true),
+				statusRecord("survival", time.Minute, 1, 10, false),
+				statusRecord("survival", 2*time.Hour, 0, 0, true),
+				statusRecord("survival", 3*time.Hour, 0, 0, true),
+			},
+			wantUptime:  25,
+			wantSamples: 4,
+			wantOutages: []model.StatusOutage{
+				{Start: statusBase, End: statusBase.Add(time.Minute), Error: "连接超时"},
+				{Start: statusBase.Add(2 * time.Hour), End: statusBase.Add(3 * time.Hour), Error: "连接超时"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			if len(tt.records) > 0 {
+				insertStatusRecords(t, tt.records...)
+			}
+
+			result, err := NewStatusHistoryService(&config.Config{}).GetUptime("survival", statusBase, statusBase.Add(24*time.Hour))
+			if err != nil {
+				t.Fatalf("统计在线率失败: %v", err)
+			}
+			if result.Uptime != tt.wantUptime || result.Samples != tt.wantSamples {
+				t.Fatalf("在线率应为%v(%d次检测), 实际%v(%d次检测)", tt.wantUptime, tt.wantSamples, result.Uptime, result.Samples)
+			}
+			if len(result.Outages) != len(tt.wantOutages) {
+				t.Fatalf("离线时段应为 %+v, 实际 %+v", tt.wantOutages, result.Outages)
+			}
+			for i, want := range tt.wantOutages {
+				got := result.Outages[i]
+				if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.Error != want.Error {
+					t.Errorf("第%d个离线时段应为 %+v, 实际 %+v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusCleanup(t *testing.T) {
+	setupTestDB(t)
+
+	now := time.Now().UTC()
+	hour := now.Add(-48 * time.Hour).Truncate(time.Hour)
+	insertStatusRecords(t,
+		// 超过保留时长的原始记录和降采样记录被删除
+		statusRecord("survival", now.Add(-40*24*time.Hour).Sub(statusBase), 1, 10, false),
+		model.ServerStatusRecord{ServerName: "survival", CheckedAt: now.Add(-31 * 24 * time.Hour), Samples: 60, Downsampled: true},
+		// 超过原始保留时长的记录按服务器和小时降采样
+		statusRecord("survival", hour.Sub(statusBase), 2, 10, false),
+		statusRecord("survival", hour.Add(30*time.Minute).Sub(statusBase), 4, 30, false),
+		statusRecord("survival", hour.Add(50*time.Minute).Sub(statusBase), 0, 0, true),
+		statusRecord("creative", hour.Add(10*time.Minute).Sub(statusBase), 1, 5, false),
+		// 原始保留时长内的记录保持不变
+		statusRecord("survival", now.Add(-time.Hour).Sub(statusBase), 3, 10, false),
+	)
+
+	history := NewStatusHistoryService(&config.Config{
+		MCStatusRawRetention:       24 * time.Hour,
+		MCStatusRetention:          30 * 24 * time.Hour,
+		MCStatusDownsampleInterval: time.Hour,
+	})
+	if err := history.Cleanup(); err != nil {
+		t.Fatalf("执行保留策略失败: %v", err)
+	}
+
+	var records []model.ServerStatusRecord
+	if err := db.DB.Order("downsampled desc, server_name desc").Find(&records).Error; err != nil {
+		t.Fatalf("读取状态记录失败: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("应剩余2条降采样记录和1条原始记录, 实际 %+v", records)
+	}
+
+	survival, creative, raw := records[0], records[1], records[2]
+	if survival.ServerName != "survival" || !survival.CheckedAt.Equal(hour) || !survival.Downsampled || !survival.Online ||
+		survival.Samples != 3 || survival.OnlineSamples != 2 || survival.Players != 2 || survival.PeakPlayers != 4 ||
+		survival.Latency != 20 || survival.Error != "连接超时" {
+		t.Errorf("survival的降采样记录错误: %+v", survival)
+	}
+	if creative.ServerName != "creative" || !creative.CheckedAt.Equal(hour) || creative.Samples != 1 || creative.Players != 1 {
+		t.Errorf("creative的降采样记录错误: %+v", creative)
+	}
+	if raw.Downsampled || raw.Players != 3 {
+		t.Errorf("原始保留时长内的记录不应降采样: %+v", raw)
+	}
+
+	// 再次执行不重复降采样
+	if err := history.Cleanup(); err != nil {
+		t.Fatalf("执行保留策略失败: %v", err)
+	}
+	var count int64
+	db.DB.Model(&model.ServerStatusRecord{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("再次执行后记录数量不应变化, 实际%d条", count)
+	}
+}
+
+func TestStatusCleanupBatches(t *testing.T) {
+	setupTestDB(t)
+
+	// 每个小时的记录数量不整除批大小，时间段会跨越批的边界
+	start := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Hour)
+	perHour := statusHistoryBatchSize/2 + 7
+	var records []model.ServerStatusRecord
+	for h := 0; h < 5; h++ {
+		for i := 0; i < perHour; i++ {
+			at := start.Add(time.Duration(h)*time.Hour + time.Duration(i)*time.Second)
+			records = append(records, statusRecord("survival", at.Sub(statusBase), 1, 10, false))
+		}
+	}
+	insertStatusRecords(t, records...)
+
+	history := NewStatusHistoryService(&config.Config{
+		MCStatusRawRetention:       24 * time.Hour,
+		MCStatusDownsampleInterval: time.Hour,
+	})
+	if err := history.Cleanup(); err != nil {
+		t.Fatalf("执行保留策略失败: %v", err)
+	}
+
+	var summaries []model.ServerStatusRecord
+	if err := db.DB.Order("checked_at").Find(&summaries).Error; err != nil {
+		t.Fatalf("读取状态记录失败: %v", err)
+	}
+	if len(summaries) != 5 {
+		t.Fatalf("每个小时应只有一条降采样记录, 实际%d条", len(summaries))
+	}
+	for i, summary := range summaries {
+		if !summary.Downsampled || !summary.CheckedAt.Equal(start.Add(time.Duration(i)*time.Hour)) || summary.Samples != perHour {
+			t.Errorf("第%d个小时的降采样记录错误: %+v", i, summary)
+		}
+	}
+}
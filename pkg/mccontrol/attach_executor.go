@@ -1,125 +1,399 @@
-package mccontrol
-
-import (
-	"bytes"
-	"context"
-	"fmt"
-	"sync"
-	"time"
-
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/remotecommand"
-)
-
-// attachExecutor 使用kubectl attach的命令执行器实现
-type attachExecutor struct {
-	clientset     *kubernetes.Clientset // K8s客户端
-	restConfig    *rest.Config          // REST配置
-	namespace     string                // 命名空间
-	podName       string                // Pod名称
-	containerName string                // 容器名称
-
-	connected bool       // 是否已连接
-	mutex     sync.Mutex // 互斥锁，保护会话操作
-
-	// 执行配置
-	timeout time.Duration // 命令执行超时时间
-}
-
-// newAttachExecutor 创建一个新的kubectl attach执行器
-func newAttachExecutor(clientset *kubernetes.Clientset, restConfig *rest.Config, namespace, podName, containerName string) *attachExecutor {
-	return &attachExecutor{
-		clientset:     clientset,
-		restConfig:    restConfig,
-		namespace:     namespace,
-		podName:       podName,
-		containerName: containerName,
-		timeout:       30 * time.Second,
-	}
-}
-
-// Connect 建立与Pod的连接（测试连接是否可用）
-// 利用控制器已有的Pod状态检查，简化连接验证
-func (e *attachExecutor) Connect() error {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	if e.connected {
-		return nil
-	}
-
-	// 假设Pod状态已由MinecraftController检查，这里只进行简单验证
-	if e.podName == "" || e.namespace == "" || e.containerName == "" {
-		return fmt.Errorf("Pod信息不完整：podName=%s, namespace=%s, containerName=%s",
-			e.podName, e.namespace, e.containerName)
-	}
-
-	e.connected = true
-	return nil
-}
-
-// ExecuteCommand 通过kubectl attach执行命令
-func (e *attachExecutor) ExecuteCommand(cmd string) (string, error) {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	// 命令加上换行符
-	stdinBuf := bytes.NewBufferString(cmd + "\n")
-
-	// 创建attach请求
-	req := e.clientset.CoreV1().RESTClient().Post().
-		Resource("pods").
-		Name(e.podName).
-		Namespace(e.namespace).
-		SubResource("attach")
-
-	req.VersionedParams(&corev1.PodAttachOptions{
-		Container: e.containerName,
-		Stdin:     true,
-		Stdout:    false,
-		Stderr:    false,
-		TTY:       false,
-	}, scheme.ParameterCodec)
-
-	// 创建执行器
-	exec, err := remotecommand.NewSPDYExecutor(e.restConfig, "POST", req.URL())
-	if err != nil {
-		return "", fmt.Errorf("创建SPDY执行器失败: %v", err)
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
-	defer cancel()
-
-	// 执行命令
-	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdin:  stdinBuf,
-		Stdout: nil,     // 不捕获标准输出
-		Stderr: nil,
-		Tty:    false,
-	})
-
-	if err != nil {
-		return "", fmt.Errorf("执行命令失败: %v", err)
-	}
-
-	return "", nil
-}
-
-// Disconnect 断开连接（对于attach方式，每次命令都是新连接，所以这里只是重置状态）
-func (e *attachExecutor) Disconnect() {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	e.connected = false
-}
-
-// IsConnected 检查是否已连接
-func (e *attachExecutor) IsConnected() bool {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	return e.connected
-}
+package mccontrol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// attachExecutor 使用kubectl attach的命令执行器实现
+// 默认每条命令建立一次attach连接；持久模式下保持一个标准输入输出数据流，
+// 所有命令复用该数据流，数据流断开后在下一条命令时自动重连
+type attachExecutor struct {
+	clientset     kubernetes.Interface // K8s客户端
+	restConfig    *rest.Config         // REST配置
+	namespace     string               // 命名空间
+	podName       string               // Pod名称
+	containerName string               // 容器名称
+
+	connected bool       // 是否已连接
+	mutex     sync.Mutex // 互斥锁，保护会话操作
+
+	// 执行配置
+	timeout time.Duration  // 命令执行超时时间
+	output  *outputCapture // 命令输出收集器，为nil则不返回输出
+
+	// 持久模式
+	persistent  bool                   // 是否保持持久数据流
+	refreshPod  func() (string, error) // 重连前获取最新的Pod名称，为nil则沿用原Pod
+	stream      *attachStream          // 当前数据流
+	listener    chan string            // 当前命令的输出接收通道，为nil则丢弃输出
+	streamMutex sync.Mutex             // 保护stream和listener
+}
+
+// attachStream 持久的attach标准输入输出数据流
+type attachStream struct {
+	stdin  *io.PipeWriter     // 写入服务器标准输入
+	cancel context.CancelFunc // 关闭数据流
+	done   chan struct{}      // 数据流结束时关闭
+	err    error              // 数据流结束的原因，done关闭后可读
+}
+
+// alive 检查数据流是否仍然可用
+func (s *attachStream) alive() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// newAttachExecutor 创建一个新的kubectl attach执行器
+func newAttachExecutor(clientset kubernetes.Interface, restConfig *rest.Config, namespace, podName, containerName string) *attachExecutor {
+	return &attachExecutor{
+		clientset:     clientset,
+		restConfig:    restConfig,
+		namespace:     namespace,
+		podName:       podName,
+		containerName: containerName,
+		timeout:       30 * time.Second,
+	}
+}
+
+// Connect 建立与Pod的连接
+// 持久模式下建立attach数据流；否则只验证Pod信息，利用控制器已有的Pod状态检查
+func (e *attachExecutor) Connect() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.connected {
+		return nil
+	}
+
+	// 假设Pod状态已由MinecraftController检查，这里只进行简单验证
+	if e.podName == "" || e.namespace == "" || e.containerName == "" {
+		return fmt.Errorf("Pod信息不完整：podName=%s, namespace=%s, containerName=%s",
+			e.podName, e.namespace, e.containerName)
+	}
+
+	if e.persistent {
+		if _, err := e.ensureStream(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	e.connected = true
+	return nil
+}
+
+// ExecuteCommand 通过kubectl attach执行命令
+func (e *attachExecutor) ExecuteCommand(cmd string) (string, error) {
+	return e.ExecuteCommandContext(context.Background(), cmd)
+}
+
+// ExecuteCommandContext 通过kubectl attach执行命令，上下文取消时中止写入和输出收集
+func (e *attachExecutor) ExecuteCommandContext(ctx context.Context, cmd string) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if e.persistent {
+		return e.executePersistent(ctx, cmd)
+	}
+
+	if e.output == nil {
+		return "", e.writeCommand(ctx, cmd)
+	}
+	// attach只写入标准输入，服务器的响应从容器日志中收集
+	return e.output.capture(ctx, e.podName, func(ctx context.Context) error {
+		return e.writeCommand(ctx, cmd)
+	})
+}
+
+// writeCommand 通过attach将命令写入服务器的标准输入
+func (e *attachExecutor) writeCommand(ctx context.Context, cmd string) error {
+	// 命令加上换行符
+	stdinBuf := bytes.NewBufferString(cmd + "\n")
+
+	// 创建attach请求
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(e.podName).
+		Namespace(e.namespace).
+		SubResource("attach")
+
+	req.VersionedParams(&corev1.PodAttachOptions{
+		Container: e.containerName,
+		Stdin:     true,
+		Stdout:    false,
+		Stderr:    false,
+		TTY:       false,
+	}, scheme.ParameterCodec)
+
+	// 创建执行器
+	exec, err := remotecommand.NewSPDYExecutor(e.restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("创建SPDY执行器失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	// 执行命令
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdinBuf,
+		Stdout: nil, // 不捕获标准输出
+		Stderr: nil,
+		Tty:    false,
+	})
+
+	if err != nil {
+		return fmt.Errorf("执行命令失败: %v", err)
+	}
+
+	return nil
+}
+
+// executePersistent 通过持久数据流执行命令，直接从标准输出收集响应
+func (e *attachExecutor) executePersistent(ctx context.Context, cmd string) (string, error) {
+	stream, err := e.ensureStream(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// 标准输出中没有与命令对应的标记，同一Pod上的输出收集依次进行
+	if e.output != nil {
+		release, err := e.output.controller.lockOutputCapture(ctx, e.podName)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+	}
+
+	lines := make(chan string, outputBufferLines)
+	e.setListener(lines)
+	defer e.setListener(nil)
+
+	if err := e.writeStdin(ctx, stream, []byte(cmd+"\n")); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		// 数据流已断开，重新连接后重试一次
+		e.closeStream()
+		if stream, err = e.ensureStream(ctx); err != nil {
+			return "", err
+		}
+		if err := e.writeStdin(ctx, stream, []byte(cmd+"\n")); err != nil {
+			return "", fmt.Errorf("写入命令失败: %v", err)
+		}
+	}
+
+	if e.output == nil {
+		return "", nil
+	}
+	return e.output.format(e.output.collect(ctx, lines, stream.done)), nil
+}
+
+// ensureStream 返回可用的持久数据流，数据流不存在或已断开时重新建立
+func (e *attachExecutor) ensureStream(ctx context.Context) (*attachStream, error) {
+	e.streamMutex.Lock()
+	stream := e.stream
+	e.streamMutex.Unlock()
+
+	if stream != nil && stream.alive() {
+		return stream, nil
+	}
+
+	// 重连时Pod可能已被重建，先获取最新的Pod名称
+	if stream != nil && e.refreshPod != nil {
+		if podName, err := e.refreshPod(); err == nil && podName != "" {
+			e.podName = podName
+		}
+	}
+
+	stream, err := e.openStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.streamMutex.Lock()
+	e.stream = stream
+	e.streamMutex.Unlock()
+	return stream, nil
+}
+
+// openStream 建立attach标准输入输出数据流，并等待数据流开始转发标准输入
+// ctx只用于等待数据流建立，建立后数据流的生命周期与执行器一致
+func (e *attachExecutor) openStream(ctx context.Context) (*attachStream, error) {
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(e.podName).
+		Namespace(e.namespace).
+		SubResource("attach")
+
+	req.VersionedParams(&corev1.PodAttachOptions{
+		Container: e.containerName,
+		Stdin:     true,
+		Stdout:    true,
+		Stderr:    true,
+		TTY:       false,
+	}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(e.restConfig, "POST", req.URL())
+	if err != nil {
+		return nil, fmt.Errorf("创建SPDY执行器失败: %v", err)
+	}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stdinReader, stdinWriter := io.Pipe()
+	stream := &attachStream{
+		stdin:  stdinWriter,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(stream.done)
+		err := exec.StreamWithContext(streamCtx, remotecommand.StreamOptions{
+			Stdin:  stdinReader,
+			Stdout: &lineWriter{onLine: e.deliver},
+			Stderr: &lineWriter{onLine: e.deliver},
+			Tty:    false,
+		})
+		if err == nil {
+			err = errors.New("attach数据流已结束")
+		}
+		stream.err = err
+		// 使等待中和之后的写入立即失败
+		stdinReader.CloseWithError(err)
+		cancel()
+	}()
+
+	// io.Pipe的写入会阻塞到数据被读取，空写入返回说明数据流已建立并开始转发标准输入
+	if err := e.writeStdin(ctx, stream, nil); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("建立attach数据流失败: %v", err)
+	}
+	return stream, nil
+}
+
+// writeStdin 向数据流的标准输入写入数据，超时未写入或上下文取消时关闭数据流
+// 写入中途放弃的数据流状态未知，关闭后由下一条命令重新建立
+func (e *attachExecutor) writeStdin(ctx context.Context, stream *attachStream, data []byte) error {
+	timer := time.AfterFunc(e.timeout, stream.cancel)
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, stream.cancel)
+	defer stop()
+
+	_, err := stream.stdin.Write(data)
+	return err
+}
+
+// switchPod 切换到新Pod，持久模式下关闭原Pod的数据流，下一条命令会连接到新Pod
+func (e *attachExecutor) switchPod(podName string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.podName == podName {
+		return
+	}
+	e.podName = podName
+	if e.persistent {
+		e.closeStream()
+	}
+}
+
+// closeStream 关闭当前的持久数据流
+func (e *attachExecutor) closeStream() {
+	e.streamMutex.Lock()
+	stream := e.stream
+	e.stream = nil
+	e.streamMutex.Unlock()
+
+	if stream != nil {
+		stream.stdin.Close()
+		stream.cancel()
+	}
+}
+
+// setListener 设置当前命令的输出接收通道
+func (e *attachExecutor) setListener(listener chan string) {
+	e.streamMutex.Lock()
+	defer e.streamMutex.Unlock()
+
+	e.listener = listener
+}
+
+// deliver 将标准输出的一行投递给当前命令，没有命令在执行时丢弃
+func (e *attachExecutor) deliver(line string) {
+	e.streamMutex.Lock()
+	defer e.streamMutex.Unlock()
+
+	if e.listener != nil {
+		offerLine(e.listener, line)
+	}
+}
+
+// Disconnect 断开连接
+// 持久模式下关闭数据流；否则每次命令都是新连接，这里只重置状态
+func (e *attachExecutor) Disconnect() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.connected = false
+	if e.persistent {
+		e.closeStream()
+	}
+}
+
+// IsConnected 检查是否已连接，持久模式下反映数据流是否仍然可用
+func (e *attachExecutor) IsConnected() bool {
+	if e.persistent {
+		e.streamMutex.Lock()
+		defer e.streamMutex.Unlock()
+
+		return e.stream != nil && e.stream.alive()
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.connected
+}
+
+// lineWriter 将写入的数据按行拆分后回调
+type lineWriter struct {
+	onLine  func(string)
+	pending []byte
+}
+
+// Write 实现io.Writer接口，同一个输出流的写入是串行的，不同输出流需使用各自的lineWriter
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		index := bytes.IndexByte(w.pending, '\n')
+		if index < 0 {
+			break
+		}
+		line := bytes.TrimRight(w.pending[:index], "\r")
+		w.onLine(string(line))
+		w.pending = w.pending[index+1:]
+	}
+	return len(p), nil
+}
@@ -1,271 +1,434 @@
-package mccontrol
-
-import (
-	"context"
-	"fmt"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-
-	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-)
-
-// MinecraftController 管理与K8s中的Minecraft服务器的交互
-type MinecraftController struct {
-	// Kubernetes配置
-	clientset        *kubernetes.Clientset // K8s客户端
-	restConfig       *rest.Config          // REST配置
-	namespace        string                // 命名空间
-	podLabelSelector string                // Pod标签选择器
-	containerName    string                // 容器名称
-
-	// 资源信息
-	currentPodName       string // 当前选中的Pod名称
-	serviceLabelSelector string // 服务标签选择器
-	serverIP             string // 服务器IP地址
-
-	// Pod信息更新控制
-	lastPodInfoUpdate     time.Time     // 上次更新Pod信息的时间
-	podInfoUpdateInterval time.Duration // Pod信息更新的最小间隔
-	podInfoUpdateMutex    sync.Mutex    // 更新Pod信息时的互斥锁
-
-	// Minecraft服务器配置
-	gamePort     int    // 游戏端口
-	rconPort     int    // RCON端口
-	rconPassword string // RCON密码
-
-	// 状态管理
-	status ServerStatus // 服务器状态信息
-
-	// 上下文控制
-	ctx        context.Context    // 上下文
-	cancelFunc context.CancelFunc // 取消函数
-
-	// 会话管理
-	sessionManager *sessionManager // 会话管理器
-}
-
-// NewMinecraftController 创建一个新的Minecraft控制器实例
-func NewMinecraftController(config K8sConfig, gamePort, rconPort int, rconPassword string) (*MinecraftController, error) {
-	var k8sConfig *rest.Config
-	var err error
-
-	// 根据运行模式选择K8s配置
-	if config.RunMode == "InCluster" {
-		k8sConfig, err = rest.InClusterConfig()
-		if err != nil {
-			return nil, fmt.Errorf("获取集群内部配置失败: %v", err)
-		}
-	} else {
-		// 使用指定的kubeconfig或默认位置
-		kubeconfigPath := config.KubeconfigPath
-		if kubeconfigPath == "" {
-			homeDir, _ := os.UserHomeDir()
-			kubeconfigPath = filepath.Join(homeDir, ".kube", "config")
-		}
-
-		k8sConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
-		if err != nil {
-			return nil, fmt.Errorf("加载kubeconfig失败: %v", err)
-		}
-	}
-
-	// 创建K8s客户端
-	clientset, err := kubernetes.NewForConfig(k8sConfig)
-	if err != nil {
-		return nil, fmt.Errorf("创建K8s客户端失败: %v", err)
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// 创建会话管理器
-	sessionMgr := &sessionManager{
-		sessions: make(map[string]*CommandSession),
-	}
-
-	controller := &MinecraftController{
-		clientset:             clientset,
-		restConfig:            k8sConfig, // 保存REST配置
-		namespace:             config.Namespace,
-		podLabelSelector:      config.PodLabelSelector,
-		containerName:         config.ContainerName,
-		gamePort:              gamePort,
-		rconPort:              rconPort,
-		rconPassword:          rconPassword,
-		ctx:                   ctx,
-		cancelFunc:            cancel,
-		serviceLabelSelector:  config.ServiceLabelSelector,
-		podInfoUpdateInterval: 5 * time.Minute, // 默认更新间隔为5分钟
-		sessionManager:        sessionMgr,
-	}
-
-	// 初始化时更新服务器信息
-	err = controller.findAndUpdatePodInfo()
-	if err != nil {
-		return controller, fmt.Errorf("初始化Pod信息失败: %v", err)
-	}
-
-	// 启动定时清理任务
-	sessionMgr.cleanupTimer = time.NewTicker(5 * time.Minute)
-	go func() {
-		for range sessionMgr.cleanupTimer.C {
-			sessionMgr.cleanupIdleSessions()
-		}
-	}()
-
-	return controller, nil
-}
-
-// updatePodInfoIfNeeded 在必要时更新Pod信息
-// forceUpdate: 是否强制更新，忽略时间间隔限制
-// 返回值: 是否执行了更新操作, 更新错误（如果有）
-func (m *MinecraftController) updatePodInfoIfNeeded(forceUpdate bool) (bool, error) {
-	// 快速检查是否需要更新（不加锁）
-	if !forceUpdate && time.Since(m.lastPodInfoUpdate) < m.podInfoUpdateInterval {
-		return false, nil
-	}
-
-	// 加锁进行详细检查与更新
-	m.podInfoUpdateMutex.Lock()
-	defer m.podInfoUpdateMutex.Unlock()
-
-	// 再次检查是否需要更新（加锁后）
-	if !forceUpdate && time.Since(m.lastPodInfoUpdate) < m.podInfoUpdateInterval {
-		return false, nil // 其他协程可能已经更新过了
-	}
-
-	// 执行更新
-	err := m.findAndUpdatePodInfo()
-	if err != nil {
-		return true, err
-	}
-
-	return true, nil
-}
-
-// SetPodInfoUpdateInterval 设置Pod信息更新的最小间隔
-func (m *MinecraftController) SetPodInfoUpdateInterval(interval time.Duration) {
-	if interval <= 0 {
-		interval = 5 * time.Minute // 默认5分钟
-	}
-	m.podInfoUpdateMutex.Lock()
-	defer m.podInfoUpdateMutex.Unlock()
-	m.podInfoUpdateInterval = interval
-}
-
-// ForceUpdatePodInfo 强制更新Pod信息，忽略时间间隔限制
-func (m *MinecraftController) ForceUpdatePodInfo() error {
-	_, err := m.updatePodInfoIfNeeded(true)
-	return err
-}
-
-// findAndUpdatePodInfo 查找符合标签的Pod并更新信息
-func (m *MinecraftController) findAndUpdatePodInfo() error {
-	// 使用标签选择器列出所有匹配的Pod
-	pods, err := m.clientset.CoreV1().Pods(m.namespace).List(m.ctx, metav1.ListOptions{
-		LabelSelector: m.podLabelSelector,
-	})
-	if err != nil {
-		return fmt.Errorf("获取Pod列表失败: %v", err)
-	}
-
-	if len(pods.Items) == 0 {
-		return fmt.Errorf("未找到匹配标签 '%s' 的Pod", m.podLabelSelector)
-	}
-
-	// 选择第一个Running状态的Pod，如果没有则选时间最近的成功运行过的pod，还没有就选第一个
-	var selectedPod *corev1.Pod
-	var latestSucceededPod *corev1.Pod
-	var latestSucceededTime time.Time
-	for i := range pods.Items {
-		if pods.Items[i].Status.Phase == corev1.PodRunning {
-			selectedPod = &pods.Items[i]
-			break
-		}
-		// 记录最近成功运行的Pod
-		if pods.Items[i].Status.Phase == corev1.PodSucceeded {
-			if latestSucceededPod == nil || pods.Items[i].Status.StartTime.Time.After(latestSucceededTime) {
-				latestSucceededPod = &pods.Items[i]
-				latestSucceededTime = pods.Items[i].Status.StartTime.Time
-			}
-		}
-	}
-	// 如果没有Running状态的Pod，则选择最近成功运行的Pod
-	if selectedPod == nil && latestSucceededPod != nil {
-		selectedPod = latestSucceededPod
-	}
-	// 如果还没有，就选择第一个Pod
-	if selectedPod == nil {
-		selectedPod = &pods.Items[0]
-	}
-
-	m.currentPodName = selectedPod.Name
-	m.serverIP = selectedPod.Status.PodIP
-	m.status.PodName = selectedPod.Name
-	m.status.PodStatus = string(selectedPod.Status.Phase)
-	m.status.ClusterIP = selectedPod.Status.PodIP
-
-	// 使用serviceLabelSelector查询服务（如果配置了该字段）
-	serviceLabelSelector := m.serviceLabelSelector
-	if serviceLabelSelector == "" {
-		serviceLabelSelector = m.podLabelSelector // 默认使用与Pod相同的标签选择器
-	}
-
-	// 尝试获取外部IP (如果存在LoadBalancer或NodePort服务)
-	if services, err := m.clientset.CoreV1().Services(m.namespace).List(m.ctx, metav1.ListOptions{
-		LabelSelector: serviceLabelSelector,
-	}); err == nil {
-		for _, service := range services.Items {
-			// 确保服务的类型是LoadBalancer或NodePort
-			if service.Spec.Type == corev1.ServiceTypeLoadBalancer || service.Spec.Type == corev1.ServiceTypeNodePort {
-				for _, port := range service.Spec.Ports {
-					// 检查服务端口是否与游戏端口匹配
-					if port.Port == int32(m.gamePort) || port.TargetPort.IntVal == int32(m.gamePort) {
-						if len(service.Status.LoadBalancer.Ingress) > 0 {
-							m.status.ExternalIP = service.Status.LoadBalancer.Ingress[0].IP
-						} else if len(service.Spec.ExternalIPs) > 0 {
-							m.status.ExternalIP = service.Spec.ExternalIPs[0]
-						}
-						break
-					}
-				}
-			}
-		}
-	}
-
-	m.lastPodInfoUpdate = time.Now()
-	return nil
-}
-
-// StartPodInfoMonitoring 开始定期监控Pod信息
-// 此功能会定期检查Pod状态，即使没有调用任何方法也能保持信息的更新
-func (m *MinecraftController) StartPodInfoMonitoring(interval time.Duration) {
-	if interval <= 0 {
-		interval = m.podInfoUpdateInterval // 使用默认间隔
-	}
-
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-m.ctx.Done():
-				return
-			case <-ticker.C:
-				// 静默更新，忽略错误
-				go m.updatePodInfoIfNeeded(true)
-			}
-		}
-	}()
-}
-
-// Close 关闭控制器并释放资源
-func (m *MinecraftController) Close() {
-	m.cancelFunc()
-}
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// MinecraftController 管理与K8s中的Minecraft服务器的交互
+type MinecraftController struct {
+	// Kubernetes配置
+	clientset        kubernetes.Interface // K8s客户端
+	restConfig       *rest.Config         // REST配置
+	namespace        string               // 命名空间
+	podLabelSelector string               // Pod标签选择器
+	containerName    string               // 容器名称
+
+	// 资源信息（由stateMutex保护）
+	currentPodName       string // 当前选中的Pod名称
+	pinnedPod            string // 固定使用的Pod名称，为空则自动选择
+	serviceLabelSelector string // 服务标签选择器
+	serverIP             string // 服务器IP地址
+
+	// Pod信息更新控制
+	lastPodInfoUpdate     time.Time     // 上次更新Pod信息的时间（由stateMutex保护）
+	podInfoUpdateInterval time.Duration // Pod信息更新的最小间隔（由stateMutex保护）
+	podInfoUpdateMutex    sync.Mutex    // 保证同一时间只有一个协程更新Pod信息
+
+	// Minecraft服务器配置
+	gamePort     int    // 游戏端口
+	rconPort     int    // RCON端口
+	rconPassword string // RCON密码
+
+	// RCON密码来源
+	rconPasswordSource    rconPasswordSource // 密码的来源配置
+	rconPasswordCheckedAt time.Time          // 上次重新读取密码的时间
+	rconPasswordMutex     sync.Mutex         // 保证同一时间只有一个协程重新读取密码
+
+	// RCON连接
+	rconReadTimeout  time.Duration   // 等待命令响应的超时
+	rconWriteTimeout time.Duration   // 写入命令的超时
+	rconPoolOptions  RconPoolOptions // 连接池配置
+	rconRetryDelay   time.Duration   // 命令失败后重连重试的延迟基准时间，创建控制器后不再修改（测试中会调小）
+	rconPool         *rconPool       // 当前服务器地址的连接池，首次使用时建立
+	rconMutex        sync.Mutex      // 保护RCON连接配置和连接池
+
+	// 状态管理
+	status         ServerStatus       // 服务器状态信息（由stateMutex保护）
+	statusRecorder func(ServerStatus) // 状态记录函数（由stateMutex保护）
+	stateMutex     sync.RWMutex       // 保护当前Pod、服务器状态等被多个协程读写的字段，只在读写字段时短暂持有
+
+	// Pod事件和资源用量缓存
+	podExtras      podExtras  // 最近获取的Pod事件和资源用量（由podExtrasMutex保护）
+	podExtrasMutex sync.Mutex // 保证同一时间只有一个协程获取Pod事件和资源用量
+
+	// 上下文控制
+	ctx        context.Context    // 上下文
+	cancelFunc context.CancelFunc // 取消函数
+
+	// 会话管理
+	sessionManager *sessionManager // 会话管理器
+
+	// 执行器自动选择
+	executors *executorSelector // 记录执行器健康状态，决定自动模式下的尝试顺序
+	// 创建指定类型的执行器，测试中替换为假的执行器
+	newExecutor func(executorType ExecutorType, persistent bool, target *podTarget) (CommandExecutor, error)
+
+	// 命令输出收集（attach和exec执行器）
+	outputCaptureWindow time.Duration            // 命令写入后最多等待输出的时间，为0则不收集
+	outputCaptureQuiet  time.Duration            // 收到输出后的静默时间
+	outputCaptureLocks  map[string]chan struct{} // 每个Pod的输出收集锁，同一Pod上的收集依次进行
+	outputCaptureMutex  sync.Mutex               // 保护输出收集配置和outputCaptureLocks
+
+	// 事件与指标
+	events          *eventBus       // 事件总线
+	pods            *podWatcher     // Pod跟踪与Pod变化通知
+	metricsObserver MetricsObserver // 指标观察者
+
+	// 重启
+	restarting atomic.Bool // 是否正在执行RestartServer
+
+	// 闲置缩容与唤醒
+	idleTimeout time.Duration // 没有玩家多久后缩容到0，为0则不缩容
+	idleSince   time.Time     // 开始闲置的时间，为零值表示未闲置
+	idleMutex   sync.Mutex    // 保护闲置状态
+	scaleMutex  sync.Mutex    // 保证缩容和唤醒依次执行
+}
+
+// NewMinecraftController 创建一个新的Minecraft控制器实例
+func NewMinecraftController(config K8sConfig, gamePort, rconPort int, rconPassword string) (*MinecraftController, error) {
+	var k8sConfig *rest.Config
+	var err error
+
+	// 根据运行模式选择K8s配置
+	if config.RunMode == "InCluster" {
+		k8sConfig, err = rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("获取集群内部配置失败: %v", err)
+		}
+	} else {
+		// 使用指定的kubeconfig或默认位置
+		kubeconfigPath := config.KubeconfigPath
+		if kubeconfigPath == "" {
+			homeDir, _ := os.UserHomeDir()
+			kubeconfigPath = filepath.Join(homeDir, ".kube", "config")
+		}
+
+		k8sConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("加载kubeconfig失败: %v", err)
+		}
+	}
+
+	// 创建K8s客户端
+	clientset, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil, fmt.Errorf("创建K8s客户端失败: %v", err)
+	}
+
+	return newMinecraftController(clientset, k8sConfig, config, gamePort, rconPort, rconPassword)
+}
+
+// newMinecraftController 使用已创建的K8s客户端创建控制器，测试时可传入fake客户端
+func newMinecraftController(clientset kubernetes.Interface, restConfig *rest.Config, config K8sConfig, gamePort, rconPort int, rconPassword string) (*MinecraftController, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// 创建会话管理器
+	sessionMgr := &sessionManager{
+		sessions: make(map[string]*CommandSession),
+	}
+
+	controller := &MinecraftController{
+		clientset:        clientset,
+		restConfig:       restConfig, // 保存REST配置
+		namespace:        config.Namespace,
+		podLabelSelector: config.PodLabelSelector,
+		containerName:    config.ContainerName,
+		gamePort:         gamePort,
+		rconPort:         rconPort,
+		rconPassword:     rconPassword,
+		rconPasswordSource: rconPasswordSource{
+			secretName:     config.RconSecretName,
+			secretKey:      config.RconSecretKey,
+			envName:        config.RconPasswordEnv,
+			propertiesPath: config.RconPropertiesPath,
+			static:         rconPassword,
+		},
+		ctx:                   ctx,
+		cancelFunc:            cancel,
+		serviceLabelSelector:  config.ServiceLabelSelector,
+		podInfoUpdateInterval: 5 * time.Minute, // 默认更新间隔为5分钟
+		sessionManager:        sessionMgr,
+		executors:             newExecutorSelector(),
+		outputCaptureWindow:   defaultOutputCaptureWindow,
+		outputCaptureQuiet:    defaultOutputCaptureQuiet,
+		rconReadTimeout:       defaultRconReadTimeout,
+		rconWriteTimeout:      defaultRconWriteTimeout,
+		rconPoolOptions:       DefaultRconPoolOptions(),
+		rconRetryDelay:        defaultRconRetryDelay,
+	}
+	controller.newExecutor = controller.createTypedExecutor
+	controller.events = newEventBus(controller)
+	controller.pods = newPodWatcher()
+
+	// 使用informer跟踪Pod，Pod变化时立即更新，同步失败时仍通过List请求获取Pod信息
+	if !config.DisablePodWatch {
+		if err := controller.startPodWatch(); err != nil {
+			log.Printf("启动Pod跟踪失败: %v", err)
+		}
+	}
+	go controller.followPodChanges()
+
+	// 初始化时更新服务器信息，服务器缩容到0时没有Pod，控制器仍需完成初始化以便唤醒
+	var initErrs []error
+	if err := controller.findAndUpdatePodInfo(); err != nil {
+		initErrs = append(initErrs, fmt.Errorf("初始化Pod信息失败: %v", err))
+	}
+
+	// 启动定时清理任务
+	sessionMgr.cleanupTimer = time.NewTicker(5 * time.Minute)
+	go func() {
+		for range sessionMgr.cleanupTimer.C {
+			sessionMgr.cleanupIdleSessions()
+		}
+	}()
+
+	// 从配置的来源读取RCON密码，读取失败时控制器仍可使用，RCON认证失败后会再次读取
+	if controller.rconPasswordSource.dynamic() {
+		password, err := controller.resolveRconPassword(ctx)
+		if err != nil {
+			initErrs = append(initErrs, err)
+		} else {
+			controller.rconPassword = password
+			controller.rconPasswordCheckedAt = time.Now()
+		}
+	}
+
+	return controller, errors.Join(initErrs...)
+}
+
+// readSecretValue 从Secret中读取指定键的值
+func readSecretValue(clientset kubernetes.Interface, namespace, name, key string) (string, error) {
+	if key == "" {
+		key = "rcon-password"
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("获取Secret '%s' 失败: %v", name, err)
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("Secret '%s' 中不存在键 '%s'", name, key)
+	}
+
+	return string(value), nil
+}
+
+// updatePodInfoIfNeeded 在必要时更新Pod信息
+// forceUpdate: 是否强制更新，忽略时间间隔限制
+// 返回值: 是否执行了更新操作, 更新错误（如果有）
+func (m *MinecraftController) updatePodInfoIfNeeded(forceUpdate bool) (bool, error) {
+	// 快速检查是否需要更新（不等待正在进行的更新）
+	if !forceUpdate && m.podInfoFresh() {
+		return false, nil
+	}
+
+	// 加锁进行详细检查与更新
+	m.podInfoUpdateMutex.Lock()
+	defer m.podInfoUpdateMutex.Unlock()
+
+	// 再次检查是否需要更新（加锁后）
+	if !forceUpdate && m.podInfoFresh() {
+		return false, nil // 其他协程可能已经更新过了
+	}
+
+	// 执行更新
+	err := m.findAndUpdatePodInfo()
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// SetPodInfoUpdateInterval 设置Pod信息更新的最小间隔
+func (m *MinecraftController) SetPodInfoUpdateInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute // 默认5分钟
+	}
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+	m.podInfoUpdateInterval = interval
+}
+
+// ForceUpdatePodInfo 强制更新Pod信息，忽略时间间隔限制
+func (m *MinecraftController) ForceUpdatePodInfo() error {
+	_, err := m.updatePodInfoIfNeeded(true)
+	return err
+}
+
+// findAndUpdatePodInfo 查找符合标签的Pod并更新信息
+func (m *MinecraftController) findAndUpdatePodInfo() error {
+	// 列出所有匹配标签的Pod，informer已同步时从缓存读取
+	pods, err := m.listPods()
+	if err != nil {
+		return fmt.Errorf("获取Pod列表失败: %v", err)
+	}
+
+	if len(pods) == 0 {
+		// 没有Pod时（如已缩容到0）清除当前Pod，避免继续向已删除的Pod发送请求
+		m.setCurrentPod(nil)
+		return fmt.Errorf("未找到匹配标签 '%s' 的Pod", m.podLabelSelector)
+	}
+
+	pod := m.selectPod(pods)
+	if pod == nil {
+		// 固定的Pod已不存在，不切换到其他Pod
+		m.setCurrentPod(nil)
+		return fmt.Errorf("%w: 固定的Pod '%s' 不存在", ErrPodNotFound, m.PinnedPod())
+	}
+	m.setCurrentPod(pod)
+
+	// 尝试获取外部IP (如果存在LoadBalancer或NodePort服务)
+	if services, err := m.listServices(); err == nil {
+		for _, service := range services {
+			// 确保服务的类型是LoadBalancer或NodePort
+			if service.Spec.Type == corev1.ServiceTypeLoadBalancer || service.Spec.Type == corev1.ServiceTypeNodePort {
+				for _, port := range service.Spec.Ports {
+					// 检查服务端口是否与游戏端口匹配
+					if port.Port == int32(m.gamePort) || port.TargetPort.IntVal == int32(m.gamePort) {
+						if len(service.Status.LoadBalancer.Ingress) > 0 {
+							m.updateStatus(func(status *ServerStatus) {
+								status.ExternalIP = service.Status.LoadBalancer.Ingress[0].IP
+							})
+						} else if len(service.Spec.ExternalIPs) > 0 {
+							m.updateStatus(func(status *ServerStatus) {
+								status.ExternalIP = service.Spec.ExternalIPs[0]
+							})
+						}
+						break
+					}
+				}
+			}
+		}
+	}
+
+	m.markPodInfoUpdated()
+	return nil
+}
+
+// selectPod 从匹配的Pod中选择要使用的Pod
+// 固定了Pod时只选择该Pod，不存在则返回nil；
+// 否则优先沿用仍在运行的当前Pod，其次选择第一个Running状态且未在删除的Pod，
+// 如果没有则选时间最近的成功运行过的pod，还没有就选第一个
+func (m *MinecraftController) selectPod(pods []*corev1.Pod) *corev1.Pod {
+	m.stateMutex.RLock()
+	current, pinned := m.currentPodName, m.pinnedPod
+	m.stateMutex.RUnlock()
+
+	if pinned != "" {
+		for _, pod := range pods {
+			if pod.Name == pinned {
+				return pod
+			}
+		}
+		return nil
+	}
+
+	var selectedPod *corev1.Pod
+	var latestSucceededPod *corev1.Pod
+	var latestSucceededTime time.Time
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			if pod.Name == current {
+				return pod
+			}
+			if selectedPod == nil {
+				selectedPod = pod
+			}
+			continue
+		}
+		// 记录最近成功运行的Pod
+		if pod.Status.Phase == corev1.PodSucceeded && pod.Status.StartTime != nil {
+			if latestSucceededPod == nil || pod.Status.StartTime.Time.After(latestSucceededTime) {
+				latestSucceededPod = pod
+				latestSucceededTime = pod.Status.StartTime.Time
+			}
+		}
+	}
+	if selectedPod != nil {
+		return selectedPod
+	}
+	// 如果没有Running状态的Pod，则选择最近成功运行的Pod
+	if latestSucceededPod != nil {
+		return latestSucceededPod
+	}
+	// 如果还没有，就选择第一个Pod
+	return pods[0]
+}
+
+// setCurrentPod 将指定Pod设为当前Pod，为nil则清除当前Pod
+// 当前Pod的名称或IP变化时通知Pod变化的订阅者
+func (m *MinecraftController) setCurrentPod(pod *corev1.Pod) {
+	var name, ip, phase string
+	if pod != nil {
+		name, ip, phase = pod.Name, pod.Status.PodIP, string(pod.Status.Phase)
+	}
+
+	m.stateMutex.Lock()
+	previous, previousIP := m.currentPodName, m.serverIP
+	m.currentPodName = name
+	m.serverIP = ip
+	m.status.PodName = name
+	m.status.PodStatus = phase
+	m.status.ClusterIP = ip
+	m.stateMutex.Unlock()
+
+	if name != previous || ip != previousIP {
+		m.pods.notify(PodChange{
+			Previous:   previous,
+			Current:    name,
+			PreviousIP: previousIP,
+			CurrentIP:  ip,
+			Time:       time.Now(),
+		})
+	}
+}
+
+// StartPodInfoMonitoring 开始定期监控Pod信息
+// 此功能会定期检查Pod状态，即使没有调用任何方法也能保持信息的更新
+func (m *MinecraftController) StartPodInfoMonitoring(interval time.Duration) {
+	if interval <= 0 {
+		m.stateMutex.RLock()
+		interval = m.podInfoUpdateInterval // 使用默认间隔
+		m.stateMutex.RUnlock()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				// 静默更新，忽略错误
+				go m.updatePodInfoIfNeeded(true)
+			}
+		}
+	}()
+}
+
+// Close 关闭控制器并释放资源
+func (m *MinecraftController) Close() {
+	m.cancelFunc()
+	m.events.close()
+	m.pods.close()
+	m.closeRconPool()
+}
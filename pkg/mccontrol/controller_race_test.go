@@ -0,0 +1,219 @@
+package mccontrol
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+const testNamespace = "minecraft"
+
+// testServerPod 创建匹配测试控制器标签选择器的运行中Pod
+func testServerPod(name, ip string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{"app": "minecraft"},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: ip,
+		},
+	}
+}
+
+// closedPort 返回一个没有监听的本地端口，状态检测连接时立即失败
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+// newTestController 使用fake客户端创建控制器，RCON连接到测试服务器
+func newTestController(t *testing.T, rcon *fakeRconServer, pods ...*corev1.Pod) (*MinecraftController, *fake.Clientset) {
+	t.Helper()
+
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range pods {
+		if _, err := clientset.CoreV1().Pods(testNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("创建Pod失败: %v", err)
+		}
+	}
+	return newTestControllerFor(t, clientset, rcon), clientset
+}
+
+// newTestControllerFor 使用已有的fake客户端创建控制器，用于模拟控制台重启
+func newTestControllerFor(t *testing.T, clientset *fake.Clientset, rcon *fakeRconServer) *MinecraftController {
+	t.Helper()
+
+	config := K8sConfig{
+		Namespace:        testNamespace,
+		PodLabelSelector: "app=minecraft",
+		ContainerName:    "minecraft",
+	}
+	// 没有Pod（如已缩容到0）时返回初始化错误，控制器仍可使用
+	controller, err := newMinecraftController(clientset, &rest.Config{}, config, closedPort(t), rcon.port(), rcon.password)
+	if controller == nil {
+		t.Fatalf("创建控制器失败: %v", err)
+	}
+	t.Cleanup(controller.Close)
+	return controller
+}
+
+func TestCheckServerStatusReturnsCopy(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+
+	controller.updateStatus(func(status *ServerStatus) {
+		status.PlayerSample = []MCOnlinePlayer{{Name: "Steve"}}
+	})
+
+	// 服务器端口未监听，状态检测结果为离线
+	status, err := controller.CheckServerStatus()
+	if err != nil {
+		t.Fatalf("状态检测失败: %v", err)
+	}
+	if status.Online || status.PodName != "mc-0" || status.LastError == "" {
+		t.Fatalf("状态检测结果错误: %+v", status)
+	}
+	status.PodName = "changed"
+	status.Online = true
+
+	snapshot := controller.LastStatus()
+	if snapshot.PodName != "mc-0" || snapshot.Online {
+		t.Fatalf("修改返回的状态影响了控制器: %+v", snapshot)
+	}
+
+	controller.updateStatus(func(status *ServerStatus) {
+		status.PlayerSample = []MCOnlinePlayer{{Name: "Steve"}}
+	})
+	snapshot = controller.LastStatus()
+	snapshot.PlayerSample[0].Name = "Alex"
+	if name := controller.LastStatus().PlayerSample[0].Name; name != "Steve" {
+		t.Fatalf("修改返回的玩家样本影响了控制器: %q", name)
+	}
+}
+
+// TestControllerConcurrentAccess 并发执行状态检测、Pod更新、命令和日志流，需使用-race运行
+func TestControllerConcurrentAccess(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon,
+		testServerPod("mc-0", "127.0.0.1"),
+		testServerPod("mc-1", "127.0.0.1"),
+	)
+	pods := clientset.CoreV1().Pods(testNamespace)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(work func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				work()
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+
+	// 状态检测与状态读取
+	run(func() {
+		if status, _ := controller.CheckServerStatus(); status != nil {
+			status.PodName = ""
+			status.PlayerSample = append(status.PlayerSample, MCOnlinePlayer{Name: "Steve"})
+		}
+	})
+	run(func() {
+		status := controller.LastStatus()
+		status.PlayerSample = append(status.PlayerSample, MCOnlinePlayer{Name: "Alex"})
+	})
+
+	// Pod更新：修改Pod状态触发informer事件，同时强制刷新Pod信息
+	run(func() {
+		pod, err := pods.Get(context.Background(), "mc-1", metav1.GetOptions{})
+		if err != nil {
+			return
+		}
+		if pod.Status.Phase == corev1.PodRunning {
+			pod.Status.Phase = corev1.PodPending
+		} else {
+			pod.Status.Phase = corev1.PodRunning
+		}
+		pods.UpdateStatus(context.Background(), pod, metav1.UpdateOptions{})
+	})
+	run(func() {
+		controller.ForceUpdatePodInfo()
+	})
+	run(func() {
+		controller.ListPods()
+		controller.PinnedPod()
+	})
+	run(func() {
+		if err := controller.PinPod("mc-0"); err != nil {
+			t.Errorf("固定Pod失败: %v", err)
+		}
+		controller.UnpinPod()
+	})
+
+	// 命令执行，RCON连接到测试服务器
+	run(func() {
+		response, err := controller.ExecuteCommand("list")
+		if err != nil {
+			t.Errorf("执行命令失败: %v", err)
+		} else if response != "list" {
+			t.Errorf("命令响应错误: %q", response)
+		}
+	})
+	run(func() {
+		controller.BroadcastCommand(context.Background(), "say hi", ExecutorRcon)
+	})
+	session, err := controller.CreateCommandSession(time.Minute, ExecutorRcon)
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	run(func() {
+		if _, err := controller.SessionExecuteCommand(session.GetID(), "list"); err != nil {
+			t.Errorf("会话执行命令失败: %v", err)
+		}
+	})
+
+	// 日志：一次性读取与流式监听
+	run(func() {
+		controller.FetchLogs(LogOptions{}, nil)
+	})
+	logStop := make(chan struct{})
+	closed := make(chan struct{})
+	if _, err := controller.FetchLogs(LogOptions{StopSignal: logStop, OnClose: func() { close(closed) }}, func([]string, string) {}); err != nil {
+		t.Fatalf("启动日志流失败: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	close(logStop)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("日志流未在停止信号后结束")
+	}
+}
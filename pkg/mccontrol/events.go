@@ -0,0 +1,459 @@
+package mccontrol
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType 表示服务器事件类型
+type EventType string
+
+const (
+	// EventPlayerJoin 玩家加入
+	EventPlayerJoin EventType = "player_join"
+
+	// EventPlayerLeave 玩家离开
+	EventPlayerLeave EventType = "player_leave"
+
+	// EventChat 玩家聊天
+	EventChat EventType = "chat"
+
+	// EventDeath 玩家死亡
+	EventDeath EventType = "death"
+
+	// EventServerState 服务器状态变化
+	EventServerState EventType = "server_state"
+
+	// EventRestart 通过RestartServer重启服务器的进度
+	EventRestart EventType = "restart"
+)
+
+// EventSource 表示事件的来源
+type EventSource string
+
+const (
+	// EventSourceLog 事件来自服务器日志
+	EventSourceLog EventSource = "log"
+
+	// EventSourceStatus 事件来自状态检测（Ping）
+	EventSourceStatus EventSource = "status"
+
+	// EventSourceRestart 事件来自控制器发起的重启
+	EventSourceRestart EventSource = "restart"
+
+	// EventSourceScale 事件来自闲置缩容或唤醒
+	EventSourceScale EventSource = "scale"
+)
+
+// ServerState 表示服务器运行状态
+type ServerState string
+
+const (
+	ServerStateOnline   ServerState = "online"   // 状态检测发现服务器上线
+	ServerStateOffline  ServerState = "offline"  // 状态检测发现服务器离线
+	ServerStateStarted  ServerState = "started"  // 日志显示服务器启动完成
+	ServerStateStopping ServerState = "stopping" // 日志显示服务器正在停止
+	ServerStateSleeping ServerState = "sleeping" // 服务器已缩容到0
+	ServerStateWaking   ServerState = "waking"   // 正在唤醒已缩容的服务器
+)
+
+// Event 表示一个服务器事件
+type Event struct {
+	Type    EventType    `json:"type"`              // 事件类型
+	Time    time.Time    `json:"time"`              // 事件发生时间
+	Source  EventSource  `json:"source"`            // 事件来源
+	Player  string       `json:"player,omitempty"`  // 相关玩家
+	Message string       `json:"message,omitempty"` // 聊天内容、死亡信息或重启进度
+	State   ServerState  `json:"state,omitempty"`   // 服务器状态，仅EventServerState事件有效
+	Phase   RestartPhase `json:"phase,omitempty"`   // 重启阶段，仅EventRestart事件有效
+	Log     *LogEntry    `json:"log,omitempty"`     // 来源日志，仅来自日志的事件有效
+}
+
+// EventFilter 事件订阅过滤条件，空字段表示不过滤
+type EventFilter struct {
+	Types   []EventType // 只接收这些类型的事件
+	Players []string    // 只接收与这些玩家相关的事件（不含服务器状态事件）
+}
+
+// Match 检查事件是否满足过滤条件
+func (f EventFilter) Match(event Event) bool {
+	if len(f.Types) > 0 && !containsValue(f.Types, event.Type) {
+		return false
+	}
+	if len(f.Players) > 0 && !containsValue(f.Players, event.Player) {
+		return false
+	}
+	return true
+}
+
+// containsValue 检查切片中是否包含指定值
+func containsValue[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	eventBufferSize          = 64               // 每个订阅者的事件缓冲区大小
+	eventDedupSize           = 512              // 用于日志去重的最近日志数量
+	defaultEventPollInterval = 30 * time.Second // 默认状态检测间隔
+)
+
+// anonymousPlayerID 服务器隐藏玩家列表或插件自定义悬浮文本时使用的UUID
+const anonymousPlayerID = "00000000-0000-0000-0000-000000000000"
+
+// eventBus 根据结构化日志和状态检测生成服务器事件
+// 第一个订阅者订阅时开始监听日志和定期检测状态，最后一个订阅者取消订阅时停止
+type eventBus struct {
+	controller   *MinecraftController
+	pollInterval time.Duration
+
+	subscribers map[chan Event]EventFilter
+	stop        chan struct{} // 为nil表示未在运行
+	logRunning  bool          // 日志流是否正在运行
+
+	// 状态跟踪
+	statusKnown    bool            // 是否已获得过状态检测结果
+	online         bool            // 最近一次状态检测的在线状态
+	sampleComplete bool            // 最近一次状态检测的玩家样本是否包含所有在线玩家
+	players        map[string]bool // 已知的在线玩家
+
+	// 日志去重，日志流重连时会重复收到断点附近的日志
+	lastLogTime time.Time
+	recentLogs  map[string]bool
+	recentOrder []string
+
+	mutex sync.Mutex
+}
+
+// newEventBus 创建事件总线
+func newEventBus(controller *MinecraftController) *eventBus {
+	return &eventBus{
+		controller:   controller,
+		pollInterval: defaultEventPollInterval,
+		subscribers:  make(map[chan Event]EventFilter),
+		players:      make(map[string]bool),
+		recentLogs:   make(map[string]bool),
+	}
+}
+
+// Subscribe 订阅服务器事件
+// 事件由服务器日志和定期状态检测共同生成：玩家加入/离开、聊天、死亡以及服务器状态变化
+// 订阅者处理过慢导致缓冲区已满时，新事件会被丢弃；控制器关闭时通道会被关闭
+func (m *MinecraftController) Subscribe(filter EventFilter) <-chan Event {
+	return m.events.subscribe(filter)
+}
+
+// Unsubscribe 取消订阅并关闭事件通道
+func (m *MinecraftController) Unsubscribe(ch <-chan Event) {
+	m.events.unsubscribe(ch)
+}
+
+// SetEventPollInterval 设置事件总线的状态检测间隔，在下次开始监听时生效
+func (m *MinecraftController) SetEventPollInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultEventPollInterval
+	}
+	m.events.mutex.Lock()
+	defer m.events.mutex.Unlock()
+	m.events.pollInterval = interval
+}
+
+// subscribe 添加订阅者，必要时开始监听
+func (b *eventBus) subscribe(filter EventFilter) <-chan Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ch := make(chan Event, eventBufferSize)
+	if b.controller.ctx.Err() != nil {
+		close(ch)
+		return ch
+	}
+
+	b.subscribers[ch] = filter
+	if b.stop == nil {
+		b.start()
+	}
+	return ch
+}
+
+// unsubscribe 移除订阅者，没有订阅者时停止监听
+func (b *eventBus) unsubscribe(ch <-chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for subscriber := range b.subscribers {
+		if subscriber == ch {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+			break
+		}
+	}
+
+	if len(b.subscribers) == 0 && b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+}
+
+// close 关闭所有订阅者
+func (b *eventBus) close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for subscriber := range b.subscribers {
+		close(subscriber)
+	}
+	b.subscribers = make(map[chan Event]EventFilter)
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+}
+
+// start 开始监听，调用方需持有锁
+func (b *eventBus) start() {
+	stop := make(chan struct{})
+	b.stop = stop
+	b.logRunning = false
+
+	// 停止期间的状态变化无从得知，重新建立基准
+	b.statusKnown = false
+	b.sampleComplete = false
+	b.players = make(map[string]bool)
+	b.lastLogTime = time.Time{}
+
+	go b.run(stop, b.pollInterval)
+}
+
+// run 定期检测服务器状态，并在日志流断开时重新连接
+func (b *eventBus) run(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		b.ensureLogStream(stop)
+		b.controller.CheckServerStatus() // 结果通过observeStatus处理
+
+		select {
+		case <-b.controller.ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureLogStream 如果日志流未运行则启动
+func (b *eventBus) ensureLogStream(stop chan struct{}) {
+	b.mutex.Lock()
+	if b.logRunning || b.stop != stop {
+		b.mutex.Unlock()
+		return
+	}
+	b.logRunning = true
+	since := b.lastLogTime
+	if since.IsZero() {
+		since = time.Now()
+	}
+	b.mutex.Unlock()
+
+	_, err := b.controller.FetchLogEntries(LogOptions{
+		SinceTime:  &since,
+		StopSignal: stop,
+		OnClose: func() {
+			b.mutex.Lock()
+			if b.stop == stop {
+				b.logRunning = false
+			}
+			b.mutex.Unlock()
+		},
+	}, func(entries []LogEntry, errMsg string) {
+		for i := range entries {
+			b.observeLog(&entries[i])
+		}
+	})
+	if err != nil {
+		// 日志流未能启动，下次检测时重试
+		b.mutex.Lock()
+		if b.stop == stop {
+			b.logRunning = false
+		}
+		b.mutex.Unlock()
+		log.Printf("事件总线启动日志流失败: %v", err)
+	}
+}
+
+// observeLog 处理一条结构化日志
+// 所有日志都参与去重并推进lastLogTime，日志流重连时从最后收到的日志继续，而不是最后一条产生事件的日志
+func (b *eventBus) observeLog(entry *LogEntry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.isDuplicateLog(entry) || entry.Event == nil {
+		return
+	}
+
+	event := Event{
+		Time:   entry.Time,
+		Source: EventSourceLog,
+		Player: entry.Event.Player,
+		Log:    entry,
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	switch entry.Event.Type {
+	case LogEventPlayerJoin:
+		if b.players[event.Player] {
+			return // 状态检测已发现该玩家
+		}
+		b.players[event.Player] = true
+		event.Type = EventPlayerJoin
+	case LogEventPlayerLeave:
+		if !b.players[event.Player] && b.sampleComplete {
+			// 状态检测已发现该玩家离开
+			return
+		}
+		delete(b.players, event.Player)
+		event.Type = EventPlayerLeave
+	case LogEventChat:
+		event.Type = EventChat
+		event.Message = entry.Event.Message
+	case LogEventDeath:
+		event.Type = EventDeath
+		event.Message = entry.Event.Message
+	case LogEventServerStarted:
+		event.Type = EventServerState
+		event.State = ServerStateStarted
+		event.Player = ""
+	case LogEventServerStopping:
+		event.Type = EventServerState
+		event.State = ServerStateStopping
+		event.Player = ""
+	default:
+		return
+	}
+
+	b.publish(event)
+}
+
+// isDuplicateLog 检查日志是否已处理过，调用方需持有锁
+func (b *eventBus) isDuplicateLog(entry *LogEntry) bool {
+	if entry.Time.IsZero() {
+		return false
+	}
+	if entry.Time.Before(b.lastLogTime) {
+		return true
+	}
+
+	key := entry.Time.Format(time.RFC3339Nano) + " " + entry.Raw
+	if b.recentLogs[key] {
+		return true
+	}
+
+	b.lastLogTime = entry.Time
+	b.recentLogs[key] = true
+	b.recentOrder = append(b.recentOrder, key)
+	if len(b.recentOrder) > eventDedupSize {
+		delete(b.recentLogs, b.recentOrder[0])
+		b.recentOrder = b.recentOrder[1:]
+	}
+	return false
+}
+
+// observeStatus 处理一次状态检测结果
+func (b *eventBus) observeStatus(status ServerStatus) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.stop == nil {
+		return
+	}
+
+	now := status.LastChecked
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// 首次检测结果只作为基准，不产生事件
+	first := !b.statusKnown
+	b.statusKnown = true
+
+	if status.Online != b.online {
+		b.online = status.Online
+		if !first {
+			state := ServerStateOffline
+			if status.Online {
+				state = ServerStateOnline
+			}
+			b.publish(Event{Type: EventServerState, Time: now, Source: EventSourceStatus, State: state})
+		}
+	}
+
+	if !status.Online {
+		// 服务器离线时所有玩家都已离开
+		b.sampleComplete = true
+		for player := range b.players {
+			delete(b.players, player)
+			if !first {
+				b.publish(Event{Type: EventPlayerLeave, Time: now, Source: EventSourceStatus, Player: player})
+			}
+		}
+		return
+	}
+
+	sample := make(map[string]bool, len(status.PlayerSample))
+	for _, player := range status.PlayerSample {
+		if player.ID == anonymousPlayerID || player.Name == "" {
+			continue
+		}
+		sample[player.Name] = true
+		if !b.players[player.Name] {
+			b.players[player.Name] = true
+			if !first {
+				b.publish(Event{Type: EventPlayerJoin, Time: now, Source: EventSourceStatus, Player: player.Name})
+			}
+		}
+	}
+
+	// 样本最多只包含部分玩家，只有样本完整时才能判断玩家离开
+	b.sampleComplete = len(sample) == status.Players
+	if b.sampleComplete {
+		for player := range b.players {
+			if !sample[player] {
+				delete(b.players, player)
+				if !first {
+					b.publish(Event{Type: EventPlayerLeave, Time: now, Source: EventSourceStatus, Player: player})
+				}
+			}
+		}
+	}
+}
+
+// emit 发布不来自日志和状态检测的事件
+func (b *eventBus) emit(event Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.publish(event)
+}
+
+// publish 将事件发送给匹配的订阅者，调用方需持有锁
+func (b *eventBus) publish(event Event) {
+	for subscriber, filter := range b.subscribers {
+		if !filter.Match(event) {
+			continue
+		}
+		select {
+		case subscriber <- event:
+		default:
+			// 订阅者处理过慢，丢弃事件
+		}
+	}
+}
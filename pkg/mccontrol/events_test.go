@@ -0,0 +1,115 @@
+package mccontrol
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// testLogEntry 解析带Kubernetes时间戳的日志行
+func testLogEntry(parser *LogParser, ts time.Time, line string) *LogEntry {
+	entry := parser.Parse(ts.UTC().Format(time.RFC3339Nano) + " " + line)
+	return &entry
+}
+
+// logRequestSince 返回fake客户端收到的日志请求的起始时间
+func logRequestSince(clientset *fake.Clientset) []time.Time {
+	var since []time.Time
+	for _, action := range clientset.Actions() {
+		generic, ok := action.(k8stesting.GenericAction)
+		if !ok || action.GetSubresource() != "log" {
+			continue
+		}
+		if options, ok := generic.GetValue().(*corev1.PodLogOptions); ok && options.SinceTime != nil {
+			since = append(since, options.SinceTime.Time)
+		}
+	}
+	return since
+}
+
+func TestEventBusLogDedup(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+	bus := controller.events
+	events := make(chan Event, 10)
+	bus.mutex.Lock()
+	bus.subscribers[events] = EventFilter{}
+	bus.mutex.Unlock()
+
+	parser := NewLogParser()
+	start := time.Now().Truncate(time.Second)
+	join := testLogEntry(parser, start, "[12:00:00] [Server thread/INFO]: Steve joined the game")
+	bus.observeLog(join)
+
+	// 不产生事件的日志也推进最后处理的日志时间
+	info := testLogEntry(parser, start.Add(time.Second), "[12:00:01] [Server thread/INFO]: Saving the game (this may take a moment!)")
+	bus.observeLog(info)
+	bus.mutex.Lock()
+	lastLogTime := bus.lastLogTime
+	bus.mutex.Unlock()
+	if !lastLogTime.Equal(info.Time) {
+		t.Fatalf("最后处理的日志时间应为最后一行日志的时间: 期望 %v, 实际 %v", info.Time, lastLogTime)
+	}
+
+	// 日志流重连时重复收到的日志不再产生事件
+	bus.observeLog(join)
+	bus.observeLog(info)
+	leave := testLogEntry(parser, start.Add(time.Second), "[12:00:01] [Server thread/INFO]: Steve left the game")
+	bus.observeLog(leave)
+	bus.observeLog(leave)
+
+	var got []EventType
+	for len(events) > 0 {
+		got = append(got, (<-events).Type)
+	}
+	if len(got) != 2 || got[0] != EventPlayerJoin || got[1] != EventPlayerLeave {
+		t.Fatalf("重复的日志不应重复产生事件: %v", got)
+	}
+}
+
+func TestEventBusLogStreamResumes(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+	bus := controller.events
+
+	// startStream 与start一样切换到新的停止信号并启动日志流，但保留最后处理的日志时间，返回日志请求的起始时间
+	startStream := func(stop chan struct{}) time.Time {
+		t.Helper()
+		bus.mutex.Lock()
+		bus.stop = stop
+		bus.logRunning = false
+		bus.mutex.Unlock()
+
+		requests := len(logRequestSince(clientset))
+		bus.ensureLogStream(stop)
+		since := logRequestSince(clientset)
+		if len(since) <= requests {
+			t.Fatal("应启动日志流")
+		}
+		return since[requests]
+	}
+
+	// 日志流从最后处理的日志（包括不产生事件的日志）继续
+	parser := NewLogParser()
+	last := time.Now().Add(-time.Minute).Truncate(time.Second)
+	bus.observeLog(testLogEntry(parser, last, "[12:00:00] [Server thread/INFO]: Saving the game (this may take a moment!)"))
+
+	first := make(chan struct{})
+	if since := startStream(first); !since.Equal(last) {
+		t.Fatalf("日志流应从最后处理的日志继续: 期望 %v, 实际 %v", last, since)
+	}
+
+	// 日志流停止后重新启动，起始时间为之后处理的最后一行日志
+	last = last.Add(10 * time.Second)
+	bus.observeLog(testLogEntry(parser, last, "[12:00:10] [Server thread/INFO]: Saved the game"))
+	close(first)
+
+	// 控制器关闭时关闭当前的停止信号
+	second := make(chan struct{})
+	if since := startStream(second); !since.Equal(last) {
+		t.Fatalf("重新启动的日志流应从最后处理的日志继续: 期望 %v, 实际 %v", last, since)
+	}
+}
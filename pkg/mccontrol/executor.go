@@ -1,119 +1,194 @@
-package mccontrol
-
-import (
-	"fmt"
-)
-
-// CreateCommandExecutor 创建命令执行器
-// 根据指定的类型创建相应的命令执行器实例
-// 如果类型为ExecutorAuto，则会按照RCON、Attach、Exec的顺序尝试创建
-func (m *MinecraftController) CreateCommandExecutor(executorType ExecutorType) (CommandExecutor, error) {
-	// 如果是自动模式，按优先级尝试不同执行器
-	if executorType == ExecutorAuto {
-		// 优先尝试RCON
-		executor, err := m.createRconExecutor()
-		if err == nil {
-			return executor, nil
-		}
-
-		// RCON失败，尝试Attach
-		executor, err = m.createAttachExecutor()
-		if err == nil {
-			return executor, nil
-		}
-
-		// Attach失败，尝试Exec
-		return m.createExecExecutor()
-	}
-
-	// 根据指定类型创建执行器
-	switch executorType {
-	case ExecutorRcon:
-		return m.createRconExecutor()
-	case ExecutorAttach:
-		return m.createAttachExecutor()
-	case ExecutorExec:
-		return m.createExecExecutor()
-	default:
-		return nil, fmt.Errorf("不支持的执行器类型: %s", executorType)
-	}
-}
-
-// ExecuteRconCommand 执行Minecraft命令
-// 使用自动选择的命令执行器执行单个命令
-func (m *MinecraftController) ExecuteCommand(command string) (string, error) {
-	// 先确保我们有最新的Pod信息
-	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
-		return "", fmt.Errorf("更新Pod信息失败: %v", err)
-	}
-
-	// 创建一次性命令执行器（自动选择最合适的方式）
-	executor, err := m.CreateCommandExecutor(ExecutorAuto)
-	if err != nil {
-		return "", fmt.Errorf("创建命令执行器失败: %v", err)
-	}
-	defer executor.Disconnect()
-
-	// 执行命令
-	response, err := executor.ExecuteCommand(command)
-	if err != nil {
-		return "", fmt.Errorf("命令执行失败: %v", err)
-	}
-
-	return response, nil
-}
-
-// createRconExecutor 创建RCON执行器
-func (m *MinecraftController) createRconExecutor() (CommandExecutor, error) {
-	if m.rconPort == 0 {
-		return nil, fmt.Errorf("RCON端口未设置")
-	}
-
-	// 确保有最新的Pod信息
-	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
-		return nil, fmt.Errorf("更新Pod信息失败: %v", err)
-	}
-
-	// 创建RCON执行器
-	executor := newRconExecutor(m.serverIP, m.rconPort, m.rconPassword)
-
-	// 尝试连接
-	if err := executor.Connect(); err != nil {
-		return nil, fmt.Errorf("RCON连接失败: %v", err)
-	}
-
-	return executor, nil
-}
-
-// createAttachExecutor 创建Attach执行器
-func (m *MinecraftController) createAttachExecutor() (CommandExecutor, error) {
-	// 确保有最新的Pod信息
-	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
-		return nil, fmt.Errorf("更新Pod信息失败: %v", err)
-	}
-
-	// 创建Attach执行器 - 传递REST配置
-	executor := newAttachExecutor(m.clientset, m.restConfig, m.namespace, m.currentPodName, m.containerName)
-
-	// 尝试连接
-	if err := executor.Connect(); err != nil {
-		return nil, fmt.Errorf("attach连接失败: %v", err)
-	}
-
-	return executor, nil
-}
-
-// createExecExecutor 创建Exec执行器
-func (m *MinecraftController) createExecExecutor() (CommandExecutor, error) {
-	// 确保有最新的Pod信息
-	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
-		return nil, fmt.Errorf("更新Pod信息失败: %v", err)
-	}
-
-	// 创建Exec执行器 - 传递REST配置
-	executor := newExecExecutor(m.clientset, m.restConfig, m.namespace, m.currentPodName, m.containerName)
-
-	// Exec执行器不需要持久连接，因此不调用Connect
-
-	return executor, nil
-}
+package mccontrol
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CreateCommandExecutor 创建命令执行器
+// 根据指定的类型创建相应的命令执行器实例
+// 如果类型为ExecutorAuto，则优先使用最近一次成功的执行器，并定期按照RCON、Attach、Exec的顺序重新探测，
+// 全部失败时返回*ExecutorSelectionError
+func (m *MinecraftController) CreateCommandExecutor(executorType ExecutorType) (CommandExecutor, error) {
+	return m.createCommandExecutor(executorType, false)
+}
+
+// createCommandExecutor 创建命令执行器
+// persistent为true时创建供命令会话长期使用的执行器，attach执行器会保持持久数据流
+func (m *MinecraftController) createCommandExecutor(executorType ExecutorType, persistent bool) (CommandExecutor, error) {
+	return m.createTargetExecutor(executorType, persistent, nil)
+}
+
+// createTargetExecutor 创建命令执行器，target为nil时使用控制器当前的Pod，否则固定使用指定的Pod
+func (m *MinecraftController) createTargetExecutor(executorType ExecutorType, persistent bool, target *podTarget) (CommandExecutor, error) {
+	// 如果是自动模式，按执行器的健康状态依次尝试
+	if executorType == ExecutorAuto {
+		return m.createAutoExecutor(persistent, target, "")
+	}
+	return m.newExecutor(executorType, persistent, target)
+}
+
+// createTypedExecutor 根据指定类型创建执行器
+func (m *MinecraftController) createTypedExecutor(executorType ExecutorType, persistent bool, target *podTarget) (CommandExecutor, error) {
+	switch executorType {
+	case ExecutorRcon:
+		return m.createRconExecutor(target)
+	case ExecutorAttach:
+		return m.createAttachExecutor(persistent, target)
+	case ExecutorExec:
+		return m.createExecExecutor(target)
+	default:
+		return nil, fmt.Errorf("不支持的执行器类型: %s", executorType)
+	}
+}
+
+// ExecuteRconCommand 执行Minecraft命令
+// 使用自动选择的命令执行器执行单个命令
+func (m *MinecraftController) ExecuteCommand(command string) (string, error) {
+	return m.ExecuteCommandContext(context.Background(), command)
+}
+
+// ExecuteCommandContext 执行Minecraft命令，上下文取消时中止执行
+func (m *MinecraftController) ExecuteCommandContext(ctx context.Context, command string) (string, error) {
+	result, err := m.ExecuteCommandDetailed(ctx, command)
+	if err != nil {
+		return "", err
+	}
+	return result.Response, nil
+}
+
+// ExecuteCommandDetailed 执行Minecraft命令并返回执行器类型、耗时等详细信息
+// 执行失败时也会返回结果，以便调用方记录实际使用的执行器和耗时
+func (m *MinecraftController) ExecuteCommandDetailed(ctx context.Context, command string) (*CommandResult, error) {
+	start := time.Now()
+	result := &CommandResult{}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	// 先确保我们有最新的Pod信息
+	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
+		result.Duration = time.Since(start)
+		return result, fmt.Errorf("更新Pod信息失败: %v", err)
+	}
+
+	// 创建一次性命令执行器（自动选择最合适的方式）
+	executor, err := m.CreateCommandExecutor(ExecutorAuto)
+	if err != nil {
+		result.Duration = time.Since(start)
+		return result, fmt.Errorf("创建命令执行器失败: %w", err)
+	}
+	defer executor.Disconnect()
+	result.ExecutorType = executorTypeOf(executor)
+
+	// 执行命令
+	executeStart := time.Now()
+	response, err := executor.ExecuteCommandContext(ctx, command)
+	m.observeCommand(result.ExecutorType, executeStart, err)
+	m.executors.record(result.ExecutorType, err)
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("命令执行失败: %w", err)
+	}
+
+	result.Response = response
+	return result, nil
+}
+
+// createRconExecutor 创建RCON执行器
+func (m *MinecraftController) createRconExecutor(target *podTarget) (CommandExecutor, error) {
+	if m.rconPort == 0 {
+		return nil, fmt.Errorf("RCON端口未设置")
+	}
+
+	var executor *rconExecutor
+	if target != nil {
+		// 指定Pod的执行器使用自己的连接池
+		executor = m.newPodRconExecutor(target.ip)
+	} else {
+		// 确保有最新的Pod信息
+		if _, err := m.updatePodInfoIfNeeded(false); err != nil {
+			return nil, fmt.Errorf("更新Pod信息失败: %v", err)
+		}
+
+		// 创建RCON执行器，所有执行器共享控制器的连接池
+		executor = newPooledRconExecutor(m.getRconPool, m.rconRetryDelay)
+	}
+	executor.reloadPassword = m.reloadRconPassword
+
+	// 尝试连接
+	if err := executor.Connect(); err != nil {
+		executor.Disconnect() // 关闭执行器自己创建的连接池
+		return nil, fmt.Errorf("RCON连接失败: %w", err)
+	}
+
+	return executor, nil
+}
+
+// createAttachExecutor 创建Attach执行器
+// persistent为true时执行器保持持久数据流，断开后重连到最新的Pod；指定了Pod时始终连接该Pod
+func (m *MinecraftController) createAttachExecutor(persistent bool, target *podTarget) (CommandExecutor, error) {
+	podName, err := m.targetPodName(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if coreRESTClient(m.clientset) == nil {
+		return nil, fmt.Errorf("Kubernetes客户端不支持attach")
+	}
+
+	// 创建Attach执行器 - 传递REST配置
+	executor := newAttachExecutor(m.clientset, m.restConfig, m.namespace, podName, m.containerName)
+	executor.output = m.newOutputCapture()
+	executor.persistent = persistent
+	if persistent && target == nil {
+		executor.refreshPod = func() (string, error) {
+			if _, err := m.updatePodInfoIfNeeded(true); err != nil {
+				return "", err
+			}
+			return m.podName(), nil
+		}
+	}
+
+	// 尝试连接
+	if err := executor.Connect(); err != nil {
+		return nil, fmt.Errorf("attach连接失败: %v", err)
+	}
+
+	return executor, nil
+}
+
+// createExecExecutor 创建Exec执行器
+func (m *MinecraftController) createExecExecutor(target *podTarget) (CommandExecutor, error) {
+	podName, err := m.targetPodName(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if coreRESTClient(m.clientset) == nil {
+		return nil, fmt.Errorf("Kubernetes客户端不支持exec")
+	}
+
+	// 创建Exec执行器 - 传递REST配置
+	executor := newExecExecutor(m.clientset, m.restConfig, m.namespace, podName, m.containerName)
+	executor.output = m.newOutputCapture()
+
+	// Exec执行器不需要持久连接，因此不调用Connect
+
+	return executor, nil
+}
+
+// targetPodName 返回执行器要使用的Pod名称，target为nil时使用控制器当前的Pod
+func (m *MinecraftController) targetPodName(target *podTarget) (string, error) {
+	if target != nil {
+		return target.name, nil
+	}
+
+	// 确保有最新的Pod信息
+	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
+		return "", fmt.Errorf("更新Pod信息失败: %v", err)
+	}
+	return m.podName(), nil
+}
@@ -0,0 +1,221 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 自动选择执行器的默认参数
+const (
+	defaultExecutorProbeInterval = 5 * time.Minute // 重新探测优先级更高的执行器的间隔
+	executorCooldownBase         = 5 * time.Second // 执行器失败后的冷却时间基准，连续失败时指数增长
+	executorCooldownMax          = 5 * time.Minute // 执行器失败后的最长冷却时间
+	executorScoreWeight          = 0.3             // 健康评分中最近一次结果的权重
+)
+
+// executorPriority 自动模式下执行器的默认优先级
+var executorPriority = []ExecutorType{ExecutorRcon, ExecutorAttach, ExecutorExec}
+
+// ExecutorAttempt 自动选择执行器时对一种执行器的尝试结果
+type ExecutorAttempt struct {
+	Type ExecutorType // 执行器类型
+	Err  error        // 创建或连接失败的原因
+}
+
+// ExecutorSelectionError 自动选择时所有执行器都不可用
+// 包含每种执行器失败的原因，可以通过errors.Is/errors.As检查其中的错误
+type ExecutorSelectionError struct {
+	Attempts []ExecutorAttempt
+}
+
+// Error 实现error接口
+func (e *ExecutorSelectionError) Error() string {
+	parts := make([]string, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		parts = append(parts, fmt.Sprintf("%s: %v", attempt.Type, attempt.Err))
+	}
+	return "没有可用的命令执行器 (" + strings.Join(parts, "; ") + ")"
+}
+
+// Unwrap 返回每种执行器的错误
+func (e *ExecutorSelectionError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts))
+	for _, attempt := range e.Attempts {
+		errs = append(errs, attempt.Err)
+	}
+	return errs
+}
+
+// ExecutorHealth 执行器的健康状态
+type ExecutorHealth struct {
+	Type                ExecutorType `json:"type"`                 // 执行器类型
+	Score               float64      `json:"score"`                // 健康评分，0~1，越高越健康
+	Successes           int64        `json:"successes"`            // 成功次数
+	Failures            int64        `json:"failures"`             // 失败次数
+	ConsecutiveFailures int          `json:"consecutive_failures"` // 连续失败次数
+	LastSuccess         time.Time    `json:"last_success"`         // 最后一次成功的时间
+	LastFailure         time.Time    `json:"last_failure"`         // 最后一次失败的时间
+	LastError           string       `json:"last_error"`           // 最后一次失败的原因
+	CoolingUntil        time.Time    `json:"cooling_until"`        // 冷却结束时间，冷却期间排在其他执行器之后
+	Preferred           bool         `json:"preferred"`            // 是否为最近一次成功使用的执行器
+}
+
+// executorSelector 记录每种执行器的健康状态，决定自动模式下尝试执行器的顺序
+// 最近一次成功的执行器会被优先使用，并定期按默认优先级重新探测，
+// 以便优先级更高的执行器（如RCON）恢复后重新被选中
+type executorSelector struct {
+	health        map[ExecutorType]*ExecutorHealth
+	preferred     ExecutorType  // 最近一次成功的执行器
+	probeInterval time.Duration // 重新探测间隔，为0则每次都按默认优先级尝试
+	lastProbe     time.Time     // 上次按默认优先级尝试的时间
+	mutex         sync.Mutex
+}
+
+// newExecutorSelector 创建执行器选择器
+func newExecutorSelector() *executorSelector {
+	health := make(map[ExecutorType]*ExecutorHealth, len(executorPriority))
+	for _, executorType := range executorPriority {
+		health[executorType] = &ExecutorHealth{Type: executorType, Score: 1}
+	}
+	return &executorSelector{
+		health:        health,
+		probeInterval: defaultExecutorProbeInterval,
+	}
+}
+
+// order 返回本次自动选择时尝试执行器的顺序
+// 到达探测间隔时按默认优先级尝试；否则最近成功的执行器优先，
+// 其余执行器中不在冷却期的优先，再按健康评分和默认优先级排序
+func (s *executorSelector) order() []ExecutorType {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	order := make([]ExecutorType, len(executorPriority))
+	copy(order, executorPriority)
+
+	if s.preferred == "" || s.probeInterval <= 0 || now.Sub(s.lastProbe) >= s.probeInterval {
+		s.lastProbe = now
+		return order
+	}
+
+	rank := func(executorType ExecutorType) int {
+		if executorType == s.preferred {
+			return 0
+		}
+		if now.Before(s.health[executorType].CoolingUntil) {
+			return 2
+		}
+		return 1
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		ri, rj := rank(order[i]), rank(order[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return s.health[order[i]].Score > s.health[order[j]].Score
+	})
+	return order
+}
+
+// record 记录执行器的一次使用结果，上下文取消导致的失败不计入
+func (s *executorSelector) record(executorType ExecutorType, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	health, ok := s.health[executorType]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if err == nil {
+		health.Successes++
+		health.ConsecutiveFailures = 0
+		health.LastSuccess = now
+		health.CoolingUntil = time.Time{}
+		health.Score = health.Score*(1-executorScoreWeight) + executorScoreWeight
+		s.preferred = executorType
+		return
+	}
+
+	health.Failures++
+	health.ConsecutiveFailures++
+	health.LastFailure = now
+	health.LastError = err.Error()
+	health.Score = health.Score * (1 - executorScoreWeight)
+
+	cooldown := executorCooldownBase << min(health.ConsecutiveFailures-1, 16)
+	if cooldown > executorCooldownMax {
+		cooldown = executorCooldownMax
+	}
+	health.CoolingUntil = now.Add(cooldown)
+
+	if s.preferred == executorType {
+		s.preferred = ""
+	}
+}
+
+// snapshot 返回所有执行器的健康状态
+func (s *executorSelector) snapshot() []ExecutorHealth {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	result := make([]ExecutorHealth, 0, len(executorPriority))
+	for _, executorType := range executorPriority {
+		health := *s.health[executorType]
+		health.Preferred = executorType == s.preferred
+		result = append(result, health)
+	}
+	return result
+}
+
+// SetExecutorProbeInterval 设置自动选择执行器时重新探测的间隔
+// 间隔内优先使用最近一次成功的执行器；为0则每次都按RCON、Attach、Exec的顺序尝试
+func (m *MinecraftController) SetExecutorProbeInterval(interval time.Duration) {
+	m.executors.mutex.Lock()
+	defer m.executors.mutex.Unlock()
+
+	m.executors.probeInterval = interval
+}
+
+// GetExecutorHealth 获取各执行器的健康状态
+func (m *MinecraftController) GetExecutorHealth() []ExecutorHealth {
+	return m.executors.snapshot()
+}
+
+// createAutoExecutor 按选择器给出的顺序依次尝试创建执行器，target为nil时使用控制器当前的Pod
+// 全部失败时返回包含每种执行器失败原因的ExecutorSelectionError
+func (m *MinecraftController) createAutoExecutor(persistent bool, target *podTarget, exclude ExecutorType) (CommandExecutor, error) {
+	selectionErr := &ExecutorSelectionError{}
+
+	order := m.executors.order()
+	if exclude != "" {
+		// 刚失败的执行器放到最后，其他执行器都不可用时仍会再尝试一次
+		for i, executorType := range order {
+			if executorType == exclude {
+				order = append(append(order[:i:i], order[i+1:]...), exclude)
+				break
+			}
+		}
+	}
+
+	for _, executorType := range order {
+		executor, err := m.createTargetExecutor(executorType, persistent, target)
+		if err == nil {
+			return executor, nil
+		}
+		m.executors.record(executorType, err)
+		selectionErr.Attempts = append(selectionErr.Attempts, ExecutorAttempt{Type: executorType, Err: err})
+	}
+	return nil, selectionErr
+}
@@ -0,0 +1,118 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errExecutorBroken = errors.New("执行器不可用")
+
+func TestExecutorSelectorOrder(t *testing.T) {
+	selector := newExecutorSelector()
+	defaultOrder := []ExecutorType{ExecutorRcon, ExecutorAttach, ExecutorExec}
+
+	steps := []struct {
+		name   string
+		record func()
+		want   []ExecutorType
+	}{
+		{
+			name: "没有成功过的执行器时按默认优先级",
+			want: defaultOrder,
+		},
+		{
+			name:   "最近成功的执行器优先",
+			record: func() { selector.record(ExecutorAttach, nil) },
+			want:   []ExecutorType{ExecutorAttach, ExecutorRcon, ExecutorExec},
+		},
+		{
+			name:   "冷却中的执行器排在最后",
+			record: func() { selector.record(ExecutorRcon, errExecutorBroken) },
+			want:   []ExecutorType{ExecutorAttach, ExecutorExec, ExecutorRcon},
+		},
+		{
+			name: "冷却结束后按健康评分排序",
+			record: func() {
+				selector.health[ExecutorRcon].CoolingUntil = time.Now().Add(-time.Second)
+			},
+			want: []ExecutorType{ExecutorAttach, ExecutorExec, ExecutorRcon},
+		},
+		{
+			name:   "最近成功的执行器失败后不再优先",
+			record: func() { selector.record(ExecutorAttach, errExecutorBroken) },
+			// 没有最近成功的执行器时按默认优先级重新探测
+			want: defaultOrder,
+		},
+		{
+			name:   "上下文取消导致的失败不计入",
+			record: func() { selector.record(ExecutorExec, nil); selector.record(ExecutorExec, context.Canceled) },
+			want:   []ExecutorType{ExecutorExec, ExecutorRcon, ExecutorAttach},
+		},
+		{
+			name:   "到达探测间隔时按默认优先级",
+			record: func() { selector.lastProbe = time.Now().Add(-defaultExecutorProbeInterval) },
+			want:   defaultOrder,
+		},
+		{
+			name: "探测后恢复优先最近成功的执行器",
+			want: []ExecutorType{ExecutorExec, ExecutorRcon, ExecutorAttach},
+		},
+		{
+			name:   "探测间隔为0时每次都按默认优先级",
+			record: func() { selector.probeInterval = 0 },
+			want:   defaultOrder,
+		},
+	}
+	for _, step := range steps {
+		if step.record != nil {
+			step.record()
+		}
+		if got := selector.order(); !reflect.DeepEqual(got, step.want) {
+			t.Fatalf("%s: 顺序应为 %v, 实际 %v", step.name, step.want, got)
+		}
+	}
+}
+
+func TestExecutorSelectorCooldown(t *testing.T) {
+	selector := newExecutorSelector()
+
+	// 连续失败时冷却时间指数增长，不超过最长冷却时间
+	for i, want := range []time.Duration{
+		executorCooldownBase, 2 * executorCooldownBase, 4 * executorCooldownBase, 8 * executorCooldownBase,
+	} {
+		selector.record(ExecutorRcon, errExecutorBroken)
+		health := selector.health[ExecutorRcon]
+		if cooldown := health.CoolingUntil.Sub(health.LastFailure); cooldown != want {
+			t.Fatalf("第%d次连续失败的冷却时间应为 %v, 实际 %v", i+1, want, cooldown)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		selector.record(ExecutorRcon, errExecutorBroken)
+	}
+	health := selector.health[ExecutorRcon]
+	if cooldown := health.CoolingUntil.Sub(health.LastFailure); cooldown != executorCooldownMax {
+		t.Fatalf("冷却时间不应超过 %v, 实际 %v", executorCooldownMax, cooldown)
+	}
+	if health.ConsecutiveFailures != 24 || health.Failures != 24 || health.LastError != errExecutorBroken.Error() {
+		t.Fatalf("失败次数记录错误: %+v", health)
+	}
+	if health.Score >= 0.01 {
+		t.Fatalf("连续失败后健康评分应接近0: %v", health.Score)
+	}
+
+	// 成功后结束冷却，连续失败次数清零，评分回升
+	score := health.Score
+	selector.record(ExecutorRcon, nil)
+	health = selector.health[ExecutorRcon]
+	if !health.CoolingUntil.IsZero() || health.ConsecutiveFailures != 0 || health.Successes != 1 || health.Score <= score {
+		t.Fatalf("成功后应结束冷却: %+v", health)
+	}
+	for _, snapshot := range selector.snapshot() {
+		if snapshot.Preferred != (snapshot.Type == ExecutorRcon) {
+			t.Fatalf("只有最近成功的执行器应标记为优先: %+v", selector.snapshot())
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package mccontrol
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogLevel 表示日志级别
+type LogLevel string
+
+const (
+	LogLevelTrace LogLevel = "TRACE"
+	LogLevelDebug LogLevel = "DEBUG"
+	LogLevelInfo  LogLevel = "INFO"
+	LogLevelWarn  LogLevel = "WARN"
+	LogLevelError LogLevel = "ERROR"
+	LogLevelFatal LogLevel = "FATAL"
+)
+
+// LogEventType 表示从日志中识别出的事件类型
+type LogEventType string
+
+const (
+	// LogEventPlayerJoin 玩家加入游戏
+	LogEventPlayerJoin LogEventType = "player_join"
+
+	// LogEventPlayerLeave 玩家离开游戏
+	LogEventPlayerLeave LogEventType = "player_leave"
+
+	// LogEventChat 玩家聊天
+	LogEventChat LogEventType = "chat"
+
+	// LogEventDeath 玩家死亡
+	LogEventDeath LogEventType = "death"
+
+	// LogEventAdvancement 玩家取得进度、完成挑战或达成目标
+	LogEventAdvancement LogEventType = "advancement"
+
+	// LogEventServerStarted 服务器启动完成
+	LogEventServerStarted LogEventType = "server_started"
+
+	// LogEventServerStopping 服务器正在停止
+	LogEventServerStopping LogEventType = "server_stopping"
+
+	// LogEventLag 服务器卡顿（Can't keep up警告）
+	LogEventLag LogEventType = "lag"
+)
+
+// LogEntry 表示一条结构化的服务器日志
+type LogEntry struct {
+	Time         time.Time `json:"time"`             // 日志时间，优先使用Kubernetes时间戳，否则使用日志中的时间
+	Thread       string    `json:"thread,omitempty"` // 线程名称，如Server thread
+	Level        LogLevel  `json:"level"`            // 日志级别
+	Logger       string    `json:"logger,omitempty"` // 日志记录器名称，如Forge的minecraft/DedicatedServer或Paper插件名
+	Message      string    `json:"message"`          // 日志内容
+	Raw          string    `json:"raw"`              // 原始日志行（不含Kubernetes时间戳）
+	Continuation bool      `json:"continuation"`     // 是否为上一条日志的延续（如异常堆栈），此时Level沿用上一条日志
+	Event        *LogEvent `json:"event,omitempty"`  // 识别出的事件，未识别则为nil
+}
+
+// LogEvent 表示从日志中识别出的事件
+type LogEvent struct {
+	Type        LogEventType  `json:"type"`                   // 事件类型
+	Player      string        `json:"player,omitempty"`       // 相关玩家
+	Message     string        `json:"message,omitempty"`      // 聊天内容或完整的死亡信息
+	Advancement string        `json:"advancement,omitempty"`  // 进度名称
+	StartupTime time.Duration `json:"startup_time,omitempty"` // 服务器启动耗时
+	LagTime     time.Duration `json:"lag_time,omitempty"`     // 卡顿时长
+	LagTicks    int           `json:"lag_ticks,omitempty"`    // 落后的tick数
+}
+
+var (
+	// 原版/Fabric/Forge格式: [12:34:56] [Server thread/INFO]: ...
+	// Forge格式: [28Mar2024 12:34:56.789] [Server thread/INFO] [net.minecraft.server.dedicated.DedicatedServer/]: ...
+	threadLogPattern = regexp.MustCompile(`^\[([^\]]+)\] \[([^\]]+)/([A-Za-z]+)\](?: \[([^\]]*)\])?: ?(.*)$`)
+
+	// Paper/Spigot控制台格式: [12:34:56 INFO]: ... 或 [12:34:56 INFO]: [PluginName] ...
+	levelLogPattern = regexp.MustCompile(`^\[(\d{1,2}:\d{2}:\d{2}(?:\.\d+)?) ([A-Za-z]+)\]:? ?(.*)$`)
+
+	// 插件日志前缀
+	pluginPrefixPattern = regexp.MustCompile(`^\[([A-Za-z0-9_.\-]+)\] (.*)$`)
+
+	// ANSI转义序列
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+	// 事件
+	joinPattern        = regexp.MustCompile(`^(` + playerNamePattern + `) joined the game$`)
+	leavePattern       = regexp.MustCompile(`^(` + playerNamePattern + `) left the game$`)
+	chatPattern        = regexp.MustCompile(`^(?:\[Not Secure\] )?<(` + playerNamePattern + `)> (.*)$`)
+	advancementPattern = regexp.MustCompile(`^(` + playerNamePattern + `) has (?:made the advancement|completed the challenge|reached the goal) \[(.+)\]$`)
+	startedPattern     = regexp.MustCompile(`^Done \(([\d.,]+)s\)! For help, type "help"`)
+	stoppingPattern    = regexp.MustCompile(`^Stopping (?:the )?server$`)
+	lagPattern         = regexp.MustCompile(`^Can't keep up! Is the server overloaded\? Running (\d+)ms or (\d+) ticks behind`)
+	deathPattern       = regexp.MustCompile(`^(` + playerNamePattern + `) (?:` + strings.Join(deathPhrases, "|") + `)\b`)
+)
+
+// playerNamePattern 玩家名称格式，允许Geyser等基岩版玩家的前缀
+const playerNamePattern = `[.*]?[A-Za-z0-9_]{1,16}`
+
+// deathPhrases 原版死亡信息中玩家名称之后的固定短语
+var deathPhrases = []string{
+	`was (?:slain|shot|pummeled|fireballed|killed|blown up|squashed|squished|impaled|skewered|stung|poked|pricked|struck by lightning|burnt to a crisp|frozen to death|obliterated|doomed to fall|knocked into the void|roasted|speared|smashed)`,
+	`drowned`,
+	`died`,
+	`fell (?:from|off|out of|into|while)`,
+	`hit the ground too hard`,
+	`blew up`,
+	`burned to death`,
+	`went up in flames`,
+	`walked into (?:fire|danger|a cactus)`,
+	`tried to swim in lava`,
+	`discovered the floor was lava`,
+	`starved to death`,
+	`suffocated in a wall`,
+	`was squeezed too much`,
+	`withered away`,
+	`froze to death`,
+	`experienced kinetic energy`,
+	`left the confines of this world`,
+	`didn't want to live in the same world as`,
+	`went off with a bang`,
+}
+
+// 日志中的时间格式
+var logTimeLayouts = []string{
+	"15:04:05",
+	"15:04:05.000",
+	"02Jan2006 15:04:05.000",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.000",
+}
+
+// LogParser 将Minecraft服务器输出解析为结构化日志
+// 解析器记录上一条日志的级别，用于没有日志前缀的延续行，因此不能在多个日志流之间共享
+type LogParser struct {
+	// Location 日志中时间的时区，为nil则使用本地时区
+	Location *time.Location
+
+	lastLevel LogLevel
+}
+
+// NewLogParser 创建日志解析器
+func NewLogParser() *LogParser {
+	return &LogParser{}
+}
+
+// Parse 解析一行日志
+// 支持带有Kubernetes时间戳前缀的日志行（见LogOptions.Timestamps），此时使用该时间戳作为日志时间
+func (p *LogParser) Parse(line string) LogEntry {
+	line = strings.TrimRight(line, "\r\n")
+
+	content, k8sTime, hasK8sTime := SplitLogTimestamp(line)
+	content = ansiPattern.ReplaceAllString(content, "")
+
+	entry := LogEntry{Raw: content}
+
+	var timeStr string
+	if m := threadLogPattern.FindStringSubmatch(content); m != nil {
+		timeStr = m[1]
+		entry.Thread = m[2]
+		entry.Level = normalizeLogLevel(m[3])
+		entry.Logger = strings.TrimSuffix(m[4], "/")
+		entry.Message = m[5]
+	} else if m := levelLogPattern.FindStringSubmatch(content); m != nil {
+		timeStr = m[1]
+		entry.Level = normalizeLogLevel(m[2])
+		entry.Message = m[3]
+		if pm := pluginPrefixPattern.FindStringSubmatch(entry.Message); pm != nil {
+			entry.Logger = pm[1]
+			entry.Message = pm[2]
+		}
+	} else {
+		// 没有日志前缀，视为上一条日志的延续
+		entry.Continuation = true
+		entry.Level = p.lastLevel
+		if entry.Level == "" {
+			entry.Level = LogLevelInfo
+		}
+		entry.Message = content
+	}
+
+	if hasK8sTime {
+		entry.Time = k8sTime
+	} else if timeStr != "" {
+		entry.Time = p.parseTime(timeStr)
+	}
+
+	if !entry.Continuation {
+		p.lastLevel = entry.Level
+		// 仅识别服务器自身输出的事件，忽略插件和模组日志
+		if (entry.Level == LogLevelInfo || entry.Level == LogLevelWarn) && isServerLogger(entry.Logger) {
+			entry.Event = ParseLogEvent(entry.Message)
+		}
+	}
+
+	return entry
+}
+
+// parseTime 解析日志中的时间，只有时分秒时使用当天日期补全
+func (p *LogParser) parseTime(value string) time.Time {
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	for _, layout := range logTimeLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		if t.Year() == 0 {
+			now := time.Now().In(loc)
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+			// 跨越午夜时，未来的时间实际属于前一天
+			if t.After(now.Add(time.Minute)) {
+				t = t.AddDate(0, 0, -1)
+			}
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// ParseLogEvent 从日志内容中识别事件，未识别则返回nil
+func ParseLogEvent(message string) *LogEvent {
+	if m := chatPattern.FindStringSubmatch(message); m != nil {
+		return &LogEvent{Type: LogEventChat, Player: m[1], Message: m[2]}
+	}
+	if m := joinPattern.FindStringSubmatch(message); m != nil {
+		return &LogEvent{Type: LogEventPlayerJoin, Player: m[1]}
+	}
+	if m := leavePattern.FindStringSubmatch(message); m != nil {
+		return &LogEvent{Type: LogEventPlayerLeave, Player: m[1]}
+	}
+	if m := advancementPattern.FindStringSubmatch(message); m != nil {
+		return &LogEvent{Type: LogEventAdvancement, Player: m[1], Advancement: m[2]}
+	}
+	if m := startedPattern.FindStringSubmatch(message); m != nil {
+		// 按十进制解析，避免浮点误差（如8.123s被解析为8.122999999s）
+		startupTime, _ := time.ParseDuration(strings.ReplaceAll(m[1], ",", ".") + "s")
+		return &LogEvent{Type: LogEventServerStarted, StartupTime: startupTime}
+	}
+	if stoppingPattern.MatchString(message) {
+		return &LogEvent{Type: LogEventServerStopping}
+	}
+	if m := lagPattern.FindStringSubmatch(message); m != nil {
+		ms, _ := strconv.Atoi(m[1])
+		ticks, _ := strconv.Atoi(m[2])
+		return &LogEvent{Type: LogEventLag, LagTime: time.Duration(ms) * time.Millisecond, LagTicks: ticks}
+	}
+	if m := deathPattern.FindStringSubmatch(message); m != nil {
+		return &LogEvent{Type: LogEventDeath, Player: m[1], Message: message}
+	}
+	return nil
+}
+
+// ParseLogLines 使用新的解析器解析多行日志
+func ParseLogLines(lines []string) []LogEntry {
+	parser := NewLogParser()
+	entries := make([]LogEntry, 0, len(lines))
+	for _, line := range lines {
+		entries = append(entries, parser.Parse(line))
+	}
+	return entries
+}
+
+// FetchLogEntries 获取结构化日志，用法与FetchLogs相同
+// 日志行会带上Kubernetes时间戳以获得准确的日志时间
+func (m *MinecraftController) FetchLogEntries(options LogOptions, callback func([]LogEntry, string)) ([]LogEntry, error) {
+	options.Timestamps = true
+
+	var lineCallback func([]string, string)
+	if callback != nil {
+		parser := NewLogParser()
+		lineCallback = func(lines []string, errMsg string) {
+			var entries []LogEntry
+			if len(lines) > 0 {
+				entries = make([]LogEntry, 0, len(lines))
+				for _, line := range lines {
+					entries = append(entries, parser.Parse(line))
+				}
+			}
+			callback(entries, errMsg)
+		}
+	}
+
+	lines, err := m.FetchLogs(options, lineCallback)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLogLines(lines), nil
+}
+
+// isServerLogger 判断日志记录器是否为Minecraft服务器自身
+func isServerLogger(logger string) bool {
+	return logger == "" || strings.Contains(strings.ToLower(logger), "minecraft")
+}
+
+// normalizeLogLevel 统一不同日志框架的级别名称
+func normalizeLogLevel(level string) LogLevel {
+	switch strings.ToUpper(level) {
+	case "WARN", "WARNING":
+		return LogLevelWarn
+	case "ERROR", "SEVERE":
+		return LogLevelError
+	case "FATAL":
+		return LogLevelFatal
+	case "DEBUG", "FINE":
+		return LogLevelDebug
+	case "TRACE", "FINER", "FINEST":
+		return LogLevelTrace
+	default:
+		return LogLevelInfo
+	}
+}
@@ -0,0 +1,163 @@
+package mccontrol
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLogEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want *LogEvent
+	}{
+		// 原版（Fabric相同）
+		{"原版加入", "[12:34:56] [Server thread/INFO]: Steve joined the game",
+			&LogEvent{Type: LogEventPlayerJoin, Player: "Steve"}},
+		{"原版登录信息", "[12:34:56] [Server thread/INFO]: Steve[/10.0.0.5:53124] logged in with entity id 123 at (0.5, 64.0, 0.5)", nil},
+		{"原版UUID", "[12:34:56] [User Authenticator #1/INFO]: UUID of player Steve is 069a79f4-44e9-4726-a5be-fca90e38aaf5", nil},
+		{"原版聊天", "[12:35:10] [Server thread/INFO]: <Steve> hello world",
+			&LogEvent{Type: LogEventChat, Player: "Steve", Message: "hello world"}},
+		{"原版未签名聊天", "[12:35:10] [Server thread/INFO]: [Not Secure] <Steve> hi",
+			&LogEvent{Type: LogEventChat, Player: "Steve", Message: "hi"}},
+		{"聊天内容像加入", "[12:35:10] [Server thread/INFO]: <Steve> Alex joined the game",
+			&LogEvent{Type: LogEventChat, Player: "Steve", Message: "Alex joined the game"}},
+		{"原版断开连接", "[12:36:00] [Server thread/INFO]: Steve lost connection: Disconnected", nil},
+		{"原版离开", "[12:36:00] [Server thread/INFO]: Steve left the game",
+			&LogEvent{Type: LogEventPlayerLeave, Player: "Steve"}},
+		{"原版被杀死", "[12:37:00] [Server thread/INFO]: Steve was slain by Zombie",
+			&LogEvent{Type: LogEventDeath, Player: "Steve", Message: "Steve was slain by Zombie"}},
+		{"原版摔死", "[12:37:00] [Server thread/INFO]: Steve fell from a high place",
+			&LogEvent{Type: LogEventDeath, Player: "Steve", Message: "Steve fell from a high place"}},
+		{"原版淹死", "[12:37:00] [Server thread/INFO]: Steve drowned",
+			&LogEvent{Type: LogEventDeath, Player: "Steve", Message: "Steve drowned"}},
+		{"原版进度", "[12:38:00] [Server thread/INFO]: Steve has made the advancement [Stone Age]",
+			&LogEvent{Type: LogEventAdvancement, Player: "Steve", Advancement: "Stone Age"}},
+		{"原版挑战", "[12:38:00] [Server thread/INFO]: Steve has completed the challenge [Monsters Hunted]",
+			&LogEvent{Type: LogEventAdvancement, Player: "Steve", Advancement: "Monsters Hunted"}},
+		{"原版目标", "[12:38:00] [Server thread/INFO]: Steve has reached the goal [Sky's the Limit]",
+			&LogEvent{Type: LogEventAdvancement, Player: "Steve", Advancement: "Sky's the Limit"}},
+		{"原版启动完成", `[12:30:00] [Server thread/INFO]: Done (12.345s)! For help, type "help"`,
+			&LogEvent{Type: LogEventServerStarted, StartupTime: 12345 * time.Millisecond}},
+		{"原版停止", "[12:40:00] [Server thread/INFO]: Stopping the server",
+			&LogEvent{Type: LogEventServerStopping}},
+		{"原版卡顿", "[12:39:00] [Server thread/WARN]: Can't keep up! Is the server overloaded? Running 2500ms or 50 ticks behind",
+			&LogEvent{Type: LogEventLag, LagTime: 2500 * time.Millisecond, LagTicks: 50}},
+
+		// Forge
+		{"Forge加入", "[28Mar2024 12:34:56.789] [Server thread/INFO] [net.minecraft.server.MinecraftServer/]: Steve joined the game",
+			&LogEvent{Type: LogEventPlayerJoin, Player: "Steve"}},
+		{"Forge模组日志", "[28Mar2024 12:34:56.789] [Server thread/INFO] [journeymap/]: Steve joined the game", nil},
+
+		// Paper/Spigot
+		{"Paper加入", "[12:34:56 INFO]: Steve joined the game",
+			&LogEvent{Type: LogEventPlayerJoin, Player: "Steve"}},
+		{"Paper登录信息", "[12:34:56 INFO]: Steve[/10.0.0.5:53124] logged in with entity id 123 at ([world]0.5, 64.0, 0.5)", nil},
+		{"Paper聊天", "[12:35:10 INFO]: <Steve> hello",
+			&LogEvent{Type: LogEventChat, Player: "Steve", Message: "hello"}},
+		{"Paper离开", "[12:36:00 INFO]: Steve left the game",
+			&LogEvent{Type: LogEventPlayerLeave, Player: "Steve"}},
+		{"Paper被射杀", "[12:37:00 INFO]: Steve was shot by Skeleton",
+			&LogEvent{Type: LogEventDeath, Player: "Steve", Message: "Steve was shot by Skeleton"}},
+		{"Paper进度", "[12:38:00 INFO]: Steve has made the advancement [Diamonds!]",
+			&LogEvent{Type: LogEventAdvancement, Player: "Steve", Advancement: "Diamonds!"}},
+		{"Paper启动完成", `[12:30:00 INFO]: Done (8.123s)! For help, type "help"`,
+			&LogEvent{Type: LogEventServerStarted, StartupTime: 8123 * time.Millisecond}},
+		{"Paper停止", "[12:40:00 INFO]: Stopping server",
+			&LogEvent{Type: LogEventServerStopping}},
+		{"Paper卡顿", "[12:39:00 WARN]: Can't keep up! Is the server overloaded? Running 5000ms or 100 ticks behind",
+			&LogEvent{Type: LogEventLag, LagTime: 5 * time.Second, LagTicks: 100}},
+		{"Paper彩色输出", "\x1b[0;33m[12:34:56 INFO]: Steve joined the game\x1b[m",
+			&LogEvent{Type: LogEventPlayerJoin, Player: "Steve"}},
+		{"Geyser基岩版玩家", "[12:34:56 INFO]: .BedrockSteve joined the game",
+			&LogEvent{Type: LogEventPlayerJoin, Player: ".BedrockSteve"}},
+		{"插件日志", "[12:34:56 INFO]: [Essentials] Steve joined the game", nil},
+		{"插件伪造死亡", "[12:37:00 INFO]: [DeathMessages] Steve was slain by Zombie", nil},
+		{"错误级别", "[12:37:00 ERROR]: Steve was slain by Zombie", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := NewLogParser().Parse(tt.line)
+			if !reflect.DeepEqual(entry.Event, tt.want) {
+				t.Errorf("Parse(%q).Event = %+v, 期望 %+v", tt.line, entry.Event, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		thread  string
+		level   LogLevel
+		logger  string
+		message string
+	}{
+		{"原版", "[12:34:56] [Server thread/INFO]: Starting minecraft server version 1.20.4",
+			"Server thread", LogLevelInfo, "", "Starting minecraft server version 1.20.4"},
+		{"Forge", "[28Mar2024 12:34:56.789] [Server thread/WARN] [net.minecraft.server.dedicated.DedicatedServer/]: **** SERVER IS RUNNING IN OFFLINE/INSECURE MODE!",
+			"Server thread", LogLevelWarn, "net.minecraft.server.dedicated.DedicatedServer", "**** SERVER IS RUNNING IN OFFLINE/INSECURE MODE!"},
+		{"Paper", "[12:34:56 INFO]: Preparing level \"world\"",
+			"", LogLevelInfo, "", "Preparing level \"world\""},
+		{"Paper插件", "[12:34:56 INFO]: [LuckPerms] Loading configuration...",
+			"", LogLevelInfo, "LuckPerms", "Loading configuration..."},
+		{"Bukkit级别", "[12:34:56 SEVERE]: Could not load 'plugins/broken.jar'",
+			"", LogLevelError, "", "Could not load 'plugins/broken.jar'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := NewLogParser().Parse(tt.line)
+			if entry.Thread != tt.thread || entry.Level != tt.level || entry.Logger != tt.logger || entry.Message != tt.message || entry.Continuation {
+				t.Errorf("Parse(%q) = thread=%q level=%q logger=%q message=%q continuation=%v",
+					tt.line, entry.Thread, entry.Level, entry.Logger, entry.Message, entry.Continuation)
+			}
+		})
+	}
+}
+
+func TestParseLogContinuation(t *testing.T) {
+	entries := ParseLogLines([]string{
+		"[12:34:56 ERROR]: Error occurred while enabling BrokenPlugin v1.0",
+		"java.lang.NullPointerException: null",
+		"\tat com.example.BrokenPlugin.onEnable(BrokenPlugin.java:42) ~[?:?]",
+		"[12:34:57 INFO]: Steve joined the game",
+	})
+
+	for _, entry := range entries[1:3] {
+		if !entry.Continuation || entry.Level != LogLevelError || entry.Event != nil {
+			t.Errorf("异常堆栈应为上一条日志的延续并沿用其级别: %+v", entry)
+		}
+	}
+	if entries[3].Continuation || entries[3].Event == nil || entries[3].Event.Type != LogEventPlayerJoin {
+		t.Errorf("延续行之后的日志应正常解析: %+v", entries[3])
+	}
+}
+
+func TestParseLogTimestamp(t *testing.T) {
+	// 带Kubernetes时间戳时使用该时间戳
+	entry := NewLogParser().Parse("2024-03-28T12:34:56.789012345Z [12:34:56] [Server thread/INFO]: Steve joined the game")
+	want := time.Date(2024, 3, 28, 12, 34, 56, 789012345, time.UTC)
+	if !entry.Time.Equal(want) || entry.Raw != "[12:34:56] [Server thread/INFO]: Steve joined the game" {
+		t.Fatalf("应使用Kubernetes时间戳: time=%v raw=%q", entry.Time, entry.Raw)
+	}
+	if entry.Event == nil || entry.Event.Type != LogEventPlayerJoin {
+		t.Fatalf("带时间戳的日志应识别事件: %+v", entry.Event)
+	}
+
+	// 没有时间戳时使用日志中的时间，只有时分秒时补全为当天（跨越午夜时为前一天）
+	parser := &LogParser{Location: time.UTC}
+	now := time.Now().UTC()
+	entry = parser.Parse("[" + now.Add(-time.Minute).Format("15:04:05") + " INFO]: Steve joined the game")
+	if diff := now.Sub(entry.Time); diff < 0 || diff > 2*time.Minute {
+		t.Fatalf("日志时间应补全为最近的日期: %v (当前 %v)", entry.Time, now)
+	}
+
+	entry = parser.Parse("[28Mar2024 12:34:56.789] [Server thread/INFO] [net.minecraft.server.MinecraftServer/]: Steve joined the game")
+	if want := time.Date(2024, 3, 28, 12, 34, 56, 789000000, time.UTC); !entry.Time.Equal(want) {
+		t.Fatalf("Forge日志时间错误: %v", entry.Time)
+	}
+}
@@ -0,0 +1,67 @@
+package mccontrol
+
+import (
+	"time"
+)
+
+// MetricsObserver 接收控制器运行过程中产生的指标
+// 实现需要是并发安全的，且不应阻塞调用方
+type MetricsObserver interface {
+	// ObserveCommand 记录一次命令执行，executorType为实际使用的执行器类型
+	ObserveCommand(executorType ExecutorType, duration time.Duration, err error)
+
+	// ObserveLogReconnect 记录一次流式日志重连
+	ObserveLogReconnect()
+}
+
+// SetMetricsObserver 设置指标观察者，为nil则不记录指标
+func (m *MinecraftController) SetMetricsObserver(observer MetricsObserver) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	m.metricsObserver = observer
+}
+
+// LastStatus 返回最近一次状态检测结果的副本，不会触发新的检测
+func (m *MinecraftController) LastStatus() ServerStatus {
+	return m.statusSnapshot()
+}
+
+// observer 返回当前的指标观察者
+func (m *MinecraftController) observer() MetricsObserver {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.metricsObserver
+}
+
+// observeCommand 记录命令执行指标
+func (m *MinecraftController) observeCommand(executorType ExecutorType, start time.Time, err error) {
+	if observer := m.observer(); observer != nil {
+		observer.ObserveCommand(executorType, time.Since(start), err)
+	}
+}
+
+// observeLogReconnect 记录日志重连指标
+func (m *MinecraftController) observeLogReconnect() {
+	if observer := m.observer(); observer != nil {
+		observer.ObserveLogReconnect()
+	}
+}
+
+// executorTypeOf 返回执行器的实际类型
+// 其他执行器实现（如测试中的假执行器）可以通过executorType方法报告类型
+func executorTypeOf(executor CommandExecutor) ExecutorType {
+	switch e := executor.(type) {
+	case *rconExecutor:
+		return ExecutorRcon
+	case *attachExecutor:
+		return ExecutorAttach
+	case *execExecutor:
+		return ExecutorExec
+	case interface{ executorType() ExecutorType }:
+		return e.executorType()
+	default:
+		return ExecutorAuto
+	}
+}
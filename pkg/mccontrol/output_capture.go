@@ -0,0 +1,173 @@
+package mccontrol
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// 命令输出收集的默认参数
+const (
+	defaultOutputCaptureWindow = 2 * time.Second        // 命令写入后最多等待输出的时间
+	defaultOutputCaptureQuiet  = 300 * time.Millisecond // 收到输出后，超过该时间没有新输出即认为输出结束
+
+	outputBufferLines = 1024 // 单条命令最多收集的输出行数
+)
+
+// outputCapture 收集attach和exec执行器写入命令后服务器的输出
+// 一次性的attach和exec执行器只能向服务器控制台写入命令，服务器的响应只会出现在日志中，
+// 因此在写入命令前开始跟踪日志，并将写入后一段时间内产生的日志作为命令输出；
+// 持久attach数据流可以直接读取标准输出，只使用其中的等待和格式化逻辑。
+//
+// 服务器输出中没有可以与命令对应的标记，收集到的输出只是尽力而为的结果：
+// 同一Pod上的收集依次进行，不会把一条命令的输出算到另一条命令上，但时间窗口内服务器
+// 自己产生的日志（如玩家加入、插件消息）仍会混入，响应较慢的命令也可能缺少部分输出
+type outputCapture struct {
+	controller *MinecraftController
+	window     time.Duration
+	quiet      time.Duration
+	parser     *LogParser
+}
+
+// SetOutputCapture 设置attach和exec执行器收集命令输出的时间窗口
+// window为命令写入后最多等待输出的时间，为0则不收集输出；
+// quiet为收到输出后的静默时间，超过该时间没有新日志即提前结束收集
+func (m *MinecraftController) SetOutputCapture(window, quiet time.Duration) {
+	m.outputCaptureMutex.Lock()
+	defer m.outputCaptureMutex.Unlock()
+
+	m.outputCaptureWindow = window
+	m.outputCaptureQuiet = quiet
+}
+
+// newOutputCapture 按控制器的配置创建输出收集器，未启用时返回nil
+func (m *MinecraftController) newOutputCapture() *outputCapture {
+	m.outputCaptureMutex.Lock()
+	window, quiet := m.outputCaptureWindow, m.outputCaptureQuiet
+	m.outputCaptureMutex.Unlock()
+
+	if window <= 0 {
+		return nil
+	}
+	if quiet <= 0 || quiet > window {
+		quiet = window
+	}
+	return &outputCapture{
+		controller: m,
+		window:     window,
+		quiet:      quiet,
+		parser:     NewLogParser(),
+	}
+}
+
+// capture 开始跟踪Pod的日志后调用write写入命令，返回写入后服务器输出的日志内容
+// 同一Pod上的收集依次进行，等待期间上下文取消时不写入命令；
+// 无法跟踪日志时仍会写入命令，只是返回空输出；上下文取消时立即结束收集
+func (c *outputCapture) capture(ctx context.Context, podName string, write func(context.Context) error) (string, error) {
+	release, err := c.controller.lockOutputCapture(ctx, podName)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan string, outputBufferLines)
+
+	// TailLines为0时只跟踪新产生的日志，不依赖集群节点与本机的时钟一致
+	tailLines := int64(0)
+	_, err = c.controller.FetchLogs(LogOptions{
+		PodName:   podName,
+		TailLines: &tailLines,
+		BatchSize: 1,
+		Context:   ctx,
+	}, func(batch []string, errMsg string) {
+		for _, line := range batch {
+			offerLine(lines, line)
+		}
+	})
+	tracking := err == nil
+
+	if err := write(ctx); err != nil {
+		return "", err
+	}
+	if !tracking {
+		return "", nil
+	}
+
+	return c.format(c.collect(ctx, lines, nil)), nil
+}
+
+// lockOutputCapture 等待并占用Pod的输出收集，返回释放函数
+// 同一Pod上同时只有一条命令收集输出，上下文取消时放弃等待
+func (m *MinecraftController) lockOutputCapture(ctx context.Context, podName string) (func(), error) {
+	m.outputCaptureMutex.Lock()
+	if m.outputCaptureLocks == nil {
+		m.outputCaptureLocks = make(map[string]chan struct{})
+	}
+	lock, ok := m.outputCaptureLocks[podName]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		m.outputCaptureLocks[podName] = lock
+	}
+	m.outputCaptureMutex.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// collect 收集命令写入后的输出行
+// 收到第一行输出前最多等待window，之后每次收到输出都重新计算静默时间，
+// 上下文取消或stop关闭时立即结束
+func (c *outputCapture) collect(ctx context.Context, lines <-chan string, stop <-chan struct{}) []string {
+	deadline := time.NewTimer(c.window)
+	defer deadline.Stop()
+
+	var output []string
+	var quiet <-chan time.Time
+	for {
+		select {
+		case line := <-lines:
+			output = append(output, line)
+			quiet = time.After(c.quiet)
+		case <-quiet:
+			return output
+		case <-deadline.C:
+			return output
+		case <-stop:
+			return output
+		case <-ctx.Done():
+			return output
+		}
+	}
+}
+
+// offerLine 非阻塞地投递一行输出，缓冲区已满时丢弃
+func offerLine(lines chan<- string, line string) {
+	select {
+	case lines <- line:
+	default:
+	}
+}
+
+// format 将日志行转换为命令输出：去掉日志前缀，只保留消息内容，并过滤掉玩家聊天
+func (c *outputCapture) format(lines []string) string {
+	output := make([]string, 0, len(lines))
+	for _, line := range lines {
+		entry := c.parser.Parse(line)
+		if entry.Event != nil && entry.Event.Type == LogEventChat {
+			continue
+		}
+		if entry.Message != "" {
+			output = append(output, entry.Message)
+		} else if entry.Raw != "" {
+			output = append(output, entry.Raw)
+		}
+	}
+	return strings.Join(output, "\n")
+}
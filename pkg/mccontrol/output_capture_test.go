@@ -0,0 +1,70 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOutputCaptureSerializedPerPod(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+
+	release, err := controller.lockOutputCapture(context.Background(), "mc-0")
+	if err != nil {
+		t.Fatalf("占用输出收集失败: %v", err)
+	}
+
+	// 其他Pod的收集不受影响
+	other, err := controller.lockOutputCapture(context.Background(), "mc-1")
+	if err != nil {
+		t.Fatalf("其他Pod的输出收集不应等待: %v", err)
+	}
+	other()
+
+	// 同一Pod的收集等待前一条命令结束，等待期间上下文取消时放弃
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := controller.lockOutputCapture(ctx, "mc-0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("同一Pod的输出收集应等待: %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		next, err := controller.lockOutputCapture(context.Background(), "mc-0")
+		if err != nil {
+			t.Errorf("占用输出收集失败: %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("前一条命令结束前不应开始收集")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case next := <-acquired:
+		if next != nil {
+			next()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("前一条命令结束后应开始收集")
+	}
+}
+
+func TestBestEffortResponse(t *testing.T) {
+	for executorType, want := range map[ExecutorType]bool{
+		ExecutorRcon:   false,
+		ExecutorAttach: true,
+		ExecutorExec:   true,
+		"":             false,
+	} {
+		if got := executorType.BestEffortResponse(); got != want {
+			t.Errorf("%q.BestEffortResponse() = %v, 期望 %v", executorType, got, want)
+		}
+	}
+}
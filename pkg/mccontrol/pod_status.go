@@ -0,0 +1,317 @@
+package mccontrol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bytedance/sonic"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	podStatusTimeout     = 5 * time.Second  // 获取Pod事件和资源用量的超时时间
+	maxPodEvents         = 10               // 状态中保留的最近事件数量
+	podExtrasTTL         = 30 * time.Second // Pod事件和资源用量的缓存时间
+	metricsRetryInterval = 10 * time.Minute // 集群没有metrics.k8s.io API时重新检查的间隔
+
+	// metricsGroupVersion 提供Pod资源用量的API，由metrics-server注册
+	metricsGroupVersion = "metrics.k8s.io/v1beta1"
+)
+
+// ContainerTermination 容器上一次终止的信息
+type ContainerTermination struct {
+	Container  string    `json:"container"`   // 容器名称
+	Reason     string    `json:"reason"`      // 终止原因，如OOMKilled、Error、Completed
+	ExitCode   int32     `json:"exit_code"`   // 退出码
+	Message    string    `json:"message"`     // 终止信息
+	FinishedAt time.Time `json:"finished_at"` // 终止时间
+}
+
+// PodCondition Pod的一个状态条件
+type PodCondition struct {
+	Type               string    `json:"type"`                 // 条件类型，如Ready、PodScheduled
+	Status             string    `json:"status"`               // True、False或Unknown
+	Reason             string    `json:"reason"`               // 原因
+	Message            string    `json:"message"`              // 详细信息
+	LastTransitionTime time.Time `json:"last_transition_time"` // 最后变化时间
+}
+
+// PodEvent 与Pod相关的Kubernetes事件
+type PodEvent struct {
+	Type     string    `json:"type"`      // 事件类型，Normal或Warning
+	Reason   string    `json:"reason"`    // 事件原因，如Pulled、BackOff、Unhealthy
+	Message  string    `json:"message"`   // 事件信息
+	Count    int32     `json:"count"`     // 事件发生次数
+	LastSeen time.Time `json:"last_seen"` // 最后发生时间
+}
+
+// ResourceUsage Pod的资源用量，来自metrics.k8s.io API
+type ResourceUsage struct {
+	CPUMillicores      int64     `json:"cpu_millicores"`       // CPU用量，单位：毫核
+	MemoryBytes        int64     `json:"memory_bytes"`         // 内存用量，单位：字节
+	CPULimitMillicores int64     `json:"cpu_limit_millicores"` // CPU限制，为0表示不限制
+	MemoryLimitBytes   int64     `json:"memory_limit_bytes"`   // 内存限制，为0表示不限制
+	Timestamp          time.Time `json:"timestamp"`            // 采样时间
+}
+
+// podDetails 状态检测时获取的当前Pod的Kubernetes状态
+type podDetails struct {
+	nodeName        string
+	ready           bool
+	restarts        int32
+	lastTermination *ContainerTermination
+	conditions      []PodCondition
+	events          []PodEvent
+	usage           *ResourceUsage
+}
+
+// apply 将Pod状态写入服务器状态，details为nil时清除
+func (d *podDetails) apply(status *ServerStatus) {
+	if d == nil {
+		d = &podDetails{}
+	}
+	status.NodeName = d.nodeName
+	status.Ready = d.ready
+	status.Restarts = d.restarts
+	status.LastTermination = d.lastTermination
+	status.Conditions = d.conditions
+	status.Events = d.events
+	status.Usage = d.usage
+}
+
+// podExtras 缓存的Pod事件和资源用量
+// 状态检测可能由定期监控、接口请求等频繁触发，事件和资源用量在缓存时间内复用，不重复请求API服务器
+type podExtras struct {
+	uid       types.UID // 缓存对应的Pod，Pod重建后缓存失效
+	events    []PodEvent
+	usage     *ResourceUsage
+	fetchedAt time.Time
+
+	metricsAvailable bool      // 集群是否提供metrics.k8s.io API
+	metricsCheckedAt time.Time // 上次检查metrics.k8s.io API的时间，为零值表示尚未检查
+}
+
+// collectPodDetails 获取当前Pod的容器状态、条件、最近事件和资源用量
+// 当前没有Pod时返回nil；事件和资源用量获取失败时对应字段为空，不影响状态检测
+func (m *MinecraftController) collectPodDetails() *podDetails {
+	podName := m.podName()
+	if podName == "" {
+		return nil
+	}
+	pod, err := m.findPod(podName)
+	if err != nil {
+		return nil
+	}
+
+	details := &podDetails{
+		nodeName:        pod.Spec.NodeName,
+		ready:           podReady(pod),
+		restarts:        podRestarts(pod),
+		lastTermination: lastTermination(pod),
+	}
+	for _, condition := range pod.Status.Conditions {
+		details.conditions = append(details.conditions, PodCondition{
+			Type:               string(condition.Type),
+			Status:             string(condition.Status),
+			Reason:             condition.Reason,
+			Message:            condition.Message,
+			LastTransitionTime: condition.LastTransitionTime.Time,
+		})
+	}
+
+	details.events, details.usage = m.cachedPodExtras(pod)
+	return details
+}
+
+// cachedPodExtras 返回Pod的最近事件和资源用量，缓存过期或Pod重建后重新获取
+func (m *MinecraftController) cachedPodExtras(pod *corev1.Pod) ([]PodEvent, *ResourceUsage) {
+	m.podExtrasMutex.Lock()
+	defer m.podExtrasMutex.Unlock()
+
+	cache := &m.podExtras
+	now := time.Now()
+	if cache.uid == pod.UID && !cache.fetchedAt.IsZero() && now.Sub(cache.fetchedAt) < podExtrasTTL {
+		return cache.events, cache.usage
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, podStatusTimeout)
+	defer cancel()
+
+	cache.uid = pod.UID
+	cache.fetchedAt = now
+	cache.events, cache.usage = nil, nil
+	if events, err := m.podEvents(ctx, pod); err == nil {
+		cache.events = events
+	}
+	if m.metricsAPIAvailable(now) {
+		if usage, err := m.podUsage(ctx, pod); err == nil {
+			cache.usage = usage
+		}
+	}
+	return cache.events, cache.usage
+}
+
+// metricsAPIAvailable 检查集群是否提供metrics.k8s.io API，调用方需持有podExtrasMutex
+// API存在时不再检查；API不存在（未安装metrics-server）时间隔metricsRetryInterval重新检查，期间不请求资源用量
+func (m *MinecraftController) metricsAPIAvailable(now time.Time) bool {
+	cache := &m.podExtras
+	if !cache.metricsCheckedAt.IsZero() && (cache.metricsAvailable || now.Sub(cache.metricsCheckedAt) < metricsRetryInterval) {
+		return cache.metricsAvailable
+	}
+
+	_, err := m.clientset.Discovery().ServerResourcesForGroupVersion(metricsGroupVersion)
+	switch {
+	case err == nil:
+		cache.metricsAvailable = true
+		cache.metricsCheckedAt = now
+	case apierrors.IsNotFound(err):
+		cache.metricsAvailable = false
+		cache.metricsCheckedAt = now
+	default:
+		// 其他错误可能是暂时的，下次状态检测时重新检查
+		return false
+	}
+	return cache.metricsAvailable
+}
+
+// podEvents 获取与Pod相关的最近事件，按时间倒序
+func (m *MinecraftController) podEvents(ctx context.Context, pod *corev1.Pod) ([]PodEvent, error) {
+	list, err := m.clientset.CoreV1().Events(m.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.name", pod.Name).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取Pod事件失败: %v", err)
+	}
+
+	var events []PodEvent
+	for _, event := range list.Items {
+		// 同名Pod重建后旧Pod的事件仍会保留一段时间，按UID过滤
+		if event.InvolvedObject.Kind != "Pod" || (event.InvolvedObject.UID != "" && event.InvolvedObject.UID != pod.UID) {
+			continue
+		}
+		events = append(events, PodEvent{
+			Type:     event.Type,
+			Reason:   event.Reason,
+			Message:  event.Message,
+			Count:    event.Count,
+			LastSeen: eventTime(event),
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.After(events[j].LastSeen) })
+	if len(events) > maxPodEvents {
+		events = events[:maxPodEvents]
+	}
+	return events, nil
+}
+
+// podMetrics metrics.k8s.io API返回的Pod资源用量
+type podMetrics struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Containers []struct {
+		Name  string            `json:"name"`
+		Usage map[string]string `json:"usage"`
+	} `json:"containers"`
+}
+
+// podUsage 从metrics.k8s.io API获取Pod的资源用量，集群未安装metrics-server时返回错误
+func (m *MinecraftController) podUsage(ctx context.Context, pod *corev1.Pod) (*ResourceUsage, error) {
+	client := coreRESTClient(m.clientset)
+	if client == nil {
+		return nil, fmt.Errorf("不支持获取资源用量")
+	}
+
+	data, err := client.Get().
+		AbsPath("/apis/"+metricsGroupVersion, "namespaces", m.namespace, "pods", pod.Name).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取Pod资源用量失败: %v", err)
+	}
+
+	var metrics podMetrics
+	if err := sonic.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("解析Pod资源用量失败: %v", err)
+	}
+
+	usage := &ResourceUsage{Timestamp: metrics.Timestamp}
+	for _, container := range metrics.Containers {
+		if cpu, err := resource.ParseQuantity(container.Usage["cpu"]); err == nil {
+			usage.CPUMillicores += cpu.MilliValue()
+		}
+		if memory, err := resource.ParseQuantity(container.Usage["memory"]); err == nil {
+			usage.MemoryBytes += memory.Value()
+		}
+	}
+	for _, container := range pod.Spec.Containers {
+		if cpu, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
+			usage.CPULimitMillicores += cpu.MilliValue()
+		}
+		if memory, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+			usage.MemoryLimitBytes += memory.Value()
+		}
+	}
+	return usage, nil
+}
+
+// coreRESTClient 返回用于attach、exec和访问metrics.k8s.io API的REST客户端，
+// 客户端不支持REST请求（如测试使用的fake客户端）时返回nil
+func coreRESTClient(clientset kubernetes.Interface) rest.Interface {
+	client, ok := clientset.CoreV1().RESTClient().(*rest.RESTClient)
+	if !ok || client == nil {
+		return nil
+	}
+	return client
+}
+
+// podRestarts 返回Pod中所有容器的重启次数之和
+func podRestarts(pod *corev1.Pod) int32 {
+	var restarts int32
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		restarts += containerStatus.RestartCount
+	}
+	return restarts
+}
+
+// lastTermination 返回Pod中最近一次终止的容器信息，容器没有终止过时返回nil
+func lastTermination(pod *corev1.Pod) *ContainerTermination {
+	var latest *ContainerTermination
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		terminated := containerStatus.LastTerminationState.Terminated
+		if terminated == nil {
+			continue
+		}
+		if latest != nil && !terminated.FinishedAt.Time.After(latest.FinishedAt) {
+			continue
+		}
+		latest = &ContainerTermination{
+			Container:  containerStatus.Name,
+			Reason:     terminated.Reason,
+			ExitCode:   terminated.ExitCode,
+			Message:    terminated.Message,
+			FinishedAt: terminated.FinishedAt.Time,
+		}
+	}
+	return latest
+}
+
+// eventTime 返回事件最后发生的时间，依次使用LastTimestamp、EventTime、FirstTimestamp和创建时间
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
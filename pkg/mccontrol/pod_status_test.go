@@ -0,0 +1,197 @@
+package mccontrol
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLastTermination(t *testing.T) {
+	now := time.Now()
+	pod := testServerPod("mc-0", "127.0.0.1")
+	if termination := lastTermination(pod); termination != nil {
+		t.Fatalf("没有终止过的容器应返回nil: %+v", termination)
+	}
+
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name:         "sidecar",
+			RestartCount: 1,
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason:     "Error",
+				ExitCode:   1,
+				FinishedAt: metav1.NewTime(now.Add(-time.Hour)),
+			}},
+		},
+		{
+			Name:         "minecraft",
+			RestartCount: 2,
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason:     "OOMKilled",
+				ExitCode:   137,
+				FinishedAt: metav1.NewTime(now),
+			}},
+		},
+	}
+
+	termination := lastTermination(pod)
+	if termination == nil || termination.Container != "minecraft" || termination.Reason != "OOMKilled" || termination.ExitCode != 137 {
+		t.Fatalf("应返回最近终止的容器: %+v", termination)
+	}
+	if restarts := podRestarts(pod); restarts != 3 {
+		t.Fatalf("重启次数应为所有容器之和: %d", restarts)
+	}
+}
+
+func TestCheckServerStatusPodDetails(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	now := time.Now()
+
+	pod := testServerPod("mc-0", "127.0.0.1")
+	pod.UID = "uid-new"
+	pod.Spec.NodeName = "node-1"
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodScheduled, Status: corev1.ConditionTrue},
+		{Type: corev1.PodReady, Status: corev1.ConditionFalse, Reason: "ContainersNotReady"},
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:         "minecraft",
+		RestartCount: 4,
+		LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			Reason:     "OOMKilled",
+			ExitCode:   137,
+			FinishedAt: metav1.NewTime(now),
+		}},
+	}}
+	controller, clientset := newTestController(t, rcon, pod)
+
+	events := []corev1.Event{
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pulled", Namespace: testNamespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "mc-0", UID: "uid-new"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         "Pulled",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+		{
+			ObjectMeta:     metav1.ObjectMeta{Name: "backoff", Namespace: testNamespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "mc-0", UID: "uid-new"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Count:          3,
+			LastTimestamp:  metav1.NewTime(now),
+		},
+		{
+			// 同名旧Pod的事件
+			ObjectMeta:     metav1.ObjectMeta{Name: "old", Namespace: testNamespace},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "mc-0", UID: "uid-old"},
+			Type:           corev1.EventTypeNormal,
+			Reason:         "Killing",
+			LastTimestamp:  metav1.NewTime(now),
+		},
+	}
+	for i := range events {
+		if _, err := clientset.CoreV1().Events(testNamespace).Create(context.Background(), &events[i], metav1.CreateOptions{}); err != nil {
+			t.Fatalf("创建事件失败: %v", err)
+		}
+	}
+
+	status, err := controller.CheckServerStatus()
+	if err != nil {
+		t.Fatalf("状态检测失败: %v", err)
+	}
+	if status.NodeName != "node-1" || status.Ready || status.Restarts != 4 {
+		t.Fatalf("Pod状态错误: node=%q ready=%v restarts=%d", status.NodeName, status.Ready, status.Restarts)
+	}
+	if status.LastTermination == nil || status.LastTermination.Reason != "OOMKilled" {
+		t.Fatalf("上一次终止信息错误: %+v", status.LastTermination)
+	}
+	if len(status.Conditions) != 2 || status.Conditions[1].Reason != "ContainersNotReady" {
+		t.Fatalf("状态条件错误: %+v", status.Conditions)
+	}
+	if len(status.Events) != 2 || status.Events[0].Reason != "BackOff" || status.Events[0].Count != 3 || status.Events[1].Reason != "Pulled" {
+		t.Fatalf("事件应只包含当前Pod的事件并按时间倒序: %+v", status.Events)
+	}
+	if status.Usage != nil {
+		t.Fatalf("fake客户端不支持获取资源用量: %+v", status.Usage)
+	}
+
+	// 返回的状态与控制器不共享Pod状态
+	status.Events[0].Reason = "changed"
+	status.LastTermination.Reason = "changed"
+	snapshot := controller.LastStatus()
+	if snapshot.Events[0].Reason != "BackOff" || snapshot.LastTermination.Reason != "OOMKilled" {
+		t.Fatalf("修改返回的状态影响了控制器: %+v", snapshot)
+	}
+}
+
+// countActions 返回fake客户端收到的指定动词和资源的请求数量
+func countActions(clientset *fake.Clientset, verb, resource string) int {
+	count := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == verb && action.GetResource().Resource == resource {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPodExtrasCached(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	pod := testServerPod("mc-0", "127.0.0.1")
+	pod.UID = "uid-0"
+	controller, clientset := newTestController(t, rcon, pod)
+
+	// 缓存时间内的状态检测不重复获取事件，fake客户端没有metrics.k8s.io API，只检查一次
+	for i := 0; i < 3; i++ {
+		if _, err := controller.CheckServerStatus(); err != nil {
+			t.Fatalf("状态检测失败: %v", err)
+		}
+	}
+	if n := countActions(clientset, "list", "events"); n != 1 {
+		t.Fatalf("缓存时间内应只获取一次事件, 实际%d次", n)
+	}
+	if n := countActions(clientset, "get", "resource"); n != 1 {
+		t.Fatalf("应只检查一次metrics.k8s.io API, 实际%d次", n)
+	}
+
+	// 缓存过期后重新获取事件，metrics.k8s.io API在重新检查间隔内不再检查
+	controller.podExtrasMutex.Lock()
+	controller.podExtras.fetchedAt = time.Now().Add(-podExtrasTTL)
+	controller.podExtrasMutex.Unlock()
+	if _, err := controller.CheckServerStatus(); err != nil {
+		t.Fatalf("状态检测失败: %v", err)
+	}
+	if n := countActions(clientset, "list", "events"); n != 2 {
+		t.Fatalf("缓存过期后应重新获取事件, 实际获取了%d次", n)
+	}
+	if n := countActions(clientset, "get", "resource"); n != 1 {
+		t.Fatalf("重新检查间隔内不应再检查metrics.k8s.io API, 实际%d次", n)
+	}
+
+	// Pod重建后缓存失效
+	pods := clientset.CoreV1().Pods(testNamespace)
+	if err := pods.Delete(context.Background(), "mc-0", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("删除Pod失败: %v", err)
+	}
+	pod = testServerPod("mc-0", "127.0.0.1")
+	pod.UID = "uid-1"
+	if _, err := pods.Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("创建Pod失败: %v", err)
+	}
+	controller.ForceUpdatePodInfo()
+	deadline := time.Now().Add(5 * time.Second)
+	for countActions(clientset, "list", "events") < 3 && time.Now().Before(deadline) {
+		if _, err := controller.CheckServerStatus(); err != nil {
+			t.Fatalf("状态检测失败: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := countActions(clientset, "list", "events"); n != 3 {
+		t.Fatalf("Pod重建后应重新获取事件, 实际获取了%d次", n)
+	}
+}
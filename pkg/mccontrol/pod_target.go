@@ -0,0 +1,270 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrPodNotFound 指定的Pod不存在或不匹配控制器的标签选择器
+var ErrPodNotFound = errors.New("Pod不存在")
+
+// PodInfo 匹配标签选择器的一个Pod
+type PodInfo struct {
+	Name      string     `json:"name"`                 // Pod名称
+	Phase     string     `json:"phase"`                // Pod状态
+	Ready     bool       `json:"ready"`                // Pod是否就绪
+	Deleting  bool       `json:"deleting"`             // 是否正在删除
+	IP        string     `json:"ip"`                   // Pod IP
+	NodeName  string     `json:"node_name"`            // 所在节点
+	Restarts  int32      `json:"restarts"`             // 容器重启次数
+	StartTime *time.Time `json:"start_time,omitempty"` // Pod启动时间
+	Current   bool       `json:"current"`              // 是否为控制器当前使用的Pod
+	Pinned    bool       `json:"pinned"`               // 是否为控制器固定使用的Pod
+}
+
+// PodCommandResult 广播命令时在一个Pod上的执行结果
+type PodCommandResult struct {
+	Pod          string        // Pod名称
+	Response     string        // 命令响应
+	ExecutorType ExecutorType  // 实际使用的执行器类型，执行器创建失败时为空
+	Duration     time.Duration // 执行耗时
+	Err          error         // 执行失败的原因
+}
+
+// podTarget 命令执行器固定使用的Pod
+type podTarget struct {
+	name string // Pod名称
+	ip   string // Pod IP，RCON执行器连接该地址
+}
+
+// ListPods 列出所有匹配标签选择器的Pod及其状态，按名称排序
+func (m *MinecraftController) ListPods() ([]PodInfo, error) {
+	pods, err := m.listPods()
+	if err != nil {
+		return nil, fmt.Errorf("获取Pod列表失败: %v", err)
+	}
+
+	m.stateMutex.RLock()
+	current, pinned := m.currentPodName, m.pinnedPod
+	m.stateMutex.RUnlock()
+
+	result := make([]PodInfo, 0, len(pods))
+	for _, pod := range pods {
+		info := PodInfo{
+			Name:     pod.Name,
+			Phase:    string(pod.Status.Phase),
+			Ready:    podReady(pod),
+			Deleting: pod.DeletionTimestamp != nil,
+			IP:       pod.Status.PodIP,
+			NodeName: pod.Spec.NodeName,
+			Restarts: podRestarts(pod),
+			Current:  pod.Name == current,
+			Pinned:   pod.Name == pinned,
+		}
+		if pod.Status.StartTime != nil {
+			startTime := pod.Status.StartTime.Time
+			info.StartTime = &startTime
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// PinPod 将控制器固定到指定的Pod，之后的命令、日志、状态检测都使用该Pod，
+// 不再自动选择其他Pod；固定的Pod被删除后相关操作会返回ErrPodNotFound，直到Pod以相同名称重建（如StatefulSet）或调用UnpinPod
+func (m *MinecraftController) PinPod(podName string) error {
+	if _, err := m.findPod(podName); err != nil {
+		return err
+	}
+
+	m.podInfoUpdateMutex.Lock()
+	defer m.podInfoUpdateMutex.Unlock()
+
+	m.setPinnedPod(podName)
+	return m.findAndUpdatePodInfo()
+}
+
+// UnpinPod 取消固定Pod，恢复自动选择
+func (m *MinecraftController) UnpinPod() error {
+	m.podInfoUpdateMutex.Lock()
+	defer m.podInfoUpdateMutex.Unlock()
+
+	m.setPinnedPod("")
+	return m.findAndUpdatePodInfo()
+}
+
+// PinnedPod 返回控制器固定使用的Pod名称，为空表示自动选择
+func (m *MinecraftController) PinnedPod() string {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.pinnedPod
+}
+
+// setPinnedPod 设置固定使用的Pod，调用方需持有podInfoUpdateMutex
+func (m *MinecraftController) setPinnedPod(podName string) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	m.pinnedPod = podName
+	m.status.PinnedPod = podName
+}
+
+// CreatePodCommandSession 创建固定使用指定Pod的命令会话
+// 会话不随控制器切换Pod，自动选择执行器时的故障转移也只在该Pod上进行
+func (m *MinecraftController) CreatePodCommandSession(podName string, idleTimeout time.Duration, executorType ExecutorType) (*CommandSession, error) {
+	target, err := m.resolvePodTarget(podName)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := m.newCommandSession(idleTimeout, executorType, target)
+	if err != nil {
+		return nil, err
+	}
+
+	m.sessionManager.mutex.Lock()
+	m.sessionManager.sessions[session.id] = session
+	m.sessionManager.mutex.Unlock()
+
+	return session, nil
+}
+
+// BroadcastCommand 在所有匹配标签选择器、正在运行的Pod上同时执行命令，返回按Pod名称排序的每个Pod的结果
+// 单个Pod执行失败记录在对应结果中；没有运行中的Pod时返回ErrPodNotFound
+func (m *MinecraftController) BroadcastCommand(ctx context.Context, command string, executorType ExecutorType) ([]PodCommandResult, error) {
+	if executorType == "" {
+		executorType = ExecutorAuto
+	}
+
+	pods, err := m.listPods()
+	if err != nil {
+		return nil, fmt.Errorf("获取Pod列表失败: %v", err)
+	}
+	var targets []*podTarget
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			targets = append(targets, &podTarget{name: pod.Name, ip: pod.Status.PodIP})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%w: 没有运行中的Pod匹配标签 '%s'", ErrPodNotFound, m.podLabelSelector)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].name < targets[j].name })
+
+	results := make([]PodCommandResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.executeOnPod(ctx, target, command, executorType)
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// executeOnPod 使用一次性执行器在指定Pod上执行命令
+func (m *MinecraftController) executeOnPod(ctx context.Context, target *podTarget, command string, executorType ExecutorType) PodCommandResult {
+	start := time.Now()
+	result := PodCommandResult{Pod: target.name}
+
+	executor, err := m.createTargetExecutor(executorType, false, target)
+	if err != nil {
+		result.Duration = time.Since(start)
+		result.Err = fmt.Errorf("创建命令执行器失败: %w", err)
+		return result
+	}
+	defer executor.Disconnect()
+	result.ExecutorType = executorTypeOf(executor)
+
+	executeStart := time.Now()
+	response, err := executor.ExecuteCommandContext(ctx, command)
+	m.observeCommand(result.ExecutorType, executeStart, err)
+	m.executors.record(result.ExecutorType, err)
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.Err = fmt.Errorf("命令执行失败: %w", err)
+		return result
+	}
+	result.Response = response
+	return result
+}
+
+// findPod 查找匹配标签选择器的指定Pod
+func (m *MinecraftController) findPod(podName string) (*corev1.Pod, error) {
+	pods, err := m.listPods()
+	if err != nil {
+		return nil, fmt.Errorf("获取Pod列表失败: %v", err)
+	}
+	for _, pod := range pods {
+		if pod.Name == podName {
+			return pod, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: '%s' 不匹配标签 '%s'", ErrPodNotFound, podName, m.podLabelSelector)
+}
+
+// resolvePodTarget 查找指定Pod并返回执行器使用的目标
+func (m *MinecraftController) resolvePodTarget(podName string) (*podTarget, error) {
+	pod, err := m.findPod(podName)
+	if err != nil {
+		return nil, err
+	}
+	return &podTarget{name: pod.Name, ip: pod.Status.PodIP}, nil
+}
+
+// newPodRconExecutor 创建连接到指定Pod IP的RCON执行器
+// 执行器使用自己的连接池，RCON密码更新后按新密码重新建立连接池
+func (m *MinecraftController) newPodRconExecutor(ip string) *rconExecutor {
+	address := net.JoinHostPort(ip, strconv.Itoa(m.rconPort))
+	var pool *rconPool
+	var mutex sync.Mutex
+
+	executor := newPooledRconExecutor(func() *rconPool {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		m.rconMutex.Lock()
+		password := m.rconPassword
+		timeouts := rconTimeouts{
+			dial:  defaultRconDialTimeout,
+			read:  m.rconReadTimeout,
+			write: m.rconWriteTimeout,
+		}
+		m.rconMutex.Unlock()
+
+		if pool != nil && pool.password == password {
+			return pool
+		}
+		if pool != nil {
+			pool.close()
+		}
+		options := DefaultRconPoolOptions()
+		options.MaxSize = 1
+		pool = newRconPool(address, password, timeouts, options)
+		return pool
+	}, m.rconRetryDelay)
+	executor.ownsPool = true
+	return executor
+}
+
+// podReady 检查Pod的Ready条件
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
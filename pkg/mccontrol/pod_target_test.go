@@ -0,0 +1,166 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// otherServerPod 创建不匹配测试控制器标签选择器的运行中Pod
+func otherServerPod(name, ip string) *corev1.Pod {
+	pod := testServerPod(name, ip)
+	pod.Labels = map[string]string{"app": "proxy"}
+	return pod
+}
+
+// deleteTestPod 删除Pod并等待控制器（informer缓存）不再列出该Pod
+func deleteTestPod(t *testing.T, controller *MinecraftController, clientset *fake.Clientset, name string) {
+	t.Helper()
+
+	if err := clientset.CoreV1().Pods(testNamespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("删除Pod失败: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := controller.findPod(name); errors.Is(err, ErrPodNotFound) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("控制器仍列出已删除的Pod %q", name)
+}
+
+func TestPinPod(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon,
+		testServerPod("mc-0", "127.0.0.1"),
+		testServerPod("mc-1", "127.0.0.1"),
+		otherServerPod("proxy-0", "127.0.0.1"),
+	)
+
+	// 只能固定匹配标签选择器的Pod
+	for _, name := range []string{"proxy-0", "missing"} {
+		if err := controller.PinPod(name); !errors.Is(err, ErrPodNotFound) {
+			t.Fatalf("固定不匹配标签选择器的Pod %q 应返回ErrPodNotFound: %v", name, err)
+		}
+	}
+	if pinned := controller.PinnedPod(); pinned != "" {
+		t.Fatalf("固定失败时不应改变固定的Pod: %q", pinned)
+	}
+
+	if err := controller.PinPod("mc-1"); err != nil {
+		t.Fatalf("固定Pod失败: %v", err)
+	}
+	if controller.podName() != "mc-1" || controller.PinnedPod() != "mc-1" || controller.LastStatus().PinnedPod != "mc-1" {
+		t.Fatalf("固定后应使用该Pod: current=%q pinned=%q", controller.podName(), controller.PinnedPod())
+	}
+	pods, err := controller.ListPods()
+	if err != nil {
+		t.Fatalf("列出Pod失败: %v", err)
+	}
+	if len(pods) != 2 || pods[0].Name != "mc-0" || pods[1].Name != "mc-1" {
+		t.Fatalf("应只列出匹配标签选择器的Pod并按名称排序: %+v", pods)
+	}
+	if pods[0].Current || pods[0].Pinned || !pods[1].Current || !pods[1].Pinned {
+		t.Fatalf("Pod的当前和固定标记错误: %+v", pods)
+	}
+
+	// 固定的Pod被删除后不切换到其他Pod
+	deleteTestPod(t, controller, clientset, "mc-1")
+	if err := controller.ForceUpdatePodInfo(); !errors.Is(err, ErrPodNotFound) {
+		t.Fatalf("固定的Pod不存在时应返回ErrPodNotFound: %v", err)
+	}
+	if name := controller.podName(); name != "" {
+		t.Fatalf("固定的Pod不存在时不应使用其他Pod: %q", name)
+	}
+
+	// 取消固定后恢复自动选择
+	if err := controller.UnpinPod(); err != nil {
+		t.Fatalf("取消固定失败: %v", err)
+	}
+	if controller.PinnedPod() != "" || controller.podName() != "mc-0" {
+		t.Fatalf("取消固定后应自动选择Pod: current=%q pinned=%q", controller.podName(), controller.PinnedPod())
+	}
+}
+
+func TestPodSelectorValidation(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon,
+		testServerPod("mc-0", "127.0.0.1"),
+		otherServerPod("proxy-0", "127.0.0.1"),
+	)
+
+	// 指定Pod（如接口的?pod=参数）时必须匹配标签选择器
+	var streamErr string
+	if _, err := controller.FetchLogs(LogOptions{PodName: "proxy-0"}, nil); !errors.Is(err, ErrPodNotFound) {
+		t.Fatalf("读取不匹配标签选择器的Pod日志应返回ErrPodNotFound: %v", err)
+	}
+	if _, err := controller.FetchLogs(LogOptions{PodName: "proxy-0"}, func(_ []string, errMsg string) {
+		streamErr = errMsg
+	}); !errors.Is(err, ErrPodNotFound) || streamErr == "" {
+		t.Fatalf("跟踪不匹配标签选择器的Pod日志应返回ErrPodNotFound并通知回调: %v %q", err, streamErr)
+	}
+	if _, err := controller.CreatePodCommandSession("proxy-0", time.Minute, ExecutorRcon); !errors.Is(err, ErrPodNotFound) {
+		t.Fatalf("为不匹配标签选择器的Pod创建会话应返回ErrPodNotFound: %v", err)
+	}
+	if _, err := controller.CreatePodCommandSession("missing", time.Minute, ExecutorRcon); !errors.Is(err, ErrPodNotFound) {
+		t.Fatalf("为不存在的Pod创建会话应返回ErrPodNotFound: %v", err)
+	}
+
+	if _, err := controller.FetchLogs(LogOptions{PodName: "mc-0"}, nil); err != nil {
+		t.Fatalf("读取匹配的Pod日志失败: %v", err)
+	}
+	session, err := controller.CreatePodCommandSession("mc-0", time.Minute, ExecutorRcon)
+	if err != nil {
+		t.Fatalf("创建Pod会话失败: %v", err)
+	}
+	defer session.Close()
+	if response, err := session.ExecuteCommand("list"); err != nil || response != "list" {
+		t.Fatalf("Pod会话执行命令失败: %q %v", response, err)
+	}
+}
+
+func TestBroadcastCommand(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	pending := testServerPod("mc-3", "127.0.0.1")
+	pending.Status.Phase = corev1.PodPending
+	controller, clientset := newTestController(t, rcon,
+		testServerPod("mc-2", "127.0.0.1"),
+		// 测试RCON服务器只监听127.0.0.1，连接该Pod失败
+		testServerPod("mc-1", "127.0.0.2"),
+		testServerPod("mc-0", "127.0.0.1"),
+		pending,
+		otherServerPod("proxy-0", "127.0.0.1"),
+	)
+	controller.rconRetryDelay = time.Millisecond
+
+	// 只在匹配标签选择器的运行中Pod上执行，结果按Pod名称排序，单个Pod失败不影响其他Pod
+	results, err := controller.BroadcastCommand(context.Background(), "say hi", ExecutorRcon)
+	if err != nil {
+		t.Fatalf("广播命令失败: %v", err)
+	}
+	if len(results) != 3 || results[0].Pod != "mc-0" || results[1].Pod != "mc-1" || results[2].Pod != "mc-2" {
+		t.Fatalf("应在运行中的Pod上执行并按名称排序: %+v", results)
+	}
+	for _, i := range []int{0, 2} {
+		if results[i].Err != nil || results[i].Response != "say hi" || results[i].ExecutorType != ExecutorRcon {
+			t.Fatalf("Pod %s 的结果错误: %+v", results[i].Pod, results[i])
+		}
+	}
+	if results[1].Err == nil || results[1].Response != "" {
+		t.Fatalf("连接失败的Pod应记录错误: %+v", results[1])
+	}
+
+	// 没有运行中的Pod时返回ErrPodNotFound
+	for _, name := range []string{"mc-0", "mc-1", "mc-2"} {
+		deleteTestPod(t, controller, clientset, name)
+	}
+	if _, err := controller.BroadcastCommand(context.Background(), "say hi", ExecutorRcon); !errors.Is(err, ErrPodNotFound) {
+		t.Fatalf("没有运行中的Pod时应返回ErrPodNotFound: %v", err)
+	}
+}
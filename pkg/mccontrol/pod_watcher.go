@@ -0,0 +1,225 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	podWatchResync      = 10 * time.Minute // informer的重新同步间隔
+	podWatchSyncTimeout = 10 * time.Second // 创建控制器时等待缓存同步的最长时间
+	podChangeBufferSize = 8                // 每个Pod变化订阅者的缓冲区大小
+)
+
+// PodChange 表示控制器当前使用的Pod发生了变化（如Pod被重建或调度到其他节点）
+type PodChange struct {
+	Previous   string    `json:"previous"`    // 原Pod名称，为空表示之前没有可用的Pod
+	Current    string    `json:"current"`     // 新Pod名称，为空表示当前没有匹配的Pod（如已缩容到0）
+	PreviousIP string    `json:"previous_ip"` // 原Pod的IP
+	CurrentIP  string    `json:"current_ip"`  // 新Pod的IP
+	Time       time.Time `json:"time"`        // 发现变化的时间
+}
+
+// podWatcher 通过informer跟踪匹配标签选择器的Pod和Service，并通知当前Pod的变化
+// 缓存同步前或未启用informer时，Pod信息仍通过List请求获取
+type podWatcher struct {
+	podLister      corelisters.PodLister     // Pod缓存，为nil表示未启用informer
+	serviceLister  corelisters.ServiceLister // Service缓存
+	podsSynced     cache.InformerSynced
+	servicesSynced cache.InformerSynced
+
+	listeners map[chan PodChange]struct{}
+	closed    bool
+	mutex     sync.Mutex // 保护listeners和closed
+}
+
+// newPodWatcher 创建Pod跟踪器，需调用startPodWatch启动informer
+func newPodWatcher() *podWatcher {
+	return &podWatcher{
+		listeners: make(map[chan PodChange]struct{}),
+	}
+}
+
+// synced 检查informer是否已启用并完成同步
+func (w *podWatcher) synced() bool {
+	return w.podLister != nil && w.podsSynced() && w.servicesSynced()
+}
+
+// SubscribePodChanges 订阅当前Pod的变化
+// 控制器切换到新Pod时立即通知，可用于将日志流、会话等迁移到新Pod；
+// 订阅者处理过慢导致缓冲区已满时，新通知会被丢弃；控制器关闭时通道会被关闭
+func (m *MinecraftController) SubscribePodChanges() <-chan PodChange {
+	w := m.pods
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	ch := make(chan PodChange, podChangeBufferSize)
+	if w.closed {
+		close(ch)
+		return ch
+	}
+	w.listeners[ch] = struct{}{}
+	return ch
+}
+
+// UnsubscribePodChanges 取消订阅并关闭通道
+func (m *MinecraftController) UnsubscribePodChanges(ch <-chan PodChange) {
+	w := m.pods
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for listener := range w.listeners {
+		if listener == ch {
+			delete(w.listeners, listener)
+			close(listener)
+			break
+		}
+	}
+}
+
+// notify 通知所有订阅者
+func (w *podWatcher) notify(change PodChange) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for listener := range w.listeners {
+		select {
+		case listener <- change:
+		default:
+			// 订阅者处理过慢，丢弃通知
+		}
+	}
+}
+
+// close 关闭所有订阅者
+func (w *podWatcher) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for listener := range w.listeners {
+		close(listener)
+	}
+	w.listeners = make(map[chan PodChange]struct{})
+	w.closed = true
+}
+
+// startPodWatch 启动Pod和Service的informer，informer随控制器关闭而停止
+// 在缓存同步或超时后返回；超时不影响使用，同步完成前通过List请求获取Pod信息
+func (m *MinecraftController) startPodWatch() error {
+	podFactory := informers.NewSharedInformerFactoryWithOptions(m.clientset, podWatchResync,
+		informers.WithNamespace(m.namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = m.podLabelSelector
+		}),
+	)
+	serviceLabelSelector := m.serviceLabelSelector
+	if serviceLabelSelector == "" {
+		serviceLabelSelector = m.podLabelSelector
+	}
+	serviceFactory := informers.NewSharedInformerFactoryWithOptions(m.clientset, podWatchResync,
+		informers.WithNamespace(m.namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = serviceLabelSelector
+		}),
+	)
+
+	pods := podFactory.Core().V1().Pods()
+	services := serviceFactory.Core().V1().Services()
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { m.onPodWatchEvent() },
+		UpdateFunc: func(interface{}, interface{}) { m.onPodWatchEvent() },
+		DeleteFunc: func(interface{}) { m.onPodWatchEvent() },
+	}
+	if _, err := pods.Informer().AddEventHandler(handler); err != nil {
+		return err
+	}
+	if _, err := services.Informer().AddEventHandler(handler); err != nil {
+		return err
+	}
+
+	m.pods.podLister = pods.Lister()
+	m.pods.serviceLister = services.Lister()
+	m.pods.podsSynced = pods.Informer().HasSynced
+	m.pods.servicesSynced = services.Informer().HasSynced
+
+	podFactory.Start(m.ctx.Done())
+	serviceFactory.Start(m.ctx.Done())
+
+	ctx, cancel := context.WithTimeout(m.ctx, podWatchSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), m.pods.podsSynced, m.pods.servicesSynced) {
+		return errors.New("等待Pod缓存同步超时，同步完成前通过List请求获取Pod信息")
+	}
+	return nil
+}
+
+// onPodWatchEvent 匹配的Pod或Service发生变化时立即更新Pod信息
+func (m *MinecraftController) onPodWatchEvent() {
+	if !m.pods.synced() {
+		return
+	}
+	m.podInfoUpdateMutex.Lock()
+	defer m.podInfoUpdateMutex.Unlock()
+	m.findAndUpdatePodInfo()
+}
+
+// listPods 列出匹配标签选择器的Pod，informer已同步时从缓存读取
+func (m *MinecraftController) listPods() ([]*corev1.Pod, error) {
+	if m.pods.synced() {
+		return m.pods.podLister.List(labels.Everything())
+	}
+
+	list, err := m.clientset.CoreV1().Pods(m.namespace).List(m.ctx, metav1.ListOptions{
+		LabelSelector: m.podLabelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, len(list.Items))
+	for i := range list.Items {
+		pods[i] = &list.Items[i]
+	}
+	return pods, nil
+}
+
+// listServices 列出匹配服务标签选择器的Service，informer已同步时从缓存读取
+func (m *MinecraftController) listServices() ([]*corev1.Service, error) {
+	if m.pods.synced() {
+		return m.pods.serviceLister.List(labels.Everything())
+	}
+
+	serviceLabelSelector := m.serviceLabelSelector
+	if serviceLabelSelector == "" {
+		serviceLabelSelector = m.podLabelSelector // 默认使用与Pod相同的标签选择器
+	}
+	list, err := m.clientset.CoreV1().Services(m.namespace).List(m.ctx, metav1.ListOptions{
+		LabelSelector: serviceLabelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*corev1.Service, len(list.Items))
+	for i := range list.Items {
+		services[i] = &list.Items[i]
+	}
+	return services, nil
+}
+
+// followPodChanges 将已注册的命令会话迁移到新Pod，控制器关闭时结束
+func (m *MinecraftController) followPodChanges() {
+	changes := m.SubscribePodChanges()
+	for change := range changes {
+		if change.Current != "" {
+			m.sessionManager.switchPod(change.Current)
+		}
+	}
+}
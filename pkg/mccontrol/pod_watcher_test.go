@@ -0,0 +1,103 @@
+package mccontrol
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod(name string, phase corev1.PodPhase, started time.Time, deleting bool) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+	if !started.IsZero() {
+		startTime := metav1.NewTime(started)
+		pod.Status.StartTime = &startTime
+	}
+	if deleting {
+		now := metav1.Now()
+		pod.DeletionTimestamp = &now
+	}
+	return pod
+}
+
+func TestSelectPod(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		current string
+		pods    []*corev1.Pod
+		want    string
+	}{
+		{
+			name: "优先选择Running",
+			pods: []*corev1.Pod{
+				testPod("pending", corev1.PodPending, time.Time{}, false),
+				testPod("running", corev1.PodRunning, now, false),
+			},
+			want: "running",
+		},
+		{
+			name: "跳过正在删除的Pod",
+			pods: []*corev1.Pod{
+				testPod("old", corev1.PodRunning, now, true),
+				testPod("new", corev1.PodRunning, now, false),
+			},
+			want: "new",
+		},
+		{
+			name:    "沿用当前Pod",
+			current: "b",
+			pods: []*corev1.Pod{
+				testPod("a", corev1.PodRunning, now, false),
+				testPod("b", corev1.PodRunning, now, false),
+			},
+			want: "b",
+		},
+		{
+			name: "最近成功运行的Pod",
+			pods: []*corev1.Pod{
+				testPod("older", corev1.PodSucceeded, now.Add(-time.Hour), false),
+				testPod("latest", corev1.PodSucceeded, now, false),
+				testPod("unstarted", corev1.PodSucceeded, time.Time{}, false),
+			},
+			want: "latest",
+		},
+		{
+			name: "都不满足时选第一个",
+			pods: []*corev1.Pod{
+				testPod("first", corev1.PodPending, time.Time{}, false),
+				testPod("second", corev1.PodFailed, now, false),
+			},
+			want: "first",
+		},
+	}
+
+	for _, tt := range tests {
+		m := &MinecraftController{currentPodName: tt.current}
+		if got := m.selectPod(tt.pods).Name; got != tt.want {
+			t.Errorf("%s: selectPod() = %s, 期望 %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectPinnedPod(t *testing.T) {
+	now := time.Now()
+	pods := []*corev1.Pod{
+		testPod("running", corev1.PodRunning, now, false),
+		testPod("pending", corev1.PodPending, time.Time{}, false),
+	}
+
+	m := &MinecraftController{currentPodName: "running", pinnedPod: "pending"}
+	if got := m.selectPod(pods); got == nil || got.Name != "pending" {
+		t.Errorf("固定Pod时应选择固定的Pod，实际为 %v", got)
+	}
+
+	m.pinnedPod = "missing"
+	if got := m.selectPod(pods); got != nil {
+		t.Errorf("固定的Pod不存在时应返回nil，实际为 %s", got.Name)
+	}
+}
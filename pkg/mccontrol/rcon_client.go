@@ -0,0 +1,393 @@
+package mccontrol
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// RCON协议的数据包类型
+const (
+	rconTypeResponse     int32 = 0 // SERVERDATA_RESPONSE_VALUE
+	rconTypeCommand      int32 = 2 // SERVERDATA_EXECCOMMAND
+	rconTypeAuthResponse int32 = 2 // SERVERDATA_AUTH_RESPONSE
+	rconTypeAuth         int32 = 3 // SERVERDATA_AUTH
+)
+
+// RCON协议的长度限制
+const (
+	rconHeaderSize       = 8       // 请求ID和类型的长度
+	rconMinPacketSize    = 10      // 请求ID、类型和两个结束符的长度
+	rconMaxPacketSize    = 1 << 20 // 单个响应数据包的最大长度，防止异常数据导致分配过多内存
+	rconMaxCommandLength = 1446    // Minecraft服务器接受的最大命令长度
+)
+
+// RCON客户端的默认超时
+const (
+	defaultRconDialTimeout  = 5 * time.Second
+	defaultRconReadTimeout  = 10 * time.Second
+	defaultRconWriteTimeout = 5 * time.Second
+)
+
+var (
+	// ErrRconAuthFailed RCON密码错误
+	ErrRconAuthFailed = errors.New("RCON认证失败: 密码错误")
+	// ErrRconClosed RCON连接已关闭
+	ErrRconClosed = errors.New("RCON连接已关闭")
+	// errRconCommandTooLong 命令超过服务器接受的最大长度
+	errRconCommandTooLong = fmt.Errorf("命令长度不能超过%d字节", rconMaxCommandLength)
+)
+
+// rconPacket RCON数据包
+type rconPacket struct {
+	id   int32
+	typ  int32
+	body []byte
+}
+
+// encodeRconPacket 将数据包编码为 长度 + 请求ID + 类型 + 内容 + 两个结束符
+func encodeRconPacket(packet rconPacket) []byte {
+	length := rconHeaderSize + len(packet.body) + 2
+	buf := make([]byte, 4+length)
+	binary.LittleEndian.PutUint32(buf[0:], uint32(length))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(packet.id))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(packet.typ))
+	copy(buf[12:], packet.body)
+	return buf
+}
+
+// readRconPacket 读取一个完整的数据包
+func readRconPacket(r io.Reader) (rconPacket, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return rconPacket{}, err
+	}
+
+	length := int32(binary.LittleEndian.Uint32(size[:]))
+	if length < rconMinPacketSize || length > rconMaxPacketSize {
+		return rconPacket{}, fmt.Errorf("无效的RCON数据包长度: %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return rconPacket{}, err
+	}
+
+	return rconPacket{
+		id:   int32(binary.LittleEndian.Uint32(data[0:])),
+		typ:  int32(binary.LittleEndian.Uint32(data[4:])),
+		body: data[rconHeaderSize : length-2],
+	}, nil
+}
+
+// rconTimeouts RCON连接的超时设置，为0则不设置对应的超时
+type rconTimeouts struct {
+	dial  time.Duration // 建立连接的超时
+	read  time.Duration // 有命令等待响应时，两次收到数据之间的最长间隔
+	write time.Duration // 写入一条命令的超时
+}
+
+// rconRequest 一条等待响应的命令
+type rconRequest struct {
+	id           int32         // 命令的请求ID
+	terminatorID int32         // 结束标记的请求ID
+	terminated   bool          // 已发送结束标记
+	body         []byte        // 已收到的响应内容
+	err          error         // 连接断开等失败原因
+	done         chan struct{} // 响应接收完毕或失败时关闭
+}
+
+// rconClient RCON协议客户端
+// Minecraft原版服务器每次从连接读取一次数据（最多1460字节）并当作一个完整的数据包处理，
+// 一次读到多个数据包时长度校验失败并断开连接，因此同一连接上同时只能有一个未被服务器读取的数据包：
+// 连接上同时只执行一条命令，收到命令的第一个响应分片（说明服务器已读取命令）后才发送空的结束标记数据包，
+// 服务器按顺序处理数据包，收到结束标记的响应即说明该命令的所有响应分片都已收到。
+// 多条命令通过连接池使用不同的连接并行执行
+type rconClient struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	timeouts rconTimeouts
+
+	busy chan struct{} // 容量为1，命令发送前占用，收到完整响应或连接关闭后释放
+
+	mutex   sync.Mutex
+	nextID  int32
+	pending map[int32]*rconRequest // 请求ID和结束标记ID -> 命令
+	err     error                  // 连接关闭的原因，不为nil表示连接已不可用
+}
+
+// dialRcon 连接RCON服务器并完成认证
+func dialRcon(ctx context.Context, address, password string, timeouts rconTimeouts) (*rconClient, error) {
+	dialer := net.Dialer{Timeout: timeouts.dial}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &rconClient{
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		timeouts: timeouts,
+		busy:     make(chan struct{}, 1),
+		nextID:   1,
+		pending:  make(map[int32]*rconRequest),
+	}
+
+	// 认证期间上下文取消时中断读写
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	err = client.authenticate(password)
+	if !stop() {
+		// 连接的超时已被修改，即使认证成功也不能继续使用
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go client.readLoop()
+	return client, nil
+}
+
+// authenticate 发送密码并等待认证结果，需在启动读取循环前调用
+func (c *rconClient) authenticate(password string) error {
+	authID := c.allocID()
+	if err := c.write(encodeRconPacket(rconPacket{id: authID, typ: rconTypeAuth, body: []byte(password)})); err != nil {
+		return err
+	}
+
+	if c.timeouts.read > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.timeouts.read))
+	}
+	for {
+		packet, err := readRconPacket(c.reader)
+		if err != nil {
+			return err
+		}
+		// 部分服务器会先返回一个空的响应数据包，忽略认证结果以外的数据包
+		if packet.typ != rconTypeAuthResponse {
+			continue
+		}
+		if packet.id == -1 {
+			return ErrRconAuthFailed
+		}
+		if packet.id == authID {
+			c.conn.SetDeadline(time.Time{})
+			return nil
+		}
+	}
+}
+
+// Command 执行命令并等待完整的响应，连接上有其他命令在执行时等待其完成
+// 上下文取消时放弃等待，命令仍在连接上完成，之后到达的响应会被丢弃，连接仍可继续使用
+func (c *rconClient) Command(ctx context.Context, command string) (string, error) {
+	if len(command) > rconMaxCommandLength {
+		return "", errRconCommandTooLong
+	}
+
+	request, err := c.send(ctx, func() (rconPacket, *rconRequest) {
+		id, terminatorID := c.allocID(), c.allocID()
+		return rconPacket{id: id, typ: rconTypeCommand, body: []byte(command)}, &rconRequest{id: id, terminatorID: terminatorID}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	select {
+	case <-request.done:
+		if request.err != nil {
+			return "", request.err
+		}
+		return string(request.body), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Ping 发送一个空的结束标记数据包并等待服务器响应，用于检查连接是否可用
+// Minecraft服务器对未知类型的请求只返回错误信息，不会执行任何命令
+func (c *rconClient) Ping(ctx context.Context) error {
+	request, err := c.send(ctx, func() (rconPacket, *rconRequest) {
+		id := c.allocID()
+		return rconPacket{id: id, typ: rconTypeResponse}, &rconRequest{id: id, terminatorID: id, terminated: true}
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-request.done:
+		return request.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send 占用连接后发送一个请求数据包，newRequest在持有锁时调用，生成数据包和对应的请求
+// 连接在请求完成（收到结束标记的响应或连接关闭）后释放
+func (c *rconClient) send(ctx context.Context, newRequest func() (rconPacket, *rconRequest)) (*rconRequest, error) {
+	select {
+	case c.busy <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mutex.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mutex.Unlock()
+		<-c.busy
+		return nil, err
+	}
+	packet, request := newRequest()
+	request.done = make(chan struct{})
+	c.pending[request.id] = request
+	c.pending[request.terminatorID] = request
+	c.mutex.Unlock()
+
+	if err := c.write(encodeRconPacket(packet)); err != nil {
+		// 写入了一部分的数据会破坏后续数据包的边界，连接不能再使用
+		c.fail(err)
+		return nil, c.closeErr()
+	}
+
+	c.mutex.Lock()
+	c.refreshReadDeadline()
+	c.mutex.Unlock()
+	return request, nil
+}
+
+// Close 关闭连接，等待中的命令返回ErrRconClosed
+func (c *rconClient) Close() {
+	c.fail(ErrRconClosed)
+}
+
+// Alive 检查连接是否仍然可用
+func (c *rconClient) Alive() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err == nil
+}
+
+// readLoop 持续读取响应数据包并分发给对应的命令
+func (c *rconClient) readLoop() {
+	for {
+		packet, err := readRconPacket(c.reader)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		var terminator *rconPacket
+		c.mutex.Lock()
+		if request, ok := c.pending[packet.id]; ok {
+			if packet.id == request.terminatorID {
+				// 结束标记的响应，命令的所有分片都已收到
+				c.finish(request)
+			} else {
+				request.body = append(request.body, packet.body...)
+				if !request.terminated {
+					// 服务器已读取命令，此时发送结束标记不会与命令在同一次读取中到达
+					request.terminated = true
+					terminator = &rconPacket{id: request.terminatorID, typ: rconTypeResponse}
+				}
+			}
+		}
+		c.refreshReadDeadline()
+		c.mutex.Unlock()
+
+		if terminator != nil {
+			if err := c.write(encodeRconPacket(*terminator)); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// finish 完成请求并释放连接，调用方需持有锁
+func (c *rconClient) finish(request *rconRequest) {
+	delete(c.pending, request.id)
+	delete(c.pending, request.terminatorID)
+	close(request.done)
+	<-c.busy
+}
+
+// write 写入数据，超过写入超时则失败，调用方需占用连接或尚未启动读取循环
+func (c *rconClient) write(data []byte) error {
+	if c.timeouts.write > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeouts.write))
+	}
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// fail 关闭连接，并使所有等待中的命令以err失败
+func (c *rconClient) fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrRconClosed):
+		c.err = err
+	case errors.As(err, &netErr) && netErr.Timeout():
+		c.err = fmt.Errorf("等待RCON响应超时: %w", err)
+	default:
+		c.err = fmt.Errorf("RCON连接已断开: %w", err)
+	}
+	c.conn.Close()
+
+	for id, request := range c.pending {
+		if id == request.id {
+			request.err = c.err
+			c.finish(request)
+		}
+	}
+}
+
+// closeErr 返回连接关闭的原因
+func (c *rconClient) closeErr() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
+
+// allocID 分配一个新的请求ID，跳过认证失败使用的-1，调用方需持有锁或尚未启动读取循环
+func (c *rconClient) allocID() int32 {
+	id := c.nextID
+	if c.nextID == math.MaxInt32 {
+		c.nextID = 1
+	} else {
+		c.nextID++
+	}
+	return id
+}
+
+// refreshReadDeadline 有命令等待响应时设置读取超时，否则取消超时，调用方需持有锁
+// 空闲连接不会因为长时间没有数据而被关闭
+func (c *rconClient) refreshReadDeadline() {
+	if c.timeouts.read <= 0 || c.err != nil {
+		return
+	}
+	if len(c.pending) == 0 {
+		c.conn.SetReadDeadline(time.Time{})
+	} else {
+		c.conn.SetReadDeadline(time.Now().Add(c.timeouts.read))
+	}
+}
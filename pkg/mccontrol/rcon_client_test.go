@@ -0,0 +1,505 @@
+package mccontrol
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 与Minecraft原版服务器一致的长度限制
+const (
+	fakeRconMaxPayload = 4096 // 响应超过该长度时拆分为多个数据包
+	fakeRconReadSize   = 1460 // 每次从连接读取的最大长度
+)
+
+// fakeRconServer 进程内的RCON服务器，行为与Minecraft原版服务器一致：
+// 每次读取当作一个完整的数据包，读取到的长度与数据包声明的长度不一致（如一次读到多个数据包）时断开连接；
+// 处理完一个数据包并发送响应后才读取下一个；认证失败返回请求ID为-1的认证响应，
+// 长响应按4096字节拆分，未知类型的请求返回"Unknown request"
+type fakeRconServer struct {
+	listener net.Listener
+	password string
+	handler  func(command string) string
+
+	received atomic.Int32 // 已读取的命令数量
+	accepted atomic.Int32 // 已接受的连接数量，用于验证连接复用
+	rejected atomic.Int32 // 因读取到的长度与数据包长度不一致而断开的连接数量
+
+	mutex sync.Mutex
+	conns []net.Conn
+}
+
+// newFakeRconServer 启动一个RCON服务器，测试结束时关闭
+func newFakeRconServer(t *testing.T, password string, handler func(command string) string) *fakeRconServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+
+	server := &fakeRconServer{
+		listener: listener,
+		password: password,
+		handler:  handler,
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		server.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.accepted.Add(1)
+			server.mutex.Lock()
+			server.conns = append(server.conns, conn)
+			server.mutex.Unlock()
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+// addr 返回服务器地址
+func (s *fakeRconServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+// port 返回服务器端口
+func (s *fakeRconServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// dropConnections 断开所有客户端连接
+func (s *fakeRconServer) dropConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// serve 按顺序读取并处理请求，每次读取只处理一个数据包
+func (s *fakeRconServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, fakeRconReadSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil || n < rconMinPacketSize {
+			return
+		}
+		packet, err := readRconPacket(bytes.NewReader(buf[:n]))
+		if err != nil || int(binary.LittleEndian.Uint32(buf)) != n-4 {
+			s.rejected.Add(1)
+			return
+		}
+		if packet.typ == rconTypeCommand {
+			s.received.Add(1)
+		}
+
+		for _, response := range s.respond(packet) {
+			if _, err := conn.Write(encodeRconPacket(response)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// respond 生成请求的响应数据包
+func (s *fakeRconServer) respond(packet rconPacket) []rconPacket {
+	switch packet.typ {
+	case rconTypeAuth:
+		id := packet.id
+		if string(packet.body) != s.password {
+			id = -1
+		}
+		return []rconPacket{{id: id, typ: rconTypeAuthResponse}}
+	case rconTypeCommand:
+		body := []byte(s.handler(string(packet.body)))
+		responses := []rconPacket{{id: packet.id, typ: rconTypeResponse, body: body[:min(len(body), fakeRconMaxPayload)]}}
+		for offset := fakeRconMaxPayload; offset < len(body); offset += fakeRconMaxPayload {
+			end := min(offset+fakeRconMaxPayload, len(body))
+			responses = append(responses, rconPacket{id: packet.id, typ: rconTypeResponse, body: body[offset:end]})
+		}
+		return responses
+	default:
+		body := fmt.Sprintf("Unknown request %x", packet.typ)
+		return []rconPacket{{id: packet.id, typ: rconTypeResponse, body: []byte(body)}}
+	}
+}
+
+// echoHandler 原样返回命令
+func echoHandler(command string) string {
+	return command
+}
+
+// dialTestRcon 连接测试服务器
+func dialTestRcon(t *testing.T, server *fakeRconServer, timeouts rconTimeouts) *rconClient {
+	t.Helper()
+
+	client, err := dialRcon(context.Background(), server.addr(), server.password, timeouts)
+	if err != nil {
+		t.Fatalf("连接RCON失败: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestRconPacketRoundTrip(t *testing.T) {
+	packets := []rconPacket{
+		{id: 1, typ: rconTypeCommand, body: []byte("list")},
+		{id: 42, typ: rconTypeResponse, body: []byte{}},
+		{id: -1, typ: rconTypeAuthResponse, body: []byte("§a彩色文本")},
+	}
+
+	for _, packet := range packets {
+		decoded, err := readRconPacket(bytes.NewReader(encodeRconPacket(packet)))
+		if err != nil {
+			t.Fatalf("解析数据包失败: %v", err)
+		}
+		if decoded.id != packet.id || decoded.typ != packet.typ || !bytes.Equal(decoded.body, packet.body) {
+			t.Errorf("数据包不一致: 期望 %+v, 实际 %+v", packet, decoded)
+		}
+	}
+}
+
+func TestRconPacketInvalidLength(t *testing.T) {
+	data := encodeRconPacket(rconPacket{id: 1, typ: rconTypeResponse})
+	data[0] = 2 // 小于最小长度
+
+	if _, err := readRconPacket(bytes.NewReader(data)); err == nil {
+		t.Fatal("期望长度无效的数据包返回错误")
+	}
+}
+
+func TestRconClientAuthenticate(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+
+	client, err := dialRcon(context.Background(), server.addr(), "secret", rconTimeouts{read: time.Second})
+	if err != nil {
+		t.Fatalf("期望认证成功: %v", err)
+	}
+	client.Close()
+
+	_, err = dialRcon(context.Background(), server.addr(), "wrong", rconTimeouts{read: time.Second})
+	if !errors.Is(err, ErrRconAuthFailed) {
+		t.Fatalf("期望ErrRconAuthFailed, 实际 %v", err)
+	}
+}
+
+func TestRconClientCommand(t *testing.T) {
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		if command == "list" {
+			return "There are 0 of a max of 20 players online: "
+		}
+		return ""
+	})
+	client := dialTestRcon(t, server, rconTimeouts{read: time.Second})
+
+	response, err := client.Command(context.Background(), "list")
+	if err != nil {
+		t.Fatalf("执行命令失败: %v", err)
+	}
+	if response != "There are 0 of a max of 20 players online: " {
+		t.Errorf("响应不正确: %q", response)
+	}
+
+	// 没有输出的命令返回空字符串
+	response, err = client.Command(context.Background(), "save-all flush")
+	if err != nil || response != "" {
+		t.Errorf("期望空响应, 实际 %q, %v", response, err)
+	}
+}
+
+func TestRconClientMultiPacketResponse(t *testing.T) {
+	sizes := []int{fakeRconMaxPayload - 1, fakeRconMaxPayload, fakeRconMaxPayload + 1, 3*fakeRconMaxPayload + 123}
+
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		size, _ := strconv.Atoi(command)
+		var builder strings.Builder
+		for i := 0; builder.Len() < size; i++ {
+			fmt.Fprintf(&builder, "%d,", i)
+		}
+		return builder.String()[:size]
+	})
+	client := dialTestRcon(t, server, rconTimeouts{read: time.Second})
+
+	for _, size := range sizes {
+		response, err := client.Command(context.Background(), strconv.Itoa(size))
+		if err != nil {
+			t.Fatalf("执行命令失败: %v", err)
+		}
+		expected := server.handler(strconv.Itoa(size))
+		if response != expected {
+			t.Errorf("长度为%d的响应拼接不正确: 实际长度%d", size, len(response))
+		}
+	}
+}
+
+func TestRconFakeServerRejectsCoalescedPackets(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+
+	conn, err := net.Dial("tcp", server.addr())
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	// 命令和结束标记在一次写入中发送，服务器一次读到两个数据包后断开连接
+	data := encodeRconPacket(rconPacket{id: 1, typ: rconTypeAuth, body: []byte("secret")})
+	data = append(data, encodeRconPacket(rconPacket{id: 2, typ: rconTypeCommand, body: []byte("list")})...)
+	if _, err := conn.Write(data); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := readRconPacket(conn); err == nil {
+		t.Fatal("一次读到多个数据包时服务器应断开连接")
+	}
+	if rejected := server.rejected.Load(); rejected != 1 {
+		t.Errorf("期望服务器拒绝1个连接, 实际%d", rejected)
+	}
+}
+
+func TestRconClientConcurrentCommands(t *testing.T) {
+	const commands = 20
+
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		// 部分命令的响应拆分为多个数据包
+		if n, _ := strconv.Atoi(command); n%3 == 0 {
+			return strings.Repeat(command, fakeRconMaxPayload)
+		}
+		return "response-" + command
+	})
+	client := dialTestRcon(t, server, rconTimeouts{read: 5 * time.Second})
+
+	// 同一连接上并发执行的命令依次发送，服务器每次只读到一个数据包
+	var wg sync.WaitGroup
+	errs := make(chan error, commands)
+	for i := 0; i < commands; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			command := strconv.Itoa(i)
+			response, err := client.Command(context.Background(), command)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if response != server.handler(command) {
+				errs <- fmt.Errorf("命令%d的响应不正确: 长度%d", i, len(response))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if received := server.received.Load(); received != commands {
+		t.Errorf("期望服务器收到%d条命令, 实际%d", commands, received)
+	}
+	if rejected := server.rejected.Load(); rejected != 0 {
+		t.Errorf("服务器不应读到合并的数据包, 实际断开了%d个连接", rejected)
+	}
+	if !client.Alive() {
+		t.Error("连接应仍然可用")
+	}
+}
+
+func TestRconClientReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		<-release
+		return ""
+	})
+	client := dialTestRcon(t, server, rconTimeouts{read: 100 * time.Millisecond})
+
+	start := time.Now()
+	_, err := client.Command(context.Background(), "hang")
+	if err == nil {
+		t.Fatal("期望等待响应超时")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("超时耗时过长: %v", elapsed)
+	}
+	if client.Alive() {
+		t.Error("超时后连接应不可用")
+	}
+}
+
+func TestRconClientIdleConnectionStaysOpen(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	client := dialTestRcon(t, server, rconTimeouts{read: 50 * time.Millisecond})
+
+	// 没有命令等待响应时，超过读取超时也不应断开
+	time.Sleep(200 * time.Millisecond)
+	if !client.Alive() {
+		t.Fatal("空闲连接不应因读取超时断开")
+	}
+	if _, err := client.Command(context.Background(), "ping"); err != nil {
+		t.Fatalf("空闲后执行命令失败: %v", err)
+	}
+}
+
+func TestRconClientContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		if command == "slow" {
+			<-release
+		}
+		return "response-" + command
+	})
+	client := dialTestRcon(t, server, rconTimeouts{read: 5 * time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Command(ctx, "slow")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望context.DeadlineExceeded, 实际 %v", err)
+	}
+	close(release)
+
+	// 被放弃的命令的响应会被丢弃，连接可以继续使用
+	response, err := client.Command(context.Background(), "fast")
+	if err != nil {
+		t.Fatalf("取消后执行命令失败: %v", err)
+	}
+	if response != "response-fast" {
+		t.Errorf("响应不正确: %q", response)
+	}
+}
+
+func TestRconClientServerClose(t *testing.T) {
+	received := make(chan struct{})
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		close(received)
+		time.Sleep(time.Second)
+		return ""
+	})
+	client := dialTestRcon(t, server, rconTimeouts{read: 5 * time.Second})
+
+	go func() {
+		<-received
+		server.dropConnections()
+	}()
+
+	if _, err := client.Command(context.Background(), "list"); err == nil {
+		t.Fatal("期望连接断开时命令失败")
+	}
+	if client.Alive() {
+		t.Error("连接断开后应不可用")
+	}
+	if _, err := client.Command(context.Background(), "list"); err == nil {
+		t.Error("期望在已断开的连接上执行命令失败")
+	}
+}
+
+func TestRconClientCommandTooLong(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	client := dialTestRcon(t, server, rconTimeouts{read: time.Second})
+
+	_, err := client.Command(context.Background(), strings.Repeat("a", rconMaxCommandLength+1))
+	if !errors.Is(err, errRconCommandTooLong) {
+		t.Fatalf("期望errRconCommandTooLong, 实际 %v", err)
+	}
+	if !client.Alive() {
+		t.Error("命令过长不应断开连接")
+	}
+}
+
+func TestRconExecutorReconnect(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+
+	executor := newRconExecutor("127.0.0.1", server.port(), "secret")
+	executor.retryDelay = 10 * time.Millisecond
+	defer executor.Disconnect()
+
+	if err := executor.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+
+	server.dropConnections()
+
+	response, err := executor.ExecuteCommand("list")
+	if err != nil {
+		t.Fatalf("断开后应自动重连: %v", err)
+	}
+	if response != "list" {
+		t.Errorf("响应不正确: %q", response)
+	}
+	if !executor.IsConnected() {
+		t.Error("重连后应处于连接状态")
+	}
+}
+
+func TestRconExecutorRetriesOnlyReadOnlyCommands(t *testing.T) {
+	// 每条命令第一次送达时断开连接，模拟命令已发送但没有收到响应
+	var server *fakeRconServer
+	var mutex sync.Mutex
+	seen := make(map[string]int)
+	server = newFakeRconServer(t, "secret", func(command string) string {
+		mutex.Lock()
+		seen[command]++
+		first := seen[command] == 1
+		mutex.Unlock()
+		if first {
+			server.dropConnections()
+		}
+		return command
+	})
+
+	executor := newRconExecutor("127.0.0.1", server.port(), "secret")
+	executor.retryDelay = 10 * time.Millisecond
+	defer executor.Disconnect()
+
+	// 有副作用的命令可能已经执行，不重试
+	if _, err := executor.ExecuteCommand("give Steve diamond"); err == nil {
+		t.Fatal("命令发送后连接断开应返回错误")
+	}
+	// 只读的查询命令重连后重试
+	response, err := executor.ExecuteCommand("list")
+	if err != nil || response != "list" {
+		t.Fatalf("只读命令应重试: %q %v", response, err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if seen["give Steve diamond"] != 1 || seen["list"] != 2 {
+		t.Fatalf("命令送达次数错误: %v", seen)
+	}
+}
+
+func TestRconExecutorAuthFailed(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+
+	executor := newRconExecutor("127.0.0.1", server.port(), "wrong")
+	executor.retryDelay = 10 * time.Millisecond
+
+	_, err := executor.ExecuteCommand("list")
+	if !errors.Is(err, ErrRconAuthFailed) {
+		t.Fatalf("期望ErrRconAuthFailed, 实际 %v", err)
+	}
+	if executor.IsConnected() {
+		t.Error("认证失败后不应处于连接状态")
+	}
+}
@@ -1,166 +1,215 @@
-package mccontrol
-
-import (
-	"fmt"
-	"math"
-	"sync"
-	"time"
-
-	"github.com/xrjr/mcutils/pkg/rcon"
-)
-
-// rconExecutor 使用RCON协议的命令执行器实现
-type rconExecutor struct {
-	client        *rcon.RCONClient // RCON客户端
-	serverIP      string           // 服务器IP
-	port          int              // RCON端口
-	password      string           // RCON密码
-	connected     bool             // 是否已连接
-	authenticated bool             // 是否已认证
-
-	// 会话控制
-	lastUsed time.Time  // 上次使用时间
-	mutex    sync.Mutex // 互斥锁，保护会话操作
-
-	// 重连控制
-	maxRetries    int           // 最大重试次数
-	retryDelay    time.Duration // 重试延迟基准时间
-	maxRetryDelay time.Duration // 最大重试延迟
-}
-
-// newRconExecutor 创建一个新的RCON执行器
-func newRconExecutor(serverIP string, port int, password string) *rconExecutor {
-	return &rconExecutor{
-		serverIP:      serverIP,
-		port:          port,
-		password:      password,
-		maxRetries:    5,
-		retryDelay:    500 * time.Millisecond,
-		maxRetryDelay: 10 * time.Second,
-	}
-}
-
-// Connect 连接到RCON服务器并进行认证
-func (e *rconExecutor) Connect() error {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	// 如果已连接，不需要重新连接
-	if e.connected && e.authenticated {
-		return nil
-	}
-
-	// 创建新客户端
-	e.client = rcon.NewClient(e.serverIP, e.port)
-
-	// 尝试连接
-	err := e.client.Connect()
-	if err != nil {
-		e.connected = false
-		e.authenticated = false
-		return fmt.Errorf("连接RCON失败: %v", err)
-	}
-
-	e.connected = true
-
-	// 尝试认证
-	ok, err := e.client.Authenticate(e.password)
-	if err != nil {
-		e.client.Disconnect()
-		e.connected = false
-		e.authenticated = false
-		return fmt.Errorf("RCON认证错误: %v", err)
-	}
-
-	if !ok {
-		e.client.Disconnect()
-		e.connected = false
-		e.authenticated = false
-		return fmt.Errorf("RCON认证失败: 密码错误")
-	}
-
-	e.authenticated = true
-	e.lastUsed = time.Now()
-	return nil
-}
-
-// ExecuteCommand 执行RCON命令，包含重连逻辑
-func (e *rconExecutor) ExecuteCommand(cmd string) (string, error) {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	// 更新最后使用时间
-	e.lastUsed = time.Now()
-
-	// 如果没有连接或者没有认证，尝试连接
-	if !e.connected || !e.authenticated {
-		e.mutex.Unlock() // 解锁以便Connect方法可以获取锁
-		if err := e.Connect(); err != nil {
-			e.mutex.Lock() // 重新获取锁
-			return "", err
-		}
-		e.mutex.Lock() // 重新获取锁
-	}
-
-	// 执行命令，带重试逻辑
-	var response string
-	var err error
-	var retryCount int
-
-	for retryCount = 0; retryCount <= e.maxRetries; retryCount++ {
-		response, err = e.client.Command(cmd)
-		if err == nil {
-			break // 命令执行成功
-		}
-
-		// 命令执行失败，可能需要重连
-		e.connected = false
-		e.authenticated = false
-
-		// 如果已经是最后一次重试，则返回错误
-		if retryCount == e.maxRetries {
-			return "", fmt.Errorf("RCON命令执行失败，已尝试重连%d次: %v", retryCount, err)
-		}
-
-		// 计算本次重试延迟
-		delay := time.Duration(float64(e.retryDelay) * math.Pow(1.5, float64(retryCount)))
-		if delay > e.maxRetryDelay {
-			delay = e.maxRetryDelay
-		}
-
-		// 释放锁，等待后重试连接
-		e.mutex.Unlock()
-		time.Sleep(delay)
-
-		// 重新连接
-		if err := e.Connect(); err != nil {
-			e.mutex.Lock() // 重新获取锁
-			continue       // 连接失败，继续重试
-		}
-
-		e.mutex.Lock() // 重新获取锁
-	}
-
-	return response, err
-}
-
-// Disconnect 断开与RCON服务器的连接
-func (e *rconExecutor) Disconnect() {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	if e.connected && e.client != nil {
-		e.client.Disconnect()
-	}
-
-	e.connected = false
-	e.authenticated = false
-}
-
-// IsConnected 检查是否已连接
-func (e *rconExecutor) IsConnected() bool {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-
-	return e.connected && e.authenticated
-}
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rconExecutor 使用RCON协议的命令执行器实现
+// 每条命令从连接池借出一个已认证的连接，执行结束后归还，命令之间不互相阻塞
+type rconExecutor struct {
+	pool     func() *rconPool // 返回当前的连接池，服务器地址变化后会返回新的连接池
+	ownsPool bool             // 连接池由执行器自己创建，断开时关闭
+	failed   bool             // 最近一条命令重试后仍然失败
+
+	// 认证失败时重新读取密码，参数为失败时使用的密码，返回true表示密码已更新、应重试
+	reloadPassword func(ctx context.Context, failed string) bool
+
+	// 会话控制
+	lastUsed time.Time  // 上次使用时间
+	mutex    sync.Mutex // 互斥锁，保护执行器状态
+
+	// 重连控制
+	maxRetries    int           // 最大重试次数
+	retryDelay    time.Duration // 重试延迟基准时间
+	maxRetryDelay time.Duration // 最大重试延迟
+}
+
+// defaultRconRetryDelay RCON命令失败后重连重试的默认延迟基准时间，之后每次重试延迟增加到1.5倍
+const defaultRconRetryDelay = 500 * time.Millisecond
+
+// newRconExecutor 创建一个使用独立连接池的RCON执行器
+func newRconExecutor(serverIP string, port int, password string) *rconExecutor {
+	timeouts := rconTimeouts{
+		dial:  defaultRconDialTimeout,
+		read:  defaultRconReadTimeout,
+		write: defaultRconWriteTimeout,
+	}
+	options := DefaultRconPoolOptions()
+	options.MaxSize = 1
+	pool := newRconPool(net.JoinHostPort(serverIP, strconv.Itoa(port)), password, timeouts, options)
+
+	executor := newPooledRconExecutor(func() *rconPool { return pool }, defaultRconRetryDelay)
+	executor.ownsPool = true
+	return executor
+}
+
+// newPooledRconExecutor 创建一个使用共享连接池的RCON执行器，retryDelay为重试延迟基准时间
+func newPooledRconExecutor(pool func() *rconPool, retryDelay time.Duration) *rconExecutor {
+	return &rconExecutor{
+		pool:          pool,
+		maxRetries:    5,
+		retryDelay:    retryDelay,
+		maxRetryDelay: 10 * time.Second,
+	}
+}
+
+// SetRconTimeouts 设置RCON连接的读写超时，当前的连接池会被关闭，下次执行命令时按新的超时重新建立
+// read为有命令等待响应时两次收到数据之间的最长间隔，write为写入一条命令的超时，为0则不限制
+func (m *MinecraftController) SetRconTimeouts(read, write time.Duration) {
+	m.rconMutex.Lock()
+	m.rconReadTimeout = read
+	m.rconWriteTimeout = write
+	m.rconMutex.Unlock()
+
+	m.closeRconPool()
+}
+
+// Connect 检查能否连接到RCON服务器并通过认证，建立的连接放入连接池供之后的命令使用
+func (e *rconExecutor) Connect() error {
+	ctx := context.Background()
+	pool := e.pool()
+	err := pool.warm(ctx)
+	if e.passwordReloaded(ctx, pool, err) {
+		err = e.pool().warm(ctx)
+	}
+	if err != nil {
+		if errors.Is(err, ErrRconAuthFailed) {
+			return err
+		}
+		return fmt.Errorf("连接RCON失败: %w", err)
+	}
+
+	e.mutex.Lock()
+	e.failed = false
+	e.mutex.Unlock()
+	return nil
+}
+
+// ExecuteCommand 执行RCON命令，包含重连逻辑
+func (e *rconExecutor) ExecuteCommand(cmd string) (string, error) {
+	return e.ExecuteCommandContext(context.Background(), cmd)
+}
+
+// ExecuteCommandContext 执行RCON命令，包含重连逻辑
+// 借出连接失败时命令还未发送，重连后重试；命令发送后失败（如等待响应超时、连接断开）时命令可能已经执行，
+// 只重试只读的查询命令（见readOnlyCommand），其他命令返回错误，由调用方决定是否重新执行。
+// 上下文取消时立即中止正在执行的命令和重试等待
+func (e *rconExecutor) ExecuteCommandContext(ctx context.Context, cmd string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// 更新最后使用时间
+	e.mutex.Lock()
+	e.lastUsed = time.Now()
+	e.mutex.Unlock()
+
+	// 执行命令，带重试逻辑
+	var lastErr error
+	for retryCount := 0; ; retryCount++ {
+		if retryCount > 0 {
+			// 计算本次重试延迟，等待后重试连接
+			delay := time.Duration(float64(e.retryDelay) * math.Pow(1.5, float64(retryCount-1)))
+			if delay > e.maxRetryDelay {
+				delay = e.maxRetryDelay
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		// 从连接池借出连接，没有可用连接时建立新连接
+		conn, err := e.getConn(ctx)
+		if err != nil {
+			// 首次连接失败、密码错误时不重试
+			if retryCount == 0 || ctx.Err() != nil || errors.Is(err, ErrRconAuthFailed) {
+				if ctx.Err() != nil {
+					return "", err
+				}
+				e.setFailed(true)
+				if !errors.Is(err, ErrRconAuthFailed) {
+					err = fmt.Errorf("连接RCON失败: %w", err)
+				}
+				return "", err
+			}
+			lastErr = err
+		} else {
+			// 命令失败时连接已被关闭，归还时不会放回连接池
+			response, err := conn.client.Command(ctx, cmd)
+			conn.pool.put(conn)
+			if err == nil {
+				e.setFailed(false)
+				return response, nil // 命令执行成功
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return "", ctxErr
+			}
+			if errors.Is(err, errRconCommandTooLong) {
+				return "", err
+			}
+			if !readOnlyCommand(cmd) {
+				e.setFailed(true)
+				return "", fmt.Errorf("RCON命令执行失败: %w", err)
+			}
+			lastErr = err
+		}
+
+		// 如果已经是最后一次重试，则返回错误
+		if retryCount == e.maxRetries {
+			e.setFailed(true)
+			return "", fmt.Errorf("RCON命令执行失败，已尝试重连%d次: %v", retryCount, lastErr)
+		}
+	}
+}
+
+// getConn 从连接池借出连接，认证失败且密码已更新时使用新密码的连接池重试一次
+func (e *rconExecutor) getConn(ctx context.Context) (*pooledRconConn, error) {
+	pool := e.pool()
+	conn, err := pool.get(ctx)
+	if e.passwordReloaded(ctx, pool, err) {
+		return e.pool().get(ctx)
+	}
+	return conn, err
+}
+
+// passwordReloaded 连接池认证失败时重新读取密码，返回密码是否已更新
+func (e *rconExecutor) passwordReloaded(ctx context.Context, pool *rconPool, err error) bool {
+	if !errors.Is(err, ErrRconAuthFailed) || e.reloadPassword == nil {
+		return false
+	}
+	return e.reloadPassword(ctx, pool.password)
+}
+
+// setFailed 记录最近一条命令是否在重试后仍然失败
+func (e *rconExecutor) setFailed(failed bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.failed = failed
+}
+
+// Disconnect 断开与RCON服务器的连接
+// 共享连接池中的连接由控制器管理，只有执行器自己创建的连接池会被关闭
+func (e *rconExecutor) Disconnect() {
+	if e.ownsPool {
+		e.pool().close()
+	}
+}
+
+// IsConnected 检查是否已连接
+// 共享连接池的执行器在最近一条命令重试后仍然失败时视为断开，供会话切换到其他执行器
+func (e *rconExecutor) IsConnected() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return !e.failed
+}
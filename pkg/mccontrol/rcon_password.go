@@ -0,0 +1,324 @@
+package mccontrol
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rconPasswordReloadInterval 认证失败后重新读取密码的最小间隔，避免密码确实错误时频繁访问API Server
+const rconPasswordReloadInterval = 10 * time.Second
+
+// rconPasswordProperty server.properties中RCON密码的键名
+const rconPasswordProperty = "rcon.password"
+
+// rconPasswordSource RCON密码的来源配置
+type rconPasswordSource struct {
+	secretName     string // Secret名称
+	secretKey      string // Secret中的键名
+	envName        string // 容器环境变量名称
+	propertiesPath string // 容器内server.properties的路径
+	static         string // 直接传入的密码
+}
+
+// dynamic 是否配置了可以重新读取的密码来源
+func (s rconPasswordSource) dynamic() bool {
+	return s.secretName != "" || s.envName != "" || s.propertiesPath != ""
+}
+
+// ReloadRconPassword 立即从配置的来源重新读取RCON密码，密码变化后下次执行命令时按新密码重新建立连接池
+func (m *MinecraftController) ReloadRconPassword(ctx context.Context) error {
+	m.rconPasswordMutex.Lock()
+	defer m.rconPasswordMutex.Unlock()
+
+	m.rconPasswordCheckedAt = time.Now()
+	password, err := m.resolveRconPassword(ctx)
+	if err != nil {
+		return err
+	}
+	m.setRconPassword(password)
+	return nil
+}
+
+// reloadRconPassword 认证失败后重新读取RCON密码，返回是否应使用新密码重试
+// failed为认证失败的连接池使用的密码，其他命令已经更新过密码时直接返回true
+func (m *MinecraftController) reloadRconPassword(ctx context.Context, failed string) bool {
+	m.rconPasswordMutex.Lock()
+	defer m.rconPasswordMutex.Unlock()
+
+	if m.getRconPassword() != failed {
+		return true
+	}
+	if !m.rconPasswordSource.dynamic() || time.Since(m.rconPasswordCheckedAt) < rconPasswordReloadInterval {
+		return false
+	}
+
+	m.rconPasswordCheckedAt = time.Now()
+	password, err := m.resolveRconPassword(ctx)
+	if err != nil || password == failed {
+		return false
+	}
+	m.setRconPassword(password)
+	return true
+}
+
+// resolveRconPassword 按Secret、容器环境变量、server.properties的顺序读取RCON密码
+// 都未配置时使用直接传入的密码；配置的来源都读取失败时返回所有来源的错误
+func (m *MinecraftController) resolveRconPassword(ctx context.Context) (string, error) {
+	source := m.rconPasswordSource
+	if !source.dynamic() {
+		return source.static, nil
+	}
+
+	var errs []error
+	if source.secretName != "" {
+		password, err := readSecretValue(m.clientset, m.namespace, source.secretName, source.secretKey)
+		if err == nil {
+			return password, nil
+		}
+		errs = append(errs, err)
+	}
+	if source.envName != "" {
+		password, err := m.readContainerEnv(ctx, source.envName)
+		if err == nil {
+			return password, nil
+		}
+		errs = append(errs, err)
+	}
+	if source.propertiesPath != "" {
+		password, err := m.readServerProperty(ctx, source.propertiesPath, rconPasswordProperty)
+		if err == nil {
+			return password, nil
+		}
+		errs = append(errs, err)
+	}
+	if source.static != "" {
+		return source.static, nil
+	}
+	return "", fmt.Errorf("读取RCON密码失败: %w", errors.Join(errs...))
+}
+
+// getRconPassword 获取当前使用的RCON密码
+func (m *MinecraftController) getRconPassword() string {
+	m.rconMutex.Lock()
+	defer m.rconMutex.Unlock()
+
+	return m.rconPassword
+}
+
+// setRconPassword 更新RCON密码，连接池在下次使用时按新密码重新建立
+func (m *MinecraftController) setRconPassword(password string) {
+	m.rconMutex.Lock()
+	defer m.rconMutex.Unlock()
+
+	m.rconPassword = password
+}
+
+// readContainerEnv 从当前Pod的容器定义中读取环境变量
+// 支持直接设置的值、valueFrom引用的Secret和ConfigMap，以及envFrom导入的Secret和ConfigMap
+func (m *MinecraftController) readContainerEnv(ctx context.Context, name string) (string, error) {
+	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
+		return "", fmt.Errorf("更新Pod信息失败: %v", err)
+	}
+
+	podName := m.podName()
+	pod, err := m.clientset.CoreV1().Pods(m.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("获取Pod '%s' 失败: %v", podName, err)
+	}
+
+	container := findContainer(pod, m.containerName)
+	if container == nil {
+		return "", fmt.Errorf("Pod '%s' 中不存在容器 '%s'", pod.Name, m.containerName)
+	}
+
+	// 后定义的env覆盖先定义的，env覆盖envFrom
+	for i := len(container.Env) - 1; i >= 0; i-- {
+		env := container.Env[i]
+		if env.Name != name {
+			continue
+		}
+		if env.ValueFrom == nil {
+			return env.Value, nil
+		}
+		switch {
+		case env.ValueFrom.SecretKeyRef != nil:
+			ref := env.ValueFrom.SecretKeyRef
+			return readSecretValue(m.clientset, m.namespace, ref.Name, ref.Key)
+		case env.ValueFrom.ConfigMapKeyRef != nil:
+			ref := env.ValueFrom.ConfigMapKeyRef
+			return m.readConfigMapValue(ctx, ref.Name, ref.Key)
+		default:
+			return "", fmt.Errorf("环境变量 '%s' 的来源不受支持", name)
+		}
+	}
+
+	for i := len(container.EnvFrom) - 1; i >= 0; i-- {
+		envFrom := container.EnvFrom[i]
+		if !strings.HasPrefix(name, envFrom.Prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, envFrom.Prefix)
+		switch {
+		case envFrom.SecretRef != nil:
+			secret, err := m.clientset.CoreV1().Secrets(m.namespace).Get(ctx, envFrom.SecretRef.Name, metav1.GetOptions{})
+			if err != nil {
+				continue
+			}
+			if value, ok := secret.Data[key]; ok {
+				return string(value), nil
+			}
+		case envFrom.ConfigMapRef != nil:
+			if value, err := m.readConfigMapValue(ctx, envFrom.ConfigMapRef.Name, key); err == nil {
+				return value, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("容器 '%s' 中未定义环境变量 '%s'", container.Name, name)
+}
+
+// readConfigMapValue 从ConfigMap中读取指定键的值
+func (m *MinecraftController) readConfigMapValue(ctx context.Context, name, key string) (string, error) {
+	configMap, err := m.clientset.CoreV1().ConfigMaps(m.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("获取ConfigMap '%s' 失败: %v", name, err)
+	}
+	value, ok := configMap.Data[key]
+	if !ok {
+		return "", fmt.Errorf("ConfigMap '%s' 中不存在键 '%s'", name, key)
+	}
+	return value, nil
+}
+
+// readServerProperty 读取容器内server.properties中的配置项
+func (m *MinecraftController) readServerProperty(ctx context.Context, path, key string) (string, error) {
+	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
+		return "", fmt.Errorf("更新Pod信息失败: %v", err)
+	}
+
+	executor := newExecExecutor(m.clientset, m.restConfig, m.namespace, m.podName(), m.containerName)
+	escapedPath := strings.Replace(path, "'", "'\\''", -1)
+	content, err := executor.executeViaDirectExec(ctx, fmt.Sprintf("cat '%s'", escapedPath))
+	if err != nil {
+		return "", fmt.Errorf("读取 %s 失败: %v", path, err)
+	}
+
+	value, ok := parseServerProperties(content)[key]
+	if !ok {
+		return "", fmt.Errorf("%s 中不存在配置项 '%s'", path, key)
+	}
+	return value, nil
+}
+
+// findContainer 按名称查找Pod中的容器，名称为空时返回第一个容器
+func findContainer(pod *corev1.Pod, name string) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if name == "" || pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// parseServerProperties 解析server.properties（Java properties格式）
+// Minecraft服务器使用java.util.Properties保存配置，值中的特殊字符会以反斜杠转义
+func parseServerProperties(content string) map[string]string {
+	properties := make(map[string]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	var logical strings.Builder
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// 以奇数个反斜杠结尾的行与下一行相连
+		trailing := len(line) - len(strings.TrimRight(line, "\\"))
+		if trailing%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+
+		key, value := splitProperty(logical.String())
+		properties[key] = value
+		logical.Reset()
+	}
+	if logical.Len() > 0 {
+		key, value := splitProperty(logical.String())
+		properties[key] = value
+	}
+	return properties
+}
+
+// splitProperty 将一行配置拆分为键和值，分隔符为第一个未转义的=、:或空白
+func splitProperty(line string) (string, string) {
+	separator := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' || line[i] == '\f' {
+			separator = i
+			break
+		}
+	}
+
+	key := line[:separator]
+	rest := line[separator:]
+	rest = strings.TrimLeft(rest, " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	return unescapeProperty(key), unescapeProperty(rest)
+}
+
+// unescapeProperty 处理Java properties的转义序列
+func unescapeProperty(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' || i+1 >= len(value) {
+			builder.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			if i+4 < len(value) {
+				if code, err := strconv.ParseUint(value[i+1:i+5], 16, 16); err == nil {
+					builder.WriteRune(rune(code))
+					i += 4
+					continue
+				}
+			}
+			builder.WriteByte('u')
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
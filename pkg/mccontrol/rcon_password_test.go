@@ -0,0 +1,130 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseServerProperties(t *testing.T) {
+	content := "#Minecraft server properties\n" +
+		"#Thu Jan 01 00:00:00 UTC 2026\n" +
+		"enable-rcon=true\n" +
+		"rcon.port = 25575\n" +
+		"rcon.password=p\\=ss\\:w\\\\rd\n" +
+		"motd=A \\u00A7aMinecraft Server\n" +
+		"  ! 注释\n" +
+		"level-name:world\n" +
+		"long-value=first \\\n" +
+		"    second\n" +
+		"empty-value=\n"
+
+	properties := parseServerProperties(content)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"enable-rcon", "true"},
+		{"rcon.port", "25575"},
+		{"rcon.password", "p=ss:w\\rd"},
+		{"motd", "A §aMinecraft Server"},
+		{"level-name", "world"},
+		{"long-value", "first second"},
+		{"empty-value", ""},
+	}
+	for _, tt := range tests {
+		got, ok := properties[tt.key]
+		if !ok {
+			t.Errorf("缺少配置项 %s", tt.key)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, 期望 %q", tt.key, got, tt.want)
+		}
+	}
+	if len(properties) != len(tests) {
+		t.Errorf("注释不应被解析为配置项, 实际 %v", properties)
+	}
+}
+
+// rotatingRconPools 模拟控制器按当前密码提供连接池
+type rotatingRconPools struct {
+	t        *testing.T
+	server   *fakeRconServer
+	mutex    sync.Mutex
+	password string
+	pool     *rconPool
+}
+
+func (p *rotatingRconPools) get() *rconPool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.pool == nil || p.pool.password != p.password {
+		if p.pool != nil {
+			p.pool.close()
+		}
+		p.pool = newRconPool(p.server.addr(), p.password, rconTimeouts{read: 2 * time.Second}, RconPoolOptions{MaxSize: 1})
+		p.t.Cleanup(p.pool.close)
+	}
+	return p.pool
+}
+
+func (p *rotatingRconPools) setPassword(password string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.password = password
+}
+
+func TestRconExecutorReloadsPasswordOnAuthFailure(t *testing.T) {
+	server := newFakeRconServer(t, "rotated", echoHandler)
+	pools := &rotatingRconPools{t: t, server: server, password: "stale"}
+
+	var reloads []string
+	executor := newPooledRconExecutor(pools.get, time.Millisecond)
+	executor.reloadPassword = func(ctx context.Context, failed string) bool {
+		reloads = append(reloads, failed)
+		pools.setPassword("rotated")
+		return true
+	}
+
+	response, err := executor.ExecuteCommandContext(context.Background(), "list")
+	if err != nil {
+		t.Fatalf("密码更新后应重试成功, 实际 %v", err)
+	}
+	if response != "list" {
+		t.Errorf("响应 = %q, 期望 %q", response, "list")
+	}
+	if len(reloads) != 1 || reloads[0] != "stale" {
+		t.Errorf("应使用失败的密码重新读取一次, 实际 %v", reloads)
+	}
+
+	// 密码正确后不再重新读取
+	if _, err := executor.ExecuteCommandContext(context.Background(), "list"); err != nil {
+		t.Fatalf("执行命令失败: %v", err)
+	}
+	if len(reloads) != 1 {
+		t.Errorf("认证成功时不应重新读取密码, 实际读取%d次", len(reloads))
+	}
+}
+
+func TestRconExecutorPasswordUnchanged(t *testing.T) {
+	server := newFakeRconServer(t, "rotated", echoHandler)
+	pools := &rotatingRconPools{t: t, server: server, password: "stale"}
+
+	executor := newPooledRconExecutor(pools.get, time.Millisecond)
+	executor.reloadPassword = func(ctx context.Context, failed string) bool {
+		return false
+	}
+
+	if err := executor.Connect(); !errors.Is(err, ErrRconAuthFailed) {
+		t.Fatalf("密码未更新时应返回ErrRconAuthFailed, 实际 %v", err)
+	}
+	if accepted := server.accepted.Load(); accepted != 1 {
+		t.Errorf("密码未更新时不应重试, 实际建立了%d个连接", accepted)
+	}
+}
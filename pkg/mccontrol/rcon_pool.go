@@ -0,0 +1,322 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// errRconPoolClosed 连接池已关闭（服务器地址或配置变更、控制器关闭）
+var errRconPoolClosed = errors.New("RCON连接池已关闭")
+
+// RconPoolOptions RCON连接池配置
+type RconPoolOptions struct {
+	MaxSize             int           // 最多同时使用的连接数，超过时等待其他命令释放连接
+	IdleTimeout         time.Duration // 空闲超过该时间的连接被关闭，为0则不限制
+	MaxLifetime         time.Duration // 连接建立超过该时间后不再复用，为0则不限制
+	HealthCheckInterval time.Duration // 检查空闲连接是否可用的间隔，为0则不检查
+}
+
+// DefaultRconPoolOptions 默认的RCON连接池配置
+func DefaultRconPoolOptions() RconPoolOptions {
+	return RconPoolOptions{
+		MaxSize:             4,
+		IdleTimeout:         5 * time.Minute,
+		MaxLifetime:         30 * time.Minute,
+		HealthCheckInterval: 30 * time.Second,
+	}
+}
+
+// RconPoolStats RCON连接池的统计信息
+type RconPoolStats struct {
+	Address string `json:"address"`  // RCON服务器地址
+	MaxSize int    `json:"max_size"` // 最多同时使用的连接数
+	InUse   int    `json:"in_use"`   // 正在使用的连接数
+	Idle    int    `json:"idle"`     // 空闲连接数
+}
+
+// pooledRconConn 连接池中的连接
+type pooledRconConn struct {
+	pool      *rconPool // 借出连接的连接池，连接池被替换后仍归还到原来的连接池
+	client    *rconClient
+	createdAt time.Time // 建立时间
+	lastUsed  time.Time // 最后一次归还的时间
+}
+
+// rconPool 已认证的RCON连接池
+// 命令执行时借出一个连接，执行结束后归还；连接断开、超过最长存活时间或空闲超时的连接会被关闭，
+// 后台定期向空闲连接发送探测包，提前发现已失效的连接
+type rconPool struct {
+	address  string
+	password string
+	timeouts rconTimeouts
+	options  RconPoolOptions
+
+	slots chan struct{} // 容量为MaxSize，借出连接时占用一个位置
+
+	mutex  sync.Mutex
+	idle   []*pooledRconConn // 空闲连接，最近归还的在末尾
+	closed bool
+	stop   chan struct{}
+}
+
+// newRconPool 创建RCON连接池，并在需要时启动后台健康检查
+func newRconPool(address, password string, timeouts rconTimeouts, options RconPoolOptions) *rconPool {
+	if options.MaxSize <= 0 {
+		options.MaxSize = 1
+	}
+
+	p := &rconPool{
+		address:  address,
+		password: password,
+		timeouts: timeouts,
+		options:  options,
+		slots:    make(chan struct{}, options.MaxSize),
+		stop:     make(chan struct{}),
+	}
+	if options.HealthCheckInterval > 0 {
+		go p.maintain()
+	}
+	return p
+}
+
+// get 借出一个可用的连接，没有空闲连接时建立新连接
+// 所有连接都在使用中时等待，直到有连接归还或上下文取消
+func (p *rconPool) get(ctx context.Context) (*pooledRconConn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		p.mutex.Lock()
+		if p.closed {
+			p.mutex.Unlock()
+			<-p.slots
+			return nil, errRconPoolClosed
+		}
+		if len(p.idle) == 0 {
+			p.mutex.Unlock()
+			break
+		}
+		conn := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mutex.Unlock()
+
+		if p.expired(conn, time.Now()) || !conn.client.Alive() {
+			conn.client.Close()
+			continue
+		}
+		return conn, nil
+	}
+
+	client, err := dialRcon(ctx, p.address, p.password, p.timeouts)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	now := time.Now()
+	return &pooledRconConn{pool: p, client: client, createdAt: now, lastUsed: now}, nil
+}
+
+// put 归还借出的连接，已断开或超过最长存活时间的连接直接关闭
+func (p *rconPool) put(conn *pooledRconConn) {
+	defer func() { <-p.slots }()
+
+	now := time.Now()
+	conn.lastUsed = now
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed || len(p.idle) >= p.options.MaxSize || p.expired(conn, now) || !conn.client.Alive() {
+		conn.client.Close()
+		return
+	}
+	p.idle = append(p.idle, conn)
+}
+
+// warm 确保连接池能够建立连接：已有空闲连接时直接返回，否则建立一个连接放入连接池
+func (p *rconPool) warm(ctx context.Context) error {
+	p.mutex.Lock()
+	for _, conn := range p.idle {
+		if conn.client.Alive() {
+			p.mutex.Unlock()
+			return nil
+		}
+	}
+	p.mutex.Unlock()
+
+	conn, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+	p.put(conn)
+	return nil
+}
+
+// close 关闭连接池和所有空闲连接，借出的连接在归还时关闭
+func (p *rconPool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.stop)
+
+	for _, conn := range p.idle {
+		conn.client.Close()
+	}
+	p.idle = nil
+}
+
+// stats 返回连接池的统计信息
+func (p *rconPool) stats() RconPoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return RconPoolStats{
+		Address: p.address,
+		MaxSize: p.options.MaxSize,
+		InUse:   len(p.slots),
+		Idle:    len(p.idle),
+	}
+}
+
+// maintain 定期检查空闲连接，直到连接池关闭
+func (p *rconPool) maintain() {
+	ticker := time.NewTicker(p.options.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkIdle()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// checkIdle 关闭空闲超时、超过最长存活时间或探测失败的空闲连接
+// 检查期间连接不在空闲列表中，不会被借出
+func (p *rconPool) checkIdle() {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mutex.Unlock()
+
+	now := time.Now()
+	healthy := make([]*pooledRconConn, 0, len(idle))
+	for _, conn := range idle {
+		if p.expired(conn, now) || !p.ping(conn) {
+			conn.client.Close()
+			continue
+		}
+		healthy = append(healthy, conn)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// 检查期间可能有新的连接归还，保留最近使用的连接
+	p.idle = append(healthy, p.idle...)
+	if p.closed {
+		for _, conn := range p.idle {
+			conn.client.Close()
+		}
+		p.idle = nil
+		return
+	}
+	for len(p.idle) > p.options.MaxSize {
+		p.idle[0].client.Close()
+		p.idle = p.idle[1:]
+	}
+}
+
+// ping 探测连接是否仍然可用
+func (p *rconPool) ping(conn *pooledRconConn) bool {
+	timeout := p.timeouts.read
+	if timeout <= 0 {
+		timeout = defaultRconReadTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return conn.client.Ping(ctx) == nil
+}
+
+// expired 检查连接是否空闲超时或超过最长存活时间
+func (p *rconPool) expired(conn *pooledRconConn, now time.Time) bool {
+	if p.options.MaxLifetime > 0 && now.Sub(conn.createdAt) > p.options.MaxLifetime {
+		return true
+	}
+	return p.options.IdleTimeout > 0 && now.Sub(conn.lastUsed) > p.options.IdleTimeout
+}
+
+// SetRconPoolOptions 设置RCON连接池配置，当前的连接池会被关闭，下次执行命令时按新配置重新建立
+func (m *MinecraftController) SetRconPoolOptions(options RconPoolOptions) {
+	m.rconMutex.Lock()
+	m.rconPoolOptions = options
+	pool := m.rconPool
+	m.rconPool = nil
+	m.rconMutex.Unlock()
+
+	if pool != nil {
+		pool.close()
+	}
+}
+
+// GetRconPoolStats 获取RCON连接池的统计信息，尚未建立连接池时返回false
+func (m *MinecraftController) GetRconPoolStats() (RconPoolStats, bool) {
+	m.rconMutex.Lock()
+	pool := m.rconPool
+	m.rconMutex.Unlock()
+
+	if pool == nil {
+		return RconPoolStats{}, false
+	}
+	return pool.stats(), true
+}
+
+// getRconPool 获取当前服务器地址的RCON连接池
+// Pod重建后服务器IP会变化，此时关闭旧的连接池并为新地址建立连接池
+func (m *MinecraftController) getRconPool() *rconPool {
+	address := net.JoinHostPort(m.podIP(), strconv.Itoa(m.rconPort))
+
+	m.rconMutex.Lock()
+	defer m.rconMutex.Unlock()
+
+	if m.rconPool != nil && m.rconPool.address == address && m.rconPool.password == m.rconPassword {
+		return m.rconPool
+	}
+	if m.rconPool != nil {
+		m.rconPool.close()
+	}
+
+	timeouts := rconTimeouts{
+		dial:  defaultRconDialTimeout,
+		read:  m.rconReadTimeout,
+		write: m.rconWriteTimeout,
+	}
+	m.rconPool = newRconPool(address, m.rconPassword, timeouts, m.rconPoolOptions)
+	return m.rconPool
+}
+
+// closeRconPool 关闭RCON连接池
+func (m *MinecraftController) closeRconPool() {
+	m.rconMutex.Lock()
+	pool := m.rconPool
+	m.rconPool = nil
+	m.rconMutex.Unlock()
+
+	if pool != nil {
+		pool.close()
+	}
+}
@@ -0,0 +1,192 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRconPool 创建连接到测试服务器的连接池，测试结束时关闭
+func newTestRconPool(t *testing.T, server *fakeRconServer, options RconPoolOptions) *rconPool {
+	t.Helper()
+
+	pool := newRconPool(server.addr(), server.password, rconTimeouts{read: 2 * time.Second}, options)
+	t.Cleanup(pool.close)
+	return pool
+}
+
+// poolCommand 借出连接执行一条命令后归还
+func poolCommand(pool *rconPool, command string) (string, error) {
+	conn, err := pool.get(context.Background())
+	if err != nil {
+		return "", err
+	}
+	defer pool.put(conn)
+
+	return conn.client.Command(context.Background(), command)
+}
+
+func TestRconPoolReusesConnections(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	pool := newTestRconPool(t, server, RconPoolOptions{MaxSize: 2})
+
+	for i := 0; i < 10; i++ {
+		if _, err := poolCommand(pool, "list"); err != nil {
+			t.Fatalf("执行命令失败: %v", err)
+		}
+	}
+
+	if accepted := server.accepted.Load(); accepted != 1 {
+		t.Errorf("顺序执行的命令应复用同一个连接, 实际建立了%d个连接", accepted)
+	}
+	if stats := pool.stats(); stats.Idle != 1 || stats.InUse != 0 {
+		t.Errorf("统计信息不正确: %+v", stats)
+	}
+}
+
+func TestRconPoolBounded(t *testing.T) {
+	const maxSize = 2
+
+	var active, peak atomic.Int32
+	server := newFakeRconServer(t, "secret", func(command string) string {
+		current := active.Add(1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		active.Add(-1)
+		return command
+	})
+	pool := newTestRconPool(t, server, RconPoolOptions{MaxSize: maxSize})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := poolCommand(pool, "list"); err != nil {
+				t.Errorf("执行命令失败: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted := server.accepted.Load(); accepted > maxSize {
+		t.Errorf("连接数不应超过%d, 实际建立了%d个连接", maxSize, accepted)
+	}
+	if peak.Load() > maxSize {
+		t.Errorf("同时执行的命令不应超过%d, 实际%d", maxSize, peak.Load())
+	}
+}
+
+func TestRconPoolWaitHonorsContext(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	pool := newTestRconPool(t, server, RconPoolOptions{MaxSize: 1})
+
+	conn, err := pool.get(context.Background())
+	if err != nil {
+		t.Fatalf("借出连接失败: %v", err)
+	}
+	defer pool.put(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("连接全部借出时应等待到上下文超时, 实际 %v", err)
+	}
+}
+
+func TestRconPoolIdleTimeoutAndLifetime(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+
+	tests := []struct {
+		name    string
+		options RconPoolOptions
+	}{
+		{"空闲超时", RconPoolOptions{MaxSize: 1, IdleTimeout: 20 * time.Millisecond}},
+		{"最长存活时间", RconPoolOptions{MaxSize: 1, MaxLifetime: 20 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newTestRconPool(t, server, tt.options)
+			before := server.accepted.Load()
+
+			if _, err := poolCommand(pool, "list"); err != nil {
+				t.Fatalf("执行命令失败: %v", err)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if _, err := poolCommand(pool, "list"); err != nil {
+				t.Fatalf("执行命令失败: %v", err)
+			}
+
+			if accepted := server.accepted.Load() - before; accepted != 2 {
+				t.Errorf("过期的连接不应被复用, 期望建立2个连接, 实际%d", accepted)
+			}
+		})
+	}
+}
+
+func TestRconPoolHealthCheck(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	pool := newTestRconPool(t, server, RconPoolOptions{MaxSize: 2})
+
+	if _, err := poolCommand(pool, "list"); err != nil {
+		t.Fatalf("执行命令失败: %v", err)
+	}
+
+	// 健康的空闲连接保留在连接池中
+	pool.checkIdle()
+	if stats := pool.stats(); stats.Idle != 1 {
+		t.Fatalf("健康的连接应保留, 实际 %+v", stats)
+	}
+
+	// 服务器断开后，空闲连接被检查移除
+	server.dropConnections()
+	pool.checkIdle()
+	if stats := pool.stats(); stats.Idle != 0 {
+		t.Fatalf("已断开的连接应被移除, 实际 %+v", stats)
+	}
+
+	if _, err := poolCommand(pool, "list"); err != nil {
+		t.Fatalf("移除失效连接后执行命令失败: %v", err)
+	}
+}
+
+func TestRconPoolClose(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	pool := newTestRconPool(t, server, RconPoolOptions{MaxSize: 1})
+
+	conn, err := pool.get(context.Background())
+	if err != nil {
+		t.Fatalf("借出连接失败: %v", err)
+	}
+	pool.close()
+
+	// 关闭后归还的连接被关闭，不能再借出连接
+	pool.put(conn)
+	if conn.client.Alive() {
+		t.Error("连接池关闭后归还的连接应被关闭")
+	}
+	if _, err := pool.get(context.Background()); !errors.Is(err, errRconPoolClosed) {
+		t.Errorf("期望errRconPoolClosed, 实际 %v", err)
+	}
+}
+
+func TestRconClientPing(t *testing.T) {
+	server := newFakeRconServer(t, "secret", echoHandler)
+	client := dialTestRcon(t, server, rconTimeouts{read: time.Second})
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("探测失败: %v", err)
+	}
+	if received := server.received.Load(); received != 0 {
+		t.Errorf("探测不应作为命令执行, 服务器收到%d条命令", received)
+	}
+}
@@ -0,0 +1,361 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bytedance/sonic"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrRestartInProgress 服务器正在重启
+var ErrRestartInProgress = errors.New("服务器正在重启")
+
+// RestartMode 表示重建服务器Pod的方式
+type RestartMode string
+
+const (
+	// RestartDeletePod 删除Pod，由所属的StatefulSet或Deployment重新创建
+	RestartDeletePod RestartMode = "delete_pod"
+
+	// RestartRollout 滚动重启Pod所属的StatefulSet或Deployment，与kubectl rollout restart相同
+	RestartRollout RestartMode = "rollout"
+)
+
+// RestartPhase 表示重启进行到的阶段
+type RestartPhase string
+
+const (
+	RestartPhaseCountdown   RestartPhase = "countdown"    // 向玩家发送倒计时提醒
+	RestartPhaseSaving      RestartPhase = "saving"       // 保存世界
+	RestartPhaseStopping    RestartPhase = "stopping"     // 停止服务器
+	RestartPhaseRecreating  RestartPhase = "recreating"   // 删除Pod或滚动重启工作负载
+	RestartPhaseWaitingPod  RestartPhase = "waiting_pod"  // 等待新Pod运行
+	RestartPhaseWaitingPing RestartPhase = "waiting_ping" // 等待服务器可以Ping通
+	RestartPhaseCompleted   RestartPhase = "completed"    // 重启完成
+	RestartPhaseFailed      RestartPhase = "failed"       // 重启失败
+)
+
+const (
+	defaultRestartStopTimeout  = time.Minute      // 默认等待服务器停止的时间
+	defaultRestartReadyTimeout = 10 * time.Minute // 默认等待新服务器可用的时间
+
+	// restartedAtAnnotation 滚动重启时修改的Pod模板注解，与kubectl rollout restart一致
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// restartPollInterval 等待期间检查Pod和服务器状态的间隔，测试中会调小
+var restartPollInterval = 2 * time.Second
+
+// restartWarningMarks 倒计时期间发送提醒的剩余时间点
+var restartWarningMarks = []time.Duration{
+	10 * time.Minute, 5 * time.Minute, time.Minute, 30 * time.Second, 10 * time.Second,
+	5 * time.Second, 4 * time.Second, 3 * time.Second, 2 * time.Second, time.Second,
+}
+
+// RestartOptions 重启服务器的选项
+type RestartOptions struct {
+	Countdown    time.Duration // 重启前的倒计时，期间在开始时以及剩余10分钟、5分钟、1分钟、30秒、10秒和最后5秒提醒玩家，为0则立即重启
+	Reason       string        // 重启原因，附加在提醒消息后
+	Mode         RestartMode   // 重建Pod的方式，为空则删除Pod
+	Force        bool          // 保存世界失败时是否仍然重启，默认中止重启以免丢失数据
+	StopTimeout  time.Duration // 发送stop后等待服务器停止的时间，超时后仍继续重建Pod，为0则使用默认值1分钟
+	ReadyTimeout time.Duration // 等待新Pod运行并且服务器可以Ping通的时间，为0则使用默认值10分钟
+
+	// OnCommand 重启过程中每条命令（提醒、save-all flush、stop）执行后调用，可以为nil，用于记录审计
+	OnCommand func(command string, result *CommandResult, err error)
+}
+
+// RestartServer 平滑重启服务器：向玩家发送倒计时提醒，执行save-all flush和stop，
+// 然后删除Pod（或滚动重启所属的StatefulSet/Deployment），等待新Pod运行并且服务器可以Ping通。
+// 各阶段的进度作为EventRestart事件发布给事件订阅者。
+// 同一时间只能进行一次重启，否则返回ErrRestartInProgress；上下文取消或控制器关闭时中止重启
+func (m *MinecraftController) RestartServer(ctx context.Context, options RestartOptions) error {
+	if !m.restarting.CompareAndSwap(false, true) {
+		return ErrRestartInProgress
+	}
+	defer m.restarting.Store(false)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
+	if err := m.restartServer(ctx, options); err != nil {
+		m.publishRestart(RestartPhaseFailed, err.Error())
+		return err
+	}
+	m.publishRestart(RestartPhaseCompleted, "服务器重启完成")
+	return nil
+}
+
+// Restarting 检查服务器是否正在重启
+func (m *MinecraftController) Restarting() bool {
+	return m.restarting.Load()
+}
+
+// restartServer 执行重启的各个阶段
+func (m *MinecraftController) restartServer(ctx context.Context, options RestartOptions) error {
+	mode := options.Mode
+	if mode == "" {
+		mode = RestartDeletePod
+	}
+	if mode != RestartDeletePod && mode != RestartRollout {
+		return fmt.Errorf("不支持的重启方式: %s", mode)
+	}
+	// 重启原因会拼接到say命令中，换行会被当作另一条命令执行
+	if strings.IndexFunc(options.Reason, unicode.IsControl) >= 0 {
+		return fmt.Errorf("重启原因不能包含换行等控制字符")
+	}
+	stopTimeout := options.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = defaultRestartStopTimeout
+	}
+	readyTimeout := options.ReadyTimeout
+	if readyTimeout <= 0 {
+		readyTimeout = defaultRestartReadyTimeout
+	}
+
+	// 通知玩家之前先确认Pod可以被重建
+	if err := m.ForceUpdatePodInfo(); err != nil {
+		return err
+	}
+	podName := m.podName()
+	pod, err := m.clientset.CoreV1().Pods(m.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("获取Pod '%s' 失败: %v", podName, err)
+	}
+	var workload workloadRef
+	if mode == RestartRollout {
+		if workload, err = m.findWorkload(ctx, pod); err != nil {
+			return fmt.Errorf("%v，无法滚动重启", err)
+		}
+	} else if metav1.GetControllerOf(pod) == nil {
+		return fmt.Errorf("Pod '%s' 不属于任何控制器，删除后不会重新创建", pod.Name)
+	}
+
+	if err := m.restartCountdown(ctx, options); err != nil {
+		return err
+	}
+
+	m.publishRestart(RestartPhaseSaving, "正在保存世界")
+	if err := m.restartCommand(ctx, options, "save-all flush"); err != nil {
+		if !options.Force {
+			return fmt.Errorf("保存世界失败，已取消重启: %w", err)
+		}
+		m.publishRestart(RestartPhaseSaving, fmt.Sprintf("保存世界失败，强制继续重启: %v", err))
+	}
+
+	// 服务器停止时会断开RCON连接，stop命令本身可能返回错误；
+	// 即使stop未能执行，删除Pod时容器收到的SIGTERM通常也会使服务器正常停止
+	m.publishRestart(RestartPhaseStopping, "正在停止服务器")
+	if err := m.restartCommand(ctx, options, "stop"); err != nil {
+		m.publishRestart(RestartPhaseStopping, fmt.Sprintf("stop命令返回错误: %v", err))
+	}
+	if err := m.waitServerStopped(ctx, stopTimeout); err != nil {
+		return err
+	}
+
+	m.publishRestart(RestartPhaseRecreating, m.recreateMessage(mode, pod, workload))
+	if mode == RestartRollout {
+		err = m.rolloutRestart(ctx, workload)
+	} else {
+		err = m.clientset.CoreV1().Pods(m.namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil {
+			err = fmt.Errorf("删除Pod '%s' 失败: %v", pod.Name, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	readyCtx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+
+	m.publishRestart(RestartPhaseWaitingPod, "等待新Pod运行")
+	newPod, err := m.waitReplacementPod(readyCtx, pod.UID)
+	if err != nil {
+		return err
+	}
+	m.podInfoUpdateMutex.Lock()
+	m.setCurrentPod(newPod)
+	m.markPodInfoUpdated()
+	m.podInfoUpdateMutex.Unlock()
+
+	m.publishRestart(RestartPhaseWaitingPing, fmt.Sprintf("新Pod '%s' 已运行，等待服务器启动", newPod.Name))
+	return m.waitServerOnline(readyCtx)
+}
+
+// restartCountdown 倒计时并在各个时间点向玩家发送提醒
+func (m *MinecraftController) restartCountdown(ctx context.Context, options RestartOptions) error {
+	deadline := time.Now().Add(options.Countdown)
+	for _, remaining := range restartWarnings(options.Countdown) {
+		select {
+		case <-time.After(time.Until(deadline.Add(-remaining))):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		message := fmt.Sprintf("服务器将在%s后重启", formatCountdown(remaining))
+		if options.Reason != "" {
+			message += "：" + options.Reason
+		}
+		m.publishRestart(RestartPhaseCountdown, message)
+		m.warnPlayers(ctx, options, message, formatCountdown(remaining))
+	}
+
+	select {
+	case <-time.After(time.Until(deadline)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// warnPlayers 通过聊天消息和屏幕标题提醒玩家，提醒失败不影响重启
+func (m *MinecraftController) warnPlayers(ctx context.Context, options RestartOptions, message, remaining string) {
+	subtitle, _ := sonic.MarshalString(map[string]string{"text": remaining + "后", "color": "yellow"})
+	title, _ := sonic.MarshalString(map[string]string{"text": "服务器即将重启", "color": "red"})
+
+	// 副标题需要在标题之前设置，显示标题时一并显示
+	commands := []string{
+		"say " + message,
+		"title @a subtitle " + subtitle,
+		"title @a title " + title,
+	}
+	for _, command := range commands {
+		if err := m.restartCommand(ctx, options, command); err != nil {
+			m.publishRestart(RestartPhaseCountdown, fmt.Sprintf("发送提醒失败: %v", err))
+			return
+		}
+	}
+}
+
+// restartCommand 执行重启过程中的一条命令，执行后调用options.OnCommand
+func (m *MinecraftController) restartCommand(ctx context.Context, options RestartOptions, command string) error {
+	result, err := m.ExecuteCommandDetailed(ctx, command)
+	if options.OnCommand != nil {
+		options.OnCommand(command, result, err)
+	}
+	return err
+}
+
+// restartWarnings 返回倒计时期间发送提醒时的剩余时间，按时间先后排列
+func restartWarnings(countdown time.Duration) []time.Duration {
+	if countdown <= 0 {
+		return nil
+	}
+	warnings := []time.Duration{countdown}
+	for _, mark := range restartWarningMarks {
+		if mark < countdown {
+			warnings = append(warnings, mark)
+		}
+	}
+	return warnings
+}
+
+// formatCountdown 将剩余时间格式化为提醒消息中的文本
+func formatCountdown(d time.Duration) string {
+	seconds := int((d + time.Second - 1) / time.Second)
+	switch {
+	case seconds < 60:
+		return fmt.Sprintf("%d秒", seconds)
+	case seconds%60 == 0:
+		return fmt.Sprintf("%d分钟", seconds/60)
+	default:
+		return fmt.Sprintf("%d分%d秒", seconds/60, seconds%60)
+	}
+}
+
+// waitServerStopped 等待服务器停止响应Ping，超时后不返回错误，由重建Pod强制停止
+func (m *MinecraftController) waitServerStopped(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case <-time.After(restartPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if status, _ := m.CheckServerStatus(); !status.Online {
+			return nil
+		}
+	}
+
+	m.publishRestart(RestartPhaseStopping, fmt.Sprintf("服务器在%s内未停止，继续重建Pod", timeout))
+	return nil
+}
+
+// waitReplacementPod 等待匹配标签选择器的新Pod（UID与旧Pod不同）进入Running状态
+func (m *MinecraftController) waitReplacementPod(ctx context.Context, oldUID types.UID) (*corev1.Pod, error) {
+	for {
+		pods, err := m.clientset.CoreV1().Pods(m.namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: m.podLabelSelector,
+		})
+		if err == nil {
+			for i := range pods.Items {
+				pod := &pods.Items[i]
+				if pod.UID != oldUID && pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning {
+					return pod, nil
+				}
+			}
+		}
+
+		select {
+		case <-time.After(restartPollInterval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("等待新Pod运行超时: %w", ctx.Err())
+		}
+	}
+}
+
+// waitServerOnline 等待服务器可以Ping通
+func (m *MinecraftController) waitServerOnline(ctx context.Context) error {
+	for {
+		if status, _ := m.CheckServerStatus(); status.Online {
+			return nil
+		}
+
+		select {
+		case <-time.After(restartPollInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("等待服务器启动超时: %w", ctx.Err())
+		}
+	}
+}
+
+// rolloutRestart 修改工作负载的Pod模板注解，触发滚动重启
+func (m *MinecraftController) rolloutRestart(ctx context.Context, workload workloadRef) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		restartedAtAnnotation, time.Now().Format(time.RFC3339))
+
+	if err := m.patchWorkload(ctx, workload, types.StrategicMergePatchType, []byte(patch)); err != nil {
+		return fmt.Errorf("滚动重启%s失败: %v", workload, err)
+	}
+	return nil
+}
+
+// recreateMessage 返回重建Pod阶段的进度消息
+func (m *MinecraftController) recreateMessage(mode RestartMode, pod *corev1.Pod, workload workloadRef) string {
+	if mode == RestartRollout {
+		return fmt.Sprintf("正在滚动重启%s", workload)
+	}
+	return fmt.Sprintf("正在删除Pod '%s'", pod.Name)
+}
+
+// publishRestart 发布重启进度事件
+func (m *MinecraftController) publishRestart(phase RestartPhase, message string) {
+	m.events.emit(Event{
+		Type:    EventRestart,
+		Time:    time.Now(),
+		Source:  EventSourceRestart,
+		Phase:   phase,
+		Message: message,
+	})
+}
@@ -0,0 +1,331 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRestartWarnings(t *testing.T) {
+	tests := []struct {
+		countdown time.Duration
+		want      []time.Duration
+	}{
+		{0, nil},
+		{3 * time.Second, []time.Duration{3 * time.Second, 2 * time.Second, time.Second}},
+		{
+			90 * time.Second,
+			[]time.Duration{
+				90 * time.Second, time.Minute, 30 * time.Second, 10 * time.Second,
+				5 * time.Second, 4 * time.Second, 3 * time.Second, 2 * time.Second, time.Second,
+			},
+		},
+		{
+			5 * time.Minute,
+			[]time.Duration{
+				5 * time.Minute, time.Minute, 30 * time.Second, 10 * time.Second,
+				5 * time.Second, 4 * time.Second, 3 * time.Second, 2 * time.Second, time.Second,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := restartWarnings(tt.countdown); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("restartWarnings(%s) = %v, 期望 %v", tt.countdown, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCountdown(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{time.Second, "1秒"},
+		{1500 * time.Millisecond, "2秒"},
+		{30 * time.Second, "30秒"},
+		{time.Minute, "1分钟"},
+		{90 * time.Second, "1分30秒"},
+		{10 * time.Minute, "10分钟"},
+	}
+
+	for _, tt := range tests {
+		if got := formatCountdown(tt.d); got != tt.want {
+			t.Errorf("formatCountdown(%s) = %q, 期望 %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// fastRestartPolling 调小重启等待期间的检查间隔，测试结束时恢复
+func fastRestartPolling(t *testing.T) {
+	t.Helper()
+
+	previous := restartPollInterval
+	restartPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { restartPollInterval = previous })
+}
+
+// ownedServerPod 创建属于指定控制器的服务器Pod
+func ownedServerPod(name string, uid types.UID, kind, owner string) *corev1.Pod {
+	pod := testServerPod(name, "127.0.0.1")
+	pod.UID = uid
+	pod.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       owner,
+		Controller: func() *bool { b := true; return &b }(),
+	}}
+	return pod
+}
+
+// replacePodAfter 在ready返回true后创建运行中的新Pod，模拟控制器重建Pod
+func replacePodAfter(t *testing.T, clientset *fake.Clientset, pod *corev1.Pod, ready func() bool) {
+	t.Helper()
+
+	done := make(chan struct{})
+	t.Cleanup(func() { <-done })
+	go func() {
+		defer close(done)
+		deadline := time.Now().Add(5 * time.Second)
+		for !ready() {
+			if time.Now().After(deadline) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if _, err := clientset.CoreV1().Pods(testNamespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Errorf("创建新Pod失败: %v", err)
+		}
+	}()
+}
+
+// restartCommands 返回记录重启过程中执行的命令的OnCommand回调
+func restartCommands(commands *[]string) func(string, *CommandResult, error) {
+	return func(command string, result *CommandResult, err error) {
+		*commands = append(*commands, command)
+	}
+}
+
+func TestWaitReplacementPod(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	old := testServerPod("mc-0", "127.0.0.1")
+	old.UID = "uid-old"
+	controller, clientset := newTestController(t, rcon, old)
+	pods := clientset.CoreV1().Pods(testNamespace)
+
+	// 只有旧Pod时等待超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := controller.waitReplacementPod(ctx, old.UID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("没有新Pod时应等待超时: %v", err)
+	}
+
+	// 新Pod处于Pending状态时继续等待，进入Running后返回
+	pending := testServerPod("mc-1", "127.0.0.2")
+	pending.UID = "uid-new"
+	pending.Status.Phase = corev1.PodPending
+	if _, err := pods.Create(context.Background(), pending, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("创建Pod失败: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		pod := pending.DeepCopy()
+		pod.Status.Phase = corev1.PodRunning
+		pods.UpdateStatus(context.Background(), pod, metav1.UpdateOptions{})
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pod, err := controller.waitReplacementPod(ctx, old.UID)
+	if err != nil {
+		t.Fatalf("等待新Pod失败: %v", err)
+	}
+	if pod.Name != "mc-1" || pod.Status.Phase != corev1.PodRunning {
+		t.Fatalf("应返回运行中的新Pod: %s %s", pod.Name, pod.Status.Phase)
+	}
+}
+
+func TestRestartServerDeletePod(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon, ownedServerPod("mc-0", "uid-0", workloadStatefulSet, "mc"))
+	pods := clientset.CoreV1().Pods(testNamespace)
+
+	// StatefulSet在旧Pod删除后以相同的名称重建Pod
+	replacePodAfter(t, clientset, ownedServerPod("mc-0", "uid-1", workloadStatefulSet, "mc"), func() bool {
+		_, err := pods.Get(context.Background(), "mc-0", metav1.GetOptions{})
+		return apierrors.IsNotFound(err)
+	})
+
+	var commands []string
+	err := controller.RestartServer(context.Background(), RestartOptions{
+		Reason:       "更新插件",
+		StopTimeout:  100 * time.Millisecond,
+		ReadyTimeout: 300 * time.Millisecond,
+		OnCommand:    restartCommands(&commands),
+	})
+
+	// 测试中服务器端口没有监听，新Pod运行后等待Ping超时
+	if err == nil || !strings.Contains(err.Error(), "等待服务器启动超时") {
+		t.Fatalf("应在等待服务器启动时超时: %v", err)
+	}
+	if !reflect.DeepEqual(commands, []string{"save-all flush", "stop"}) {
+		t.Fatalf("重启执行的命令错误: %v", commands)
+	}
+	pod, err := pods.Get(context.Background(), "mc-0", metav1.GetOptions{})
+	if err != nil || pod.UID != "uid-1" {
+		t.Fatalf("旧Pod应被删除并重建: %v", err)
+	}
+	if controller.Restarting() {
+		t.Fatal("重启结束后不应处于重启状态")
+	}
+}
+
+func TestRestartServerRollout(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon, ownedServerPod("mc-abc-0", "uid-0", "ReplicaSet", "mc-abc"))
+
+	deployments := clientset.AppsV1().Deployments(testNamespace)
+	if _, err := deployments.Create(context.Background(), &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "mc", Namespace: testNamespace},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("创建Deployment失败: %v", err)
+	}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "mc-abc", Namespace: testNamespace}}
+	replicaSet.OwnerReferences = ownedServerPod("", "", workloadDeployment, "mc").OwnerReferences
+	if _, err := clientset.AppsV1().ReplicaSets(testNamespace).Create(context.Background(), replicaSet, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("创建ReplicaSet失败: %v", err)
+	}
+
+	// Deployment的Pod模板修改后创建新的Pod
+	replacePodAfter(t, clientset, ownedServerPod("mc-def-0", "uid-1", "ReplicaSet", "mc-def"), func() bool {
+		deployment, err := deployments.Get(context.Background(), "mc", metav1.GetOptions{})
+		return err == nil && deployment.Spec.Template.Annotations[restartedAtAnnotation] != ""
+	})
+
+	var commands []string
+	err := controller.RestartServer(context.Background(), RestartOptions{
+		Mode:         RestartRollout,
+		StopTimeout:  100 * time.Millisecond,
+		ReadyTimeout: 300 * time.Millisecond,
+		OnCommand:    restartCommands(&commands),
+	})
+	if err == nil || !strings.Contains(err.Error(), "等待服务器启动超时") {
+		t.Fatalf("应在等待服务器启动时超时: %v", err)
+	}
+	if !reflect.DeepEqual(commands, []string{"save-all flush", "stop"}) {
+		t.Fatalf("重启执行的命令错误: %v", commands)
+	}
+	if name := controller.podName(); name != "mc-def-0" {
+		t.Fatalf("控制器应切换到新Pod: %q", name)
+	}
+	// 滚动重启不删除旧Pod，由Deployment替换
+	if _, err := clientset.CoreV1().Pods(testNamespace).Get(context.Background(), "mc-abc-0", metav1.GetOptions{}); err != nil {
+		t.Fatalf("滚动重启不应删除Pod: %v", err)
+	}
+}
+
+func TestRestartServerSaveFailure(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon, ownedServerPod("mc-0", "uid-0", workloadStatefulSet, "mc"))
+	pods := clientset.CoreV1().Pods(testNamespace)
+
+	// 关闭RCON服务器，保存世界的命令执行失败
+	rcon.listener.Close()
+	rcon.dropConnections()
+
+	var commands []string
+	err := controller.RestartServer(context.Background(), RestartOptions{OnCommand: restartCommands(&commands)})
+	if err == nil || !strings.Contains(err.Error(), "保存世界失败") {
+		t.Fatalf("保存世界失败时应取消重启: %v", err)
+	}
+	if !reflect.DeepEqual(commands, []string{"save-all flush"}) {
+		t.Fatalf("取消重启后不应发送stop: %v", commands)
+	}
+	if _, err := pods.Get(context.Background(), "mc-0", metav1.GetOptions{}); err != nil {
+		t.Fatalf("取消重启时不应删除Pod: %v", err)
+	}
+
+	// Force时继续停止服务器并删除Pod
+	commands = nil
+	err = controller.RestartServer(context.Background(), RestartOptions{
+		Force:        true,
+		StopTimeout:  100 * time.Millisecond,
+		ReadyTimeout: 100 * time.Millisecond,
+		OnCommand:    restartCommands(&commands),
+	})
+	if err == nil || !strings.Contains(err.Error(), "等待新Pod运行超时") {
+		t.Fatalf("没有新Pod时应等待超时: %v", err)
+	}
+	if !reflect.DeepEqual(commands, []string{"save-all flush", "stop"}) {
+		t.Fatalf("强制重启执行的命令错误: %v", commands)
+	}
+	if _, err := pods.Get(context.Background(), "mc-0", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("强制重启应删除Pod: %v", err)
+	}
+}
+
+func TestRestartServerAbort(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon, ownedServerPod("mc-0", "uid-0", workloadStatefulSet, "mc"))
+
+	// 重启原因包含换行时不发送任何命令
+	var commands []string
+	err := controller.RestartServer(context.Background(), RestartOptions{
+		Countdown: time.Second,
+		Reason:    "维护\nop Steve",
+		OnCommand: restartCommands(&commands),
+	})
+	if err == nil || len(commands) != 0 {
+		t.Fatalf("重启原因包含换行时应拒绝重启: %v %v", err, commands)
+	}
+
+	// 倒计时期间取消
+	ctx, cancel := context.WithCancel(context.Background())
+	err = controller.RestartServer(ctx, RestartOptions{
+		Countdown: 10 * time.Second,
+		OnCommand: func(command string, result *CommandResult, err error) {
+			commands = append(commands, command)
+			cancel()
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应中止重启: %v", err)
+	}
+	if len(commands) == 0 || !strings.HasPrefix(commands[0], "say 服务器将在10秒后重启") {
+		t.Fatalf("取消前应发送第一条提醒: %v", commands)
+	}
+	for _, command := range commands {
+		if command == "save-all flush" || command == "stop" {
+			t.Fatalf("中止重启后不应停止服务器: %v", commands)
+		}
+	}
+	if _, err := clientset.CoreV1().Pods(testNamespace).Get(context.Background(), "mc-0", metav1.GetOptions{}); err != nil {
+		t.Fatalf("中止重启时不应删除Pod: %v", err)
+	}
+
+	// 不属于任何控制器的Pod删除后不会重建，通知玩家之前拒绝重启
+	commands = nil
+	controller, _ = newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+	err = controller.RestartServer(context.Background(), RestartOptions{
+		Countdown: time.Second,
+		OnCommand: restartCommands(&commands),
+	})
+	if err == nil || !strings.Contains(err.Error(), "不属于任何控制器") || len(commands) != 0 {
+		t.Fatalf("Pod不属于控制器时应拒绝重启: %v %v", err, commands)
+	}
+}
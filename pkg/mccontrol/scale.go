@@ -0,0 +1,249 @@
+package mccontrol
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultWakeTimeout = 10 * time.Minute // 默认等待唤醒的服务器可用的时间
+
+	// scaledDownReplicasAnnotation 缩容到0时记录原副本数的工作负载注解，唤醒时按该值恢复，
+	// 记录在集群中，控制台重启后仍可正确唤醒
+	scaledDownReplicasAnnotation = "k8s-console.newnan.city/scaled-down-replicas"
+)
+
+// SetIdleTimeout 设置闲置缩容时间：服务器在线且没有玩家的时间超过该值后，
+// 将所属的StatefulSet/Deployment缩容到0，为0则不自动缩容。
+// 闲置时间根据CheckServerStatus的检测结果计算，需要定期检测状态（如StartStatusMonitoring）
+func (m *MinecraftController) SetIdleTimeout(timeout time.Duration) {
+	if timeout < 0 {
+		timeout = 0
+	}
+	m.idleMutex.Lock()
+	defer m.idleMutex.Unlock()
+	m.idleTimeout = timeout
+	m.idleSince = time.Time{}
+}
+
+// observeIdle 根据状态检测结果计算闲置时间，闲置超时后在后台缩容
+func (m *MinecraftController) observeIdle(status ServerStatus) {
+	m.idleMutex.Lock()
+	if m.idleTimeout <= 0 || !status.Online || status.Players > 0 || m.Restarting() {
+		m.idleSince = time.Time{}
+		m.idleMutex.Unlock()
+		return
+	}
+
+	now := status.LastChecked
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if m.idleSince.IsZero() {
+		m.idleSince = now
+	}
+	if now.Sub(m.idleSince) < m.idleTimeout {
+		m.idleMutex.Unlock()
+		return
+	}
+	timeout := m.idleTimeout
+	m.idleSince = time.Time{}
+	m.idleMutex.Unlock()
+
+	go func() {
+		if err := m.scaleToZero(m.ctx, fmt.Sprintf("服务器已闲置%s", timeout)); err != nil {
+			log.Printf("闲置缩容失败: %v", err)
+		}
+	}()
+}
+
+// ScaleToZero 保存世界后将服务器所属的StatefulSet/Deployment缩容到0，原副本数记录在工作负载的注解中
+// 已缩容到0时直接返回；服务器正在重启时返回ErrRestartInProgress
+func (m *MinecraftController) ScaleToZero(ctx context.Context) error {
+	return m.scaleToZero(ctx, "手动缩容")
+}
+
+// scaleToZero 缩容到0，reason用于进度事件
+func (m *MinecraftController) scaleToZero(ctx context.Context, reason string) error {
+	m.scaleMutex.Lock()
+	defer m.scaleMutex.Unlock()
+
+	if m.Restarting() {
+		return ErrRestartInProgress
+	}
+
+	workload, err := m.findScaleTarget(ctx)
+	if err != nil {
+		return err
+	}
+	scale, err := m.getScale(ctx, workload)
+	if err != nil {
+		return fmt.Errorf("获取%s的副本数失败: %v", workload, err)
+	}
+	if scale.Spec.Replicas == 0 {
+		return nil
+	}
+
+	// 缩容时容器收到SIGTERM会正常停止服务器，这里提前保存一次，失败不影响缩容
+	m.ExecuteCommandContext(ctx, "save-all flush")
+
+	if err := m.annotateReplicas(ctx, workload, strconv.Itoa(int(scale.Spec.Replicas))); err != nil {
+		return fmt.Errorf("记录%s的副本数失败: %v", workload, err)
+	}
+	scale.Spec.Replicas = 0
+	if err := m.updateScale(ctx, workload, scale); err != nil {
+		return fmt.Errorf("缩容%s失败: %v", workload, err)
+	}
+
+	m.setScaledDown(true)
+	m.publishScale(ServerStateSleeping, fmt.Sprintf("%s，已将%s缩容到0", reason, workload))
+	return nil
+}
+
+// WakeServer 唤醒已缩容到0的服务器：恢复缩容前的副本数（没有记录时为1），
+// 等待Pod运行并且服务器可以Ping通。服务器未缩容时只等待服务器可以Ping通。
+// 多个调用同时唤醒时依次执行，后面的调用会发现服务器已在运行；timeout为0则使用默认值10分钟
+func (m *MinecraftController) WakeServer(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultWakeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	stop := context.AfterFunc(m.ctx, cancel)
+	defer stop()
+
+	m.scaleMutex.Lock()
+	defer m.scaleMutex.Unlock()
+
+	workload, err := m.findScaleTarget(ctx)
+	if err != nil {
+		return err
+	}
+	scale, err := m.getScale(ctx, workload)
+	if err != nil {
+		return fmt.Errorf("获取%s的副本数失败: %v", workload, err)
+	}
+	annotations, err := m.workloadAnnotations(ctx, workload)
+	if err != nil {
+		return fmt.Errorf("获取%s失败: %v", workload, err)
+	}
+	recorded, hasRecord := annotations[scaledDownReplicasAnnotation]
+
+	if scale.Spec.Replicas == 0 {
+		replicas := 1
+		if n, err := strconv.Atoi(recorded); err == nil && n > 0 {
+			replicas = n
+		}
+		m.publishScale(ServerStateWaking, fmt.Sprintf("正在将%s扩容到%d", workload, replicas))
+		scale.Spec.Replicas = int32(replicas)
+		if err := m.updateScale(ctx, workload, scale); err != nil {
+			return fmt.Errorf("扩容%s失败: %v", workload, err)
+		}
+	}
+	if hasRecord {
+		if err := m.annotateReplicas(ctx, workload, ""); err != nil {
+			log.Printf("清除%s的副本数记录失败: %v", workload, err)
+		}
+	}
+	m.setScaledDown(false)
+
+	pod, err := m.waitReplacementPod(ctx, "")
+	if err != nil {
+		return err
+	}
+	m.podInfoUpdateMutex.Lock()
+	m.setCurrentPod(pod)
+	m.markPodInfoUpdated()
+	m.podInfoUpdateMutex.Unlock()
+
+	return m.waitServerOnline(ctx)
+}
+
+// Replicas 获取服务器所属StatefulSet/Deployment当前的副本数
+func (m *MinecraftController) Replicas(ctx context.Context) (int, error) {
+	workload, err := m.findScaleTarget(ctx)
+	if err != nil {
+		return 0, err
+	}
+	scale, err := m.getScale(ctx, workload)
+	if err != nil {
+		return 0, fmt.Errorf("获取%s的副本数失败: %v", workload, err)
+	}
+	return int(scale.Spec.Replicas), nil
+}
+
+// setScaledDown 记录服务器是否已缩容到0，缩容和唤醒后立即更新状态，之后的状态检测从集群中重新确定
+func (m *MinecraftController) setScaledDown(scaledDown bool) {
+	m.updateStatus(func(status *ServerStatus) { status.ScaledDown = scaledDown })
+}
+
+// checkScaledDown 从集群中确定服务器是否已由控制台缩容到0：工作负载的副本数为0且记录了缩容前的副本数
+// 用于没有Pod时的状态检测，控制台重启后仍能区分缩容和故障；无法确定时返回false
+func (m *MinecraftController) checkScaledDown() bool {
+	ctx, cancel := context.WithTimeout(m.ctx, podStatusTimeout)
+	defer cancel()
+
+	workload, err := m.findWorkloadBySelector(ctx)
+	if err != nil {
+		return false
+	}
+	scale, err := m.getScale(ctx, workload)
+	if err != nil || scale.Spec.Replicas != 0 {
+		return false
+	}
+	annotations, err := m.workloadAnnotations(ctx, workload)
+	if err != nil {
+		return false
+	}
+	_, ok := annotations[scaledDownReplicasAnnotation]
+	return ok
+}
+
+// findScaleTarget 确定要缩放的工作负载：优先使用当前Pod所属的工作负载，没有Pod时按标签选择器查找
+func (m *MinecraftController) findScaleTarget(ctx context.Context) (workloadRef, error) {
+	_, err := m.updatePodInfoIfNeeded(false)
+	if podName := m.podName(); err == nil && podName != "" {
+		pod, err := m.clientset.CoreV1().Pods(m.namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err == nil {
+			if workload, err := m.findWorkload(ctx, pod); err == nil {
+				return workload, nil
+			}
+		}
+	}
+	return m.findWorkloadBySelector(ctx)
+}
+
+// annotateReplicas 在工作负载上记录缩容前的副本数，为空则删除记录
+func (m *MinecraftController) annotateReplicas(ctx context.Context, workload workloadRef, replicas string) error {
+	var value interface{}
+	if replicas != "" {
+		value = replicas
+	}
+	patch, err := sonic.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{scaledDownReplicasAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return m.patchWorkload(ctx, workload, types.MergePatchType, patch)
+}
+
+// publishScale 发布缩容或唤醒事件
+func (m *MinecraftController) publishScale(state ServerState, message string) {
+	m.events.emit(Event{
+		Type:    EventServerState,
+		Time:    time.Now(),
+		Source:  EventSourceScale,
+		State:   state,
+		Message: message,
+	})
+}
@@ -0,0 +1,183 @@
+package mccontrol
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeStatefulSetScale 让fake客户端支持StatefulSet的scale子资源，读写StatefulSet的spec.replicas
+func fakeStatefulSetScale(clientset *fake.Clientset) {
+	resource := appsv1.SchemeGroupVersion.WithResource("statefulsets")
+	tracker := clientset.Tracker()
+
+	clientset.PrependReactor("get", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		if get.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		obj, err := tracker.Get(resource, get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		statefulSet := obj.(*appsv1.StatefulSet)
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: statefulSet.Name, Namespace: statefulSet.Namespace},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: *statefulSet.Spec.Replicas},
+		}, nil
+	})
+	clientset.PrependReactor("update", "statefulsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update := action.(k8stesting.UpdateAction)
+		if update.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := update.GetObject().(*autoscalingv1.Scale)
+		obj, err := tracker.Get(resource, update.GetNamespace(), scale.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		statefulSet := obj.(*appsv1.StatefulSet).DeepCopy()
+		statefulSet.Spec.Replicas = &scale.Spec.Replicas
+		return true, scale, tracker.Update(resource, statefulSet, update.GetNamespace())
+	})
+}
+
+// createTestStatefulSet 创建Pod模板匹配测试控制器标签选择器的StatefulSet
+func createTestStatefulSet(t *testing.T, clientset *fake.Clientset, name string, replicas int32, annotations map[string]string) {
+	t.Helper()
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace, Annotations: annotations},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "minecraft"}},
+			},
+		},
+	}
+	if _, err := clientset.AppsV1().StatefulSets(testNamespace).Create(context.Background(), statefulSet, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("创建StatefulSet失败: %v", err)
+	}
+}
+
+// getTestStatefulSet 获取StatefulSet
+func getTestStatefulSet(t *testing.T, clientset *fake.Clientset, name string) *appsv1.StatefulSet {
+	t.Helper()
+
+	statefulSet, err := clientset.AppsV1().StatefulSets(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("获取StatefulSet失败: %v", err)
+	}
+	return statefulSet
+}
+
+func TestScaleToZeroAndWake(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon, ownedServerPod("mc-0", "uid-0", workloadStatefulSet, "mc"))
+	fakeStatefulSetScale(clientset)
+	createTestStatefulSet(t, clientset, "mc", 2, nil)
+	pods := clientset.CoreV1().Pods(testNamespace)
+
+	// 缩容到0并记录原副本数
+	if err := controller.ScaleToZero(context.Background()); err != nil {
+		t.Fatalf("缩容失败: %v", err)
+	}
+	statefulSet := getTestStatefulSet(t, clientset, "mc")
+	if *statefulSet.Spec.Replicas != 0 || statefulSet.Annotations[scaledDownReplicasAnnotation] != "2" {
+		t.Fatalf("应缩容到0并记录原副本数: replicas=%d annotations=%v", *statefulSet.Spec.Replicas, statefulSet.Annotations)
+	}
+	if !controller.LastStatus().ScaledDown {
+		t.Fatal("缩容后状态应为已缩容")
+	}
+	if replicas, err := controller.Replicas(context.Background()); err != nil || replicas != 0 {
+		t.Fatalf("缩容后副本数应为0: %d %v", replicas, err)
+	}
+
+	// StatefulSet删除Pod后，重启的控制台从集群中确定服务器已缩容
+	if err := pods.Delete(context.Background(), "mc-0", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("删除Pod失败: %v", err)
+	}
+	controller = newTestControllerFor(t, clientset, rcon)
+	status, err := controller.CheckServerStatus()
+	if err != nil {
+		t.Fatalf("缩容后没有Pod不应视为错误: %v", err)
+	}
+	if !status.ScaledDown || status.Online || !strings.Contains(status.LastError, "缩容到0") {
+		t.Fatalf("重启后应从集群中确定已缩容: %+v", status)
+	}
+
+	// 唤醒时恢复原副本数并清除记录，新Pod运行后等待服务器可以Ping通
+	replacePodAfter(t, clientset, ownedServerPod("mc-0", "uid-1", workloadStatefulSet, "mc"), func() bool {
+		statefulSet, err := clientset.AppsV1().StatefulSets(testNamespace).Get(context.Background(), "mc", metav1.GetOptions{})
+		return err == nil && *statefulSet.Spec.Replicas > 0
+	})
+	err = controller.WakeServer(context.Background(), 300*time.Millisecond)
+	// 测试中服务器端口没有监听，新Pod运行后等待Ping超时
+	if err == nil {
+		t.Fatal("服务器端口没有监听时唤醒应超时")
+	}
+	statefulSet = getTestStatefulSet(t, clientset, "mc")
+	if *statefulSet.Spec.Replicas != 2 {
+		t.Fatalf("唤醒应恢复原副本数: %d", *statefulSet.Spec.Replicas)
+	}
+	if replicas, err := controller.Replicas(context.Background()); err != nil || replicas != 2 {
+		t.Fatalf("唤醒后副本数应为2: %d %v", replicas, err)
+	}
+	if _, ok := statefulSet.Annotations[scaledDownReplicasAnnotation]; ok {
+		t.Fatalf("唤醒后应清除副本数记录: %v", statefulSet.Annotations)
+	}
+	if name := controller.podName(); name != "mc-0" {
+		t.Fatalf("唤醒后应使用新Pod: %q", name)
+	}
+	if controller.LastStatus().ScaledDown {
+		t.Fatal("唤醒后状态不应为已缩容")
+	}
+}
+
+func TestScaledDownRequiresRecord(t *testing.T) {
+	fastRestartPolling(t)
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, clientset := newTestController(t, rcon)
+	fakeStatefulSetScale(clientset)
+
+	// 副本数为0但不是控制台缩容的（没有记录），没有Pod视为错误
+	createTestStatefulSet(t, clientset, "mc", 0, nil)
+	if err := controller.ScaleToZero(context.Background()); err != nil {
+		t.Fatalf("已缩容到0时缩容应直接返回: %v", err)
+	}
+	status, err := controller.CheckServerStatus()
+	if err == nil || status.ScaledDown {
+		t.Fatalf("没有缩容记录时不应视为已缩容: %v %+v", err, status)
+	}
+	if _, ok := getTestStatefulSet(t, clientset, "mc").Annotations[scaledDownReplicasAnnotation]; ok {
+		t.Fatal("副本数已为0时不应记录副本数")
+	}
+
+	// 没有记录时唤醒恢复为1个副本
+	replacePodAfter(t, clientset, ownedServerPod("mc-0", "uid-0", workloadStatefulSet, "mc"), func() bool {
+		statefulSet, err := clientset.AppsV1().StatefulSets(testNamespace).Get(context.Background(), "mc", metav1.GetOptions{})
+		return err == nil && *statefulSet.Spec.Replicas > 0
+	})
+	controller.WakeServer(context.Background(), 300*time.Millisecond)
+	if replicas := *getTestStatefulSet(t, clientset, "mc").Spec.Replicas; replicas != 1 {
+		t.Fatalf("没有记录时应恢复为1个副本: %d", replicas)
+	}
+
+	// 工作负载不存在时无法确定，不视为已缩容
+	if err := clientset.AppsV1().StatefulSets(testNamespace).Delete(context.Background(), "mc", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("删除StatefulSet失败: %v", err)
+	}
+	if controller.checkScaledDown() {
+		t.Fatal("工作负载不存在时不应视为已缩容")
+	}
+}
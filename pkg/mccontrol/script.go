@@ -0,0 +1,199 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 脚本的限制
+const (
+	maxScriptSteps     = 100              // 单个脚本最多包含的步骤数
+	maxScriptStepDelay = 10 * time.Minute // 单个步骤执行前最多等待的时间
+	maxScriptDuration  = 30 * time.Minute // 脚本最长执行时间，包括步骤之间的等待，所有步骤的等待时间之和也不能超过该值
+)
+
+// ErrInvalidScript 脚本格式错误（没有步骤、引用了未定义的变量等），脚本未执行任何命令
+var ErrInvalidScript = errors.New("无效的脚本")
+
+// scriptVariablePattern 匹配脚本命令中的变量引用，如${player}
+var scriptVariablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// ScriptErrorPolicy 脚本步骤失败后的处理方式
+type ScriptErrorPolicy string
+
+const (
+	ScriptStopOnError ScriptErrorPolicy = "stop"     // 停止执行，跳过剩余的步骤
+	ScriptContinue    ScriptErrorPolicy = "continue" // 继续执行下一个步骤
+)
+
+// ScriptStep 脚本中的一个步骤
+type ScriptStep struct {
+	Command string            // 要执行的命令，可以使用${name}引用变量
+	Delay   time.Duration     // 执行前等待的时间
+	OnError ScriptErrorPolicy // 该步骤失败后的处理方式，为空则使用脚本的默认方式
+}
+
+// Script 按顺序在同一个命令会话中执行的一组命令
+type Script struct {
+	Steps        []ScriptStep      // 按顺序执行的步骤
+	Variables    map[string]string // 命令中${name}引用的变量
+	OnError      ScriptErrorPolicy // 步骤失败后的默认处理方式，为空则停止执行
+	ExecutorType ExecutorType      // 使用的执行器类型，为空则自动选择
+}
+
+// ScriptStepResult 脚本步骤的执行结果
+type ScriptStepResult struct {
+	CommandResult
+	Index   int    // 步骤序号，从0开始
+	Command string // 替换变量后的命令
+	Err     error  // 执行失败的原因
+	Skipped bool   // 之前的步骤失败或上下文取消，该步骤未执行
+}
+
+// ScriptResult 脚本的执行结果
+type ScriptResult struct {
+	Steps    []ScriptStepResult // 每个步骤的结果，与脚本的步骤一一对应
+	Success  bool               // 所有步骤都执行成功
+	Duration time.Duration      // 总耗时，包括步骤之间的等待
+}
+
+// Commands 校验脚本并返回替换变量后的命令，不执行任何命令
+// 脚本没有步骤、步骤过多、等待时间超出限制、命令为空或引用了未定义的变量时返回ErrInvalidScript
+func (s Script) Commands() ([]string, error) {
+	if len(s.Steps) == 0 {
+		return nil, fmt.Errorf("%w: 脚本没有任何步骤", ErrInvalidScript)
+	}
+	if len(s.Steps) > maxScriptSteps {
+		return nil, fmt.Errorf("%w: 脚本最多包含%d个步骤", ErrInvalidScript, maxScriptSteps)
+	}
+	if err := s.OnError.validate(); err != nil {
+		return nil, err
+	}
+
+	commands := make([]string, len(s.Steps))
+	var totalDelay time.Duration
+	for i, step := range s.Steps {
+		if err := step.OnError.validate(); err != nil {
+			return nil, err
+		}
+		if step.Delay < 0 {
+			return nil, fmt.Errorf("%w: 第%d步的等待时间不能为负数", ErrInvalidScript, i+1)
+		}
+		if step.Delay > maxScriptStepDelay {
+			return nil, fmt.Errorf("%w: 第%d步的等待时间不能超过%s", ErrInvalidScript, i+1, maxScriptStepDelay)
+		}
+		if totalDelay += step.Delay; totalDelay > maxScriptDuration {
+			return nil, fmt.Errorf("%w: 所有步骤的等待时间之和不能超过%s", ErrInvalidScript, maxScriptDuration)
+		}
+
+		var missing string
+		command := scriptVariablePattern.ReplaceAllStringFunc(step.Command, func(ref string) string {
+			name := scriptVariablePattern.FindStringSubmatch(ref)[1]
+			value, ok := s.Variables[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return value
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("%w: 第%d步引用了未定义的变量 '%s'", ErrInvalidScript, i+1, missing)
+		}
+		if strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("%w: 第%d步的命令为空", ErrInvalidScript, i+1)
+		}
+		commands[i] = command
+	}
+	return commands, nil
+}
+
+// validate 检查错误处理方式是否有效，为空表示使用默认方式
+func (p ScriptErrorPolicy) validate() error {
+	switch p {
+	case "", ScriptStopOnError, ScriptContinue:
+		return nil
+	default:
+		return fmt.Errorf("%w: 不支持的错误处理方式 '%s'", ErrInvalidScript, p)
+	}
+}
+
+// ExecuteScript 在一个新的命令会话中按顺序执行脚本，执行结束后关闭会话
+// 每个步骤执行后调用observer（可以为nil），被跳过的步骤不会调用；
+// 步骤失败不会使本方法返回错误，失败信息记录在对应步骤的结果中。
+// 脚本无效、会话创建失败时返回错误；上下文取消时跳过剩余步骤，返回已有的结果和ctx.Err()。
+// 脚本最多执行30分钟，超时后与上下文取消相同，返回context.DeadlineExceeded
+func (m *MinecraftController) ExecuteScript(ctx context.Context, script Script, observer func(ScriptStepResult)) (*ScriptResult, error) {
+	commands, err := script.Commands()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, maxScriptDuration)
+	defer cancel()
+
+	start := time.Now()
+	result := &ScriptResult{Steps: make([]ScriptStepResult, len(commands))}
+	for i, command := range commands {
+		result.Steps[i] = ScriptStepResult{Index: i, Command: command, Skipped: true}
+	}
+
+	// 脚本的会话不加入会话管理器，步骤之间的等待不会使会话因空闲被清理
+	session, err := m.newCommandSession(0, script.ExecutorType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建命令会话失败: %w", err)
+	}
+	defer session.Close()
+
+	result.Success = true
+	for i, step := range script.Steps {
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-ctx.Done():
+				result.Success = false
+				result.Duration = time.Since(start)
+				return result, ctx.Err()
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			result.Success = false
+			result.Duration = time.Since(start)
+			return result, err
+		}
+
+		stepResult := &result.Steps[i]
+		stepResult.Skipped = false
+		stepResult.SessionID = session.GetID()
+
+		stepStart := time.Now()
+		stepResult.Response, stepResult.Err = session.ExecuteCommandContext(ctx, stepResult.Command)
+		stepResult.ExecutorType = session.GetExecutorType()
+		stepResult.Duration = time.Since(stepStart)
+		if observer != nil {
+			observer(*stepResult)
+		}
+
+		if stepResult.Err == nil {
+			continue
+		}
+		result.Success = false
+		if ctx.Err() != nil {
+			result.Duration = time.Since(start)
+			return result, ctx.Err()
+		}
+
+		policy := step.OnError
+		if policy == "" {
+			policy = script.OnError
+		}
+		if policy != ScriptContinue {
+			break
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
@@ -0,0 +1,210 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScriptCommands(t *testing.T) {
+	script := Script{
+		Variables: map[string]string{"player": "Steve", "minutes": "5"},
+		Steps: []ScriptStep{
+			{Command: "say 服务器将在${minutes}分钟后重启"},
+			{Command: "kick ${player} ${player}", Delay: time.Second},
+			{Command: "tellraw @a {\"text\":\"$notavariable\"}"},
+		},
+	}
+
+	commands, err := script.Commands()
+	if err != nil {
+		t.Fatalf("校验脚本失败: %v", err)
+	}
+	want := []string{
+		"say 服务器将在5分钟后重启",
+		"kick Steve Steve",
+		"tellraw @a {\"text\":\"$notavariable\"}",
+	}
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("替换变量后的命令 = %q, 期望 %q", commands, want)
+	}
+}
+
+func TestScriptCommandsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		script Script
+	}{
+		{"没有步骤", Script{}},
+		{"未定义的变量", Script{Steps: []ScriptStep{{Command: "kick ${player}"}}}},
+		{"空命令", Script{Steps: []ScriptStep{{Command: "${empty}"}}, Variables: map[string]string{"empty": " "}}},
+		{"负数等待时间", Script{Steps: []ScriptStep{{Command: "list", Delay: -time.Second}}}},
+		{"不支持的错误处理方式", Script{Steps: []ScriptStep{{Command: "list", OnError: "retry"}}}},
+		{"步骤过多", Script{Steps: make([]ScriptStep, maxScriptSteps+1)}},
+		{"等待时间过长", Script{Steps: []ScriptStep{{Command: "list", Delay: maxScriptStepDelay + time.Second}}}},
+		{"等待时间之和过长", Script{Steps: []ScriptStep{
+			{Command: "list", Delay: maxScriptStepDelay},
+			{Command: "list", Delay: maxScriptStepDelay},
+			{Command: "list", Delay: maxScriptStepDelay},
+			{Command: "list", Delay: time.Second},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.script.Commands(); !errors.Is(err, ErrInvalidScript) {
+				t.Errorf("期望ErrInvalidScript, 实际 %v", err)
+			}
+		})
+	}
+}
+
+// newScriptTestController 创建RCON连接到测试服务器的控制器，命令fail会使服务器断开连接，重试后执行失败
+func newScriptTestController(t *testing.T) *MinecraftController {
+	t.Helper()
+
+	var rcon *fakeRconServer
+	rcon = newFakeRconServer(t, "secret", func(command string) string {
+		if command == "fail" {
+			rcon.dropConnections()
+		}
+		return command
+	})
+	controller, _ := newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+	controller.rconRetryDelay = time.Millisecond
+	return controller
+}
+
+// scriptObserver 记录每次调用observer的步骤
+type scriptObserver struct {
+	mutex sync.Mutex
+	steps []ScriptStepResult
+}
+
+func (o *scriptObserver) observe(step ScriptStepResult) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.steps = append(o.steps, step)
+}
+
+// indexes 返回调用过observer的步骤序号
+func (o *scriptObserver) indexes() []int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	indexes := make([]int, len(o.steps))
+	for i, step := range o.steps {
+		indexes[i] = step.Index
+	}
+	return indexes
+}
+
+func TestExecuteScriptErrorPolicy(t *testing.T) {
+	controller := newScriptTestController(t)
+
+	// 默认在失败后停止，跳过剩余的步骤
+	var observer scriptObserver
+	result, err := controller.ExecuteScript(context.Background(), Script{
+		Steps:        []ScriptStep{{Command: "say 1"}, {Command: "fail"}, {Command: "say 3"}},
+		ExecutorType: ExecutorRcon,
+	}, observer.observe)
+	if err != nil {
+		t.Fatalf("步骤失败不应返回错误: %v", err)
+	}
+	if result.Success || result.Steps[0].Response != "say 1" || result.Steps[1].Err == nil || !result.Steps[2].Skipped {
+		t.Fatalf("失败后应停止执行: %+v", result)
+	}
+	if got := observer.indexes(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Fatalf("observer应只在执行过的步骤后调用: %v", got)
+	}
+	if step := result.Steps[0]; step.ExecutorType != ExecutorRcon || step.SessionID == "" {
+		t.Fatalf("步骤结果应包含执行器和会话: %+v", step)
+	}
+
+	// 脚本默认继续时执行剩余的步骤，步骤可以覆盖默认方式
+	observer = scriptObserver{}
+	result, err = controller.ExecuteScript(context.Background(), Script{
+		Steps: []ScriptStep{
+			{Command: "fail"},
+			{Command: "fail", OnError: ScriptStopOnError},
+			{Command: "say 3"},
+		},
+		OnError:      ScriptContinue,
+		ExecutorType: ExecutorRcon,
+	}, observer.observe)
+	if err != nil {
+		t.Fatalf("步骤失败不应返回错误: %v", err)
+	}
+	if result.Success || result.Steps[0].Err == nil || result.Steps[1].Skipped || result.Steps[1].Err == nil || !result.Steps[2].Skipped {
+		t.Fatalf("第1步失败后继续，第2步失败后停止: %+v", result)
+	}
+	if got := observer.indexes(); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Fatalf("observer调用错误: %v", got)
+	}
+
+	// 所有步骤成功
+	result, err = controller.ExecuteScript(context.Background(), Script{
+		Steps:        []ScriptStep{{Command: "say 1"}, {Command: "say 2"}},
+		ExecutorType: ExecutorRcon,
+	}, nil)
+	if err != nil || !result.Success || result.Steps[1].Response != "say 2" {
+		t.Fatalf("脚本应执行成功: %v %+v", err, result)
+	}
+}
+
+func TestExecuteScriptDelayAndCancel(t *testing.T) {
+	controller := newScriptTestController(t)
+
+	// 步骤执行前等待，总耗时包括等待时间
+	result, err := controller.ExecuteScript(context.Background(), Script{
+		Steps: []ScriptStep{
+			{Command: "say 1"},
+			{Command: "say 2", Delay: 50 * time.Millisecond},
+			{Command: "say 3", Delay: 50 * time.Millisecond},
+		},
+		ExecutorType: ExecutorRcon,
+	}, nil)
+	if err != nil || !result.Success {
+		t.Fatalf("脚本执行失败: %v %+v", err, result)
+	}
+	if result.Duration < 100*time.Millisecond || result.Steps[2].Duration >= 50*time.Millisecond {
+		t.Fatalf("等待时间应计入总耗时，不计入步骤耗时: total=%s step=%s", result.Duration, result.Steps[2].Duration)
+	}
+
+	// 等待期间取消时跳过剩余的步骤
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var observer scriptObserver
+	result, err = controller.ExecuteScript(ctx, Script{
+		Steps: []ScriptStep{
+			{Command: "say 1"},
+			{Command: "say 2", Delay: time.Minute},
+			{Command: "say 3"},
+		},
+		ExecutorType: ExecutorRcon,
+	}, func(step ScriptStepResult) {
+		observer.observe(step)
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消后应返回context.Canceled: %v", err)
+	}
+	if result == nil || result.Success || result.Steps[0].Skipped || !result.Steps[1].Skipped || !result.Steps[2].Skipped {
+		t.Fatalf("取消后应跳过剩余的步骤: %+v", result)
+	}
+	if got := observer.indexes(); !reflect.DeepEqual(got, []int{0}) {
+		t.Fatalf("被跳过的步骤不应调用observer: %v", got)
+	}
+
+	// 无效的脚本不执行任何命令
+	if _, err := controller.ExecuteScript(context.Background(), Script{
+		Steps: []ScriptStep{{Command: "say 1"}, {Command: "kick ${player}"}},
+	}, observer.observe); !errors.Is(err, ErrInvalidScript) {
+		t.Fatalf("无效的脚本应返回ErrInvalidScript: %v", err)
+	}
+	if got := observer.indexes(); len(got) != 1 {
+		t.Fatalf("无效的脚本不应执行任何步骤: %v", got)
+	}
+}
@@ -1,187 +1,360 @@
-package mccontrol
-
-import (
-	"fmt"
-	"sync"
-	"time"
-
-	"github.com/google/uuid"
-)
-
-// CommandSession 表示与Minecraft服务器的命令会话
-type CommandSession struct {
-	id           string          // 会话唯一标识符
-	executor     CommandExecutor // 命令执行器
-	executorType ExecutorType    // 执行器类型
-	lastUsed     time.Time       // 最后使用时间
-	idleTimeout  time.Duration   // 空闲超时时间
-	mutex        sync.Mutex      // 互斥锁
-}
-
-// sessionManager 管理命令会话
-type sessionManager struct {
-	sessions     map[string]*CommandSession // 会话映射 (ID -> 会话)
-	mutex        sync.Mutex                 // 互斥锁
-	cleanupTimer *time.Ticker               // 清理计时器
-}
-
-// CreateCommandSession 创建一个新的命令会话
-// executorType 指定要使用的执行器类型，使用ExecutorAuto自动选择最适合的执行器
-func (m *MinecraftController) CreateCommandSession(idleTimeout time.Duration, executorType ExecutorType) (*CommandSession, error) {
-	// 如果没有指定执行器类型，使用自动选择
-	if executorType == "" {
-		executorType = ExecutorAuto
-	}
-
-	// 创建命令执行器
-	executor, err := m.CreateCommandExecutor(executorType)
-	if err != nil {
-		return nil, fmt.Errorf("创建命令执行器失败: %v", err)
-	}
-
-	if err := executor.Connect(); err != nil {
-		return nil, fmt.Errorf("连接执行器失败: %v", err)
-	}
-
-	// 创建会话
-	session := &CommandSession{
-		id:           uuid.New().String(),
-		executor:     executor,
-		executorType: executorType,
-		lastUsed:     time.Now(),
-		idleTimeout:  idleTimeout,
-	}
-
-	// 将会话添加到管理器
-	m.sessionManager.mutex.Lock()
-	m.sessionManager.sessions[session.id] = session
-	m.sessionManager.mutex.Unlock()
-
-	return session, nil
-}
-
-// ExecuteCommand 在会话中执行命令
-func (s *CommandSession) ExecuteCommand(command string) (string, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 更新最后使用时间
-	s.lastUsed = time.Now()
-
-	// 执行命令
-	response, err := s.executor.ExecuteCommand(command)
-	return response, err
-}
-
-// Close 关闭会话
-func (s *CommandSession) Close() {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 断开执行器连接
-	s.executor.Disconnect()
-}
-
-// IsIdle 检查会话是否空闲
-func (s *CommandSession) IsIdle() bool {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	return time.Since(s.lastUsed) > s.idleTimeout
-}
-
-// GetID 获取会话ID
-func (s *CommandSession) GetID() string {
-	return s.id
-}
-
-// GetExecutorType 获取执行器类型
-func (s *CommandSession) GetExecutorType() ExecutorType {
-	return s.executorType
-}
-
-// SessionExecuteCommand 使用指定会话执行命令
-func (m *MinecraftController) SessionExecuteCommand(sessionID, command string) (string, error) {
-	m.sessionManager.mutex.Lock()
-	session, ok := m.sessionManager.sessions[sessionID]
-	m.sessionManager.mutex.Unlock()
-
-	if !ok {
-		return "", fmt.Errorf("会话不存在: %s", sessionID)
-	}
-
-	return session.ExecuteCommand(command)
-}
-
-// CloseCommandSession 关闭指定的命令会话
-func (m *MinecraftController) CloseCommandSession(sessionID string) error {
-	m.sessionManager.mutex.Lock()
-	session, ok := m.sessionManager.sessions[sessionID]
-	if ok {
-		delete(m.sessionManager.sessions, sessionID)
-	}
-	m.sessionManager.mutex.Unlock()
-
-	if !ok {
-		return fmt.Errorf("会话不存在: %s", sessionID)
-	}
-
-	session.Close()
-	return nil
-}
-
-// CloseAllCommandSessions 关闭所有命令会话
-func (m *MinecraftController) CloseAllCommandSessions() {
-	m.sessionManager.mutex.Lock()
-	sessions := make([]*CommandSession, 0, len(m.sessionManager.sessions))
-	for _, session := range m.sessionManager.sessions {
-		sessions = append(sessions, session)
-	}
-	m.sessionManager.sessions = make(map[string]*CommandSession)
-	m.sessionManager.mutex.Unlock()
-
-	// 关闭所有会话
-	for _, session := range sessions {
-		session.Close()
-	}
-}
-
-// ListCommandSessions 列出所有活跃的命令会话
-func (m *MinecraftController) ListCommandSessions() []string {
-	m.sessionManager.mutex.Lock()
-	defer m.sessionManager.mutex.Unlock()
-
-	ids := make([]string, 0, len(m.sessionManager.sessions))
-	for id := range m.sessionManager.sessions {
-		ids = append(ids, id)
-	}
-
-	return ids
-}
-
-// cleanupIdleSessions 清理空闲的会话
-func (sm *sessionManager) cleanupIdleSessions() {
-	sm.mutex.Lock()
-	idleSessions := make([]string, 0)
-
-	// 查找空闲会话
-	for id, session := range sm.sessions {
-		if session.IsIdle() {
-			idleSessions = append(idleSessions, id)
-		}
-	}
-
-	// 删除空闲会话
-	for _, id := range idleSessions {
-		session := sm.sessions[id]
-		delete(sm.sessions, id)
-		sm.mutex.Unlock() // 在可能阻塞的操作前解锁
-
-		// 关闭会话
-		session.Close()
-
-		sm.mutex.Lock() // 重新获取锁
-	}
-
-	sm.mutex.Unlock()
-}
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound 命令会话不存在（可能已因空闲超时被清理）
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// CommandSession 表示与Minecraft服务器的命令会话
+type CommandSession struct {
+	controller   *MinecraftController // 所属控制器
+	id           string               // 会话唯一标识符
+	executor     CommandExecutor      // 命令执行器
+	executorType ExecutorType         // 执行器类型
+	auto         bool                 // 是否自动选择执行器，为true时执行器失效后会切换到其他执行器
+	target       *podTarget           // 固定使用的Pod，为nil则跟随控制器当前的Pod
+	lastUsed     time.Time            // 最后使用时间
+	idleTimeout  time.Duration        // 空闲超时时间
+	mutex        sync.Mutex           // 互斥锁
+	typeMutex    sync.Mutex           // 保护executorType，故障转移时会更新
+}
+
+// sessionManager 管理命令会话
+type sessionManager struct {
+	sessions     map[string]*CommandSession // 会话映射 (ID -> 会话)
+	mutex        sync.Mutex                 // 互斥锁
+	cleanupTimer *time.Ticker               // 清理计时器
+}
+
+// CreateCommandSession 创建一个新的命令会话
+// executorType 指定要使用的执行器类型，使用ExecutorAuto自动选择最适合的执行器
+func (m *MinecraftController) CreateCommandSession(idleTimeout time.Duration, executorType ExecutorType) (*CommandSession, error) {
+	session, err := m.newCommandSession(idleTimeout, executorType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 将会话添加到管理器
+	m.sessionManager.mutex.Lock()
+	m.sessionManager.sessions[session.id] = session
+	m.sessionManager.mutex.Unlock()
+
+	return session, nil
+}
+
+// newCommandSession 创建命令会话并连接执行器，不加入会话管理器
+// target为nil时会话跟随控制器当前的Pod，否则固定使用指定的Pod
+func (m *MinecraftController) newCommandSession(idleTimeout time.Duration, executorType ExecutorType, target *podTarget) (*CommandSession, error) {
+	// 如果没有指定执行器类型，使用自动选择
+	if executorType == "" {
+		executorType = ExecutorAuto
+	}
+
+	// 创建命令执行器
+	// 会话长期使用执行器，attach执行器保持持久数据流
+	executor, err := m.createTargetExecutor(executorType, true, target)
+	if err != nil {
+		return nil, fmt.Errorf("创建命令执行器失败: %w", err)
+	}
+
+	if err := executor.Connect(); err != nil {
+		return nil, fmt.Errorf("连接执行器失败: %v", err)
+	}
+
+	// 创建会话
+	session := &CommandSession{
+		controller:   m,
+		id:           uuid.New().String(),
+		executor:     executor,
+		executorType: executorTypeOf(executor), // 记录自动选择后实际使用的执行器类型
+		auto:         executorType == ExecutorAuto,
+		target:       target,
+		lastUsed:     time.Now(),
+		idleTimeout:  idleTimeout,
+	}
+	return session, nil
+}
+
+// ExecuteCommand 在会话中执行命令
+func (s *CommandSession) ExecuteCommand(command string) (string, error) {
+	return s.ExecuteCommandContext(context.Background(), command)
+}
+
+// ExecuteCommandContext 在会话中执行命令，上下文取消时中止执行
+// 自动选择执行器的会话在当前执行器失效（断开且无法重连）时切换到其他可用的执行器；
+// 失效前命令可能已经送达服务器，因此只重试只读的查询命令（见readOnlyCommand），其他命令返回原错误，由调用方决定是否重新执行
+func (s *CommandSession) ExecuteCommandContext(ctx context.Context, command string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// 等待会话中前一条命令期间上下文可能已被取消
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// 更新最后使用时间
+	s.lastUsed = time.Now()
+
+	// 执行命令
+	response, err := s.execute(ctx, command)
+	if err == nil || !s.auto || ctx.Err() != nil || s.executor.IsConnected() {
+		return response, err
+	}
+
+	if failoverErr := s.failover(); failoverErr != nil {
+		return "", fmt.Errorf("%w; 切换执行器失败: %w", err, failoverErr)
+	}
+	if !readOnlyCommand(command) {
+		return "", fmt.Errorf("%w; 已切换到%s执行器，命令可能已送达服务器，未重试", err, s.GetExecutorType())
+	}
+	return s.execute(ctx, command)
+}
+
+// readOnlyCommands 重复执行没有副作用的查询命令，键为命令名，值为要求的子命令（为空表示不限）
+var readOnlyCommands = map[string]string{
+	"list":        "",
+	"seed":        "",
+	"help":        "",
+	"version":     "",
+	"tps":         "",
+	"mspt":        "",
+	"banlist":     "",
+	"whitelist":   "list",
+	"time":        "query",
+	"data":        "get",
+	"worldborder": "get",
+}
+
+// readOnlyCommand 判断命令是否为只读的查询命令，执行器失效后可以安全地重试
+func readOnlyCommand(command string) bool {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(command), "/"))
+	if len(fields) == 0 {
+		return false
+	}
+	subcommand, ok := readOnlyCommands[strings.ToLower(fields[0])]
+	if !ok {
+		return false
+	}
+	return subcommand == "" || (len(fields) > 1 && strings.ToLower(fields[1]) == subcommand)
+}
+
+// execute 使用当前执行器执行命令并记录结果，调用方需持有锁
+func (s *CommandSession) execute(ctx context.Context, command string) (string, error) {
+	executorType := s.GetExecutorType()
+
+	start := time.Now()
+	response, err := s.executor.ExecuteCommandContext(ctx, command)
+	s.controller.observeCommand(executorType, start, err)
+	s.controller.executors.record(executorType, err)
+	return response, err
+}
+
+// failover 断开已失效的执行器并自动选择新的执行器，调用方需持有锁
+// 刚失效的执行器排在最后，其他执行器都不可用时才会再次尝试
+func (s *CommandSession) failover() error {
+	failed := s.GetExecutorType()
+	s.executor.Disconnect()
+
+	executor, err := s.controller.createAutoExecutor(true, s.target, failed)
+	if err != nil {
+		return err
+	}
+	if err := executor.Connect(); err != nil {
+		executor.Disconnect()
+		return fmt.Errorf("连接执行器失败: %v", err)
+	}
+
+	s.executor = executor
+	s.typeMutex.Lock()
+	s.executorType = executorTypeOf(executor)
+	s.typeMutex.Unlock()
+	return nil
+}
+
+// podSwitcher 由绑定到具体Pod的执行器实现，用于切换到新Pod
+type podSwitcher interface {
+	switchPod(podName string)
+}
+
+// switchPod 将会话的执行器切换到新Pod，等待正在执行的命令完成后切换，固定了Pod的会话不切换
+func (s *CommandSession) switchPod(podName string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.target != nil {
+		return
+	}
+	if switcher, ok := s.executor.(podSwitcher); ok {
+		switcher.switchPod(podName)
+	}
+}
+
+// Close 关闭会话
+func (s *CommandSession) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// 断开执行器连接
+	s.executor.Disconnect()
+}
+
+// IsIdle 检查会话是否空闲
+func (s *CommandSession) IsIdle() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return time.Since(s.lastUsed) > s.idleTimeout
+}
+
+// GetID 获取会话ID
+func (s *CommandSession) GetID() string {
+	return s.id
+}
+
+// GetPodName 获取会话固定使用的Pod名称，为空表示跟随控制器当前的Pod
+func (s *CommandSession) GetPodName() string {
+	if s.target == nil {
+		return ""
+	}
+	return s.target.name
+}
+
+// GetExecutorType 获取当前使用的执行器类型，自动选择的会话发生故障转移后会变化
+func (s *CommandSession) GetExecutorType() ExecutorType {
+	s.typeMutex.Lock()
+	defer s.typeMutex.Unlock()
+
+	return s.executorType
+}
+
+// SessionExecuteCommand 使用指定会话执行命令
+func (m *MinecraftController) SessionExecuteCommand(sessionID, command string) (string, error) {
+	return m.SessionExecuteCommandContext(context.Background(), sessionID, command)
+}
+
+// SessionExecuteCommandContext 使用指定会话执行命令，上下文取消时中止执行
+func (m *MinecraftController) SessionExecuteCommandContext(ctx context.Context, sessionID, command string) (string, error) {
+	m.sessionManager.mutex.Lock()
+	session, ok := m.sessionManager.sessions[sessionID]
+	m.sessionManager.mutex.Unlock()
+
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	return session.ExecuteCommandContext(ctx, command)
+}
+
+// SessionExecuteCommandDetailed 使用指定会话执行命令并返回详细信息
+// 执行失败时也会返回结果，以便调用方记录会话的执行器和耗时
+func (m *MinecraftController) SessionExecuteCommandDetailed(ctx context.Context, sessionID, command string) (*CommandResult, error) {
+	result := &CommandResult{SessionID: sessionID}
+
+	m.sessionManager.mutex.Lock()
+	session, ok := m.sessionManager.sessions[sessionID]
+	m.sessionManager.mutex.Unlock()
+
+	if !ok {
+		return result, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	start := time.Now()
+	response, err := session.ExecuteCommandContext(ctx, command)
+	result.ExecutorType = session.GetExecutorType()
+	result.PodName = session.GetPodName()
+	result.Duration = time.Since(start)
+	result.Response = response
+	return result, err
+}
+
+// CloseCommandSession 关闭指定的命令会话
+func (m *MinecraftController) CloseCommandSession(sessionID string) error {
+	m.sessionManager.mutex.Lock()
+	session, ok := m.sessionManager.sessions[sessionID]
+	if ok {
+		delete(m.sessionManager.sessions, sessionID)
+	}
+	m.sessionManager.mutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	session.Close()
+	return nil
+}
+
+// CloseAllCommandSessions 关闭所有命令会话
+func (m *MinecraftController) CloseAllCommandSessions() {
+	m.sessionManager.mutex.Lock()
+	sessions := make([]*CommandSession, 0, len(m.sessionManager.sessions))
+	for _, session := range m.sessionManager.sessions {
+		sessions = append(sessions, session)
+	}
+	m.sessionManager.sessions = make(map[string]*CommandSession)
+	m.sessionManager.mutex.Unlock()
+
+	// 关闭所有会话
+	for _, session := range sessions {
+		session.Close()
+	}
+}
+
+// ListCommandSessions 列出所有活跃的命令会话
+func (m *MinecraftController) ListCommandSessions() []string {
+	m.sessionManager.mutex.Lock()
+	defer m.sessionManager.mutex.Unlock()
+
+	ids := make([]string, 0, len(m.sessionManager.sessions))
+	for id := range m.sessionManager.sessions {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// switchPod 将所有会话切换到新Pod，各会话在后台切换，不等待正在执行的命令
+func (sm *sessionManager) switchPod(podName string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	for _, session := range sm.sessions {
+		go session.switchPod(podName)
+	}
+}
+
+// cleanupIdleSessions 清理空闲的会话
+func (sm *sessionManager) cleanupIdleSessions() {
+	sm.mutex.Lock()
+	idleSessions := make([]string, 0)
+
+	// 查找空闲会话
+	for id, session := range sm.sessions {
+		if session.IsIdle() {
+			idleSessions = append(idleSessions, id)
+		}
+	}
+
+	// 删除空闲会话
+	for _, id := range idleSessions {
+		session := sm.sessions[id]
+		delete(sm.sessions, id)
+		sm.mutex.Unlock() // 在可能阻塞的操作前解锁
+
+		// 关闭会话
+		session.Close()
+
+		sm.mutex.Lock() // 重新获取锁
+	}
+
+	sm.mutex.Unlock()
+}
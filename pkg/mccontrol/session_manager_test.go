@@ -0,0 +1,192 @@
+package mccontrol
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyCommand(t *testing.T) {
+	for command, want := range map[string]bool{
+		"list":                true,
+		"/list uuids":         true,
+		"  TPS ":              true,
+		"time query daytime":  true,
+		"whitelist list":      true,
+		"data get entity @p":  true,
+		"time set day":        false,
+		"whitelist add Steve": false,
+		"whitelist":           false,
+		"say hi":              false,
+		"give Steve diamond":  false,
+		"":                    false,
+	} {
+		if got := readOnlyCommand(command); got != want {
+			t.Errorf("readOnlyCommand(%q) = %v, 期望 %v", command, got, want)
+		}
+	}
+}
+
+// fakeExecutor 假的命令执行器，broken为true时命令失败并断开连接
+type fakeExecutor struct {
+	typ       ExecutorType
+	mutex     sync.Mutex
+	broken    bool
+	connected bool
+	commands  []string
+}
+
+func (e *fakeExecutor) executorType() ExecutorType {
+	return e.typ
+}
+
+func (e *fakeExecutor) ExecuteCommand(cmd string) (string, error) {
+	return e.ExecuteCommandContext(context.Background(), cmd)
+}
+
+func (e *fakeExecutor) ExecuteCommandContext(ctx context.Context, cmd string) (string, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.commands = append(e.commands, cmd)
+	if e.broken {
+		e.connected = false
+		return "", errExecutorBroken
+	}
+	return string(e.typ) + ": " + cmd, nil
+}
+
+func (e *fakeExecutor) Connect() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.broken {
+		return errExecutorBroken
+	}
+	e.connected = true
+	return nil
+}
+
+func (e *fakeExecutor) Disconnect() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.connected = false
+}
+
+func (e *fakeExecutor) IsConnected() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.connected
+}
+
+// received 返回执行器收到的命令
+func (e *fakeExecutor) received() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return append([]string(nil), e.commands...)
+}
+
+// setBroken 设置执行器之后的命令和连接是否失败
+func (e *fakeExecutor) setBroken(broken bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.broken = broken
+}
+
+// useFakeExecutors 让控制器创建假的执行器，每种类型只有一个实例
+func useFakeExecutors(controller *MinecraftController) map[ExecutorType]*fakeExecutor {
+	executors := make(map[ExecutorType]*fakeExecutor)
+	for _, executorType := range executorPriority {
+		executors[executorType] = &fakeExecutor{typ: executorType}
+	}
+	controller.newExecutor = func(executorType ExecutorType, persistent bool, target *podTarget) (CommandExecutor, error) {
+		executor := executors[executorType]
+		executor.mutex.Lock()
+		defer executor.mutex.Unlock()
+		if executor.broken {
+			return nil, errExecutorBroken
+		}
+		return executor, nil
+	}
+	return executors
+}
+
+func TestCommandSessionFailover(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon)
+	executors := useFakeExecutors(controller)
+
+	session, err := controller.CreateCommandSession(time.Minute, ExecutorAuto)
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	defer session.Close()
+	if session.GetExecutorType() != ExecutorRcon {
+		t.Fatalf("应优先使用RCON执行器: %s", session.GetExecutorType())
+	}
+
+	// 只读命令在执行器失效后切换到其他执行器重试
+	executors[ExecutorRcon].setBroken(true)
+	response, err := session.ExecuteCommand("list")
+	if err != nil || response != "attach: list" {
+		t.Fatalf("只读命令应在新执行器上重试: %q %v", response, err)
+	}
+	if session.GetExecutorType() != ExecutorAttach {
+		t.Fatalf("应切换到attach执行器: %s", session.GetExecutorType())
+	}
+
+	// 其他命令可能已经送达服务器，切换执行器后不重试
+	executors[ExecutorAttach].setBroken(true)
+	_, err = session.ExecuteCommand("give Steve diamond")
+	if !errors.Is(err, errExecutorBroken) || !strings.Contains(err.Error(), "未重试") {
+		t.Fatalf("非只读命令应返回原错误且不重试: %v", err)
+	}
+	// RCON仍在冷却，切换到exec执行器
+	if session.GetExecutorType() != ExecutorExec {
+		t.Fatalf("应切换到exec执行器: %s", session.GetExecutorType())
+	}
+	if commands := executors[ExecutorExec].received(); len(commands) != 0 {
+		t.Fatalf("新执行器不应执行未重试的命令: %v", commands)
+	}
+	if response, err := session.ExecuteCommand("say hi"); err != nil || response != "exec: say hi" {
+		t.Fatalf("切换后应使用新执行器: %q %v", response, err)
+	}
+
+	// 所有执行器都不可用时返回切换失败的原因
+	for _, executor := range executors {
+		executor.setBroken(true)
+	}
+	_, err = session.ExecuteCommand("list")
+	var selectionErr *ExecutorSelectionError
+	if !errors.As(err, &selectionErr) || !strings.Contains(err.Error(), "切换执行器失败") {
+		t.Fatalf("应返回切换执行器失败的原因: %v", err)
+	}
+}
+
+func TestCommandSessionFixedExecutorNoFailover(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon)
+	executors := useFakeExecutors(controller)
+
+	session, err := controller.CreateCommandSession(time.Minute, ExecutorRcon)
+	if err != nil {
+		t.Fatalf("创建会话失败: %v", err)
+	}
+	defer session.Close()
+
+	// 指定了执行器类型的会话不切换执行器
+	executors[ExecutorRcon].setBroken(true)
+	if _, err := session.ExecuteCommand("list"); !errors.Is(err, errExecutorBroken) {
+		t.Fatalf("应返回执行器的错误: %v", err)
+	}
+	if session.GetExecutorType() != ExecutorRcon || len(executors[ExecutorAttach].received()) != 0 {
+		t.Fatalf("指定了执行器类型的会话不应切换执行器: %s", session.GetExecutorType())
+	}
+}
@@ -0,0 +1,76 @@
+package mccontrol
+
+import (
+	"time"
+)
+
+// podName 返回当前Pod的名称
+func (m *MinecraftController) podName() string {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.currentPodName
+}
+
+// podIP 返回当前Pod的IP
+func (m *MinecraftController) podIP() string {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.serverIP
+}
+
+// podInfoFresh 检查距上次更新Pod信息是否还未超过更新间隔
+func (m *MinecraftController) podInfoFresh() bool {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return time.Since(m.lastPodInfoUpdate) < m.podInfoUpdateInterval
+}
+
+// markPodInfoUpdated 记录Pod信息已更新
+func (m *MinecraftController) markPodInfoUpdated() {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	m.lastPodInfoUpdate = time.Now()
+}
+
+// updateStatus 在锁内修改服务器状态，返回修改后状态的副本
+func (m *MinecraftController) updateStatus(update func(status *ServerStatus)) ServerStatus {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	update(&m.status)
+	return m.status.clone()
+}
+
+// statusSnapshot 返回服务器状态的副本，副本与控制器不共享任何可变数据
+func (m *MinecraftController) statusSnapshot() ServerStatus {
+	m.stateMutex.RLock()
+	defer m.stateMutex.RUnlock()
+
+	return m.status.clone()
+}
+
+// clone 复制服务器状态，包括玩家样本和Pod状态
+func (s ServerStatus) clone() ServerStatus {
+	if s.PlayerSample != nil {
+		s.PlayerSample = append([]MCOnlinePlayer(nil), s.PlayerSample...)
+	}
+	if s.LastTermination != nil {
+		termination := *s.LastTermination
+		s.LastTermination = &termination
+	}
+	if s.Conditions != nil {
+		s.Conditions = append([]PodCondition(nil), s.Conditions...)
+	}
+	if s.Events != nil {
+		s.Events = append([]PodEvent(nil), s.Events...)
+	}
+	if s.Usage != nil {
+		usage := *s.Usage
+		s.Usage = &usage
+	}
+	return s
+}
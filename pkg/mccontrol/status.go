@@ -1,108 +1,172 @@
-package mccontrol
-
-import (
-	"fmt"
-	"time"
-
-	"github.com/bytedance/sonic"
-	"github.com/xrjr/mcutils/pkg/ping"
-)
-
-// CheckServerStatus 检查服务器状态
-func (m *MinecraftController) CheckServerStatus() (*ServerStatus, error) {
-	// 更新Pod状态
-	_, err := m.updatePodInfoIfNeeded(false)
-	if err != nil {
-		m.status.LastError = fmt.Sprintf("更新Pod信息失败: %v", err)
-		return &m.status, err
-	}
-
-	// 检查Minecraft服务器状态
-	properties, latency, err := ping.Ping(m.serverIP, m.gamePort)
-	if err != nil {
-		// 如果Ping失败，可能是Pod信息已过期，尝试强制更新一次
-		updated, updateErr := m.updatePodInfoIfNeeded(true)
-		if updated && updateErr == nil {
-			// 更新成功后重试Ping
-			properties, latency, err = ping.Ping(m.serverIP, m.gamePort)
-			if err != nil {
-				m.status.Online = false
-				m.status.LastError = fmt.Sprintf("即使更新Pod信息后，Ping服务器仍然失败: %v", err)
-				m.status.LastChecked = time.Now()
-				return &m.status, nil
-			}
-		} else {
-			// Ping失败且无法更新Pod信息
-			m.status.Online = false
-			m.status.LastError = fmt.Sprintf("Ping服务器失败: %v", err)
-			m.status.LastChecked = time.Now()
-			return &m.status, nil
-		}
-	}
-
-	m.status.Online = true
-	m.status.Latency = int(latency)
-	m.status.LastChecked = time.Now()
-	m.status.LastError = ""
-
-	// 使用 sonic 解析 JSON 数据
-	var mcStatus MinecraftStatus
-
-	// 将 map[string]interface{} 转换为 JSON 字符串
-	jsonData, err := sonic.Marshal(properties)
-	if err != nil {
-		m.status.LastError = fmt.Sprintf("序列化服务器属性失败: %v", err)
-		return &m.status, fmt.Errorf("序列化服务器属性失败: %v", err)
-	}
-
-	// 解析 JSON 到结构体
-	if err := sonic.Unmarshal(jsonData, &mcStatus); err != nil {
-		m.status.LastError = fmt.Sprintf("解析服务器状态失败: %v", err)
-		return &m.status, fmt.Errorf("解析服务器状态失败: %v", err)
-	}
-
-	// 从解析后的结构体设置状态信息
-	if mcStatus.Version.Name != "" {
-		m.status.Version = mcStatus.Version.Name
-	}
-
-	m.status.Players = mcStatus.Players.Online
-	m.status.MaxPlayers = mcStatus.Players.Max
-
-	// 使用辅助方法从不同格式的描述字段中提取文本
-	descText := mcStatus.GetDescriptionText()
-	if descText != "" {
-		m.status.Description = descText
-	} else {
-		// 兼容性处理：如果辅助方法无法提取文本，尝试直接处理原始properties中的描述
-		if desc, ok := properties["description"]; ok {
-			switch d := desc.(type) {
-			case string:
-				m.status.Description = d
-			case map[string]interface{}:
-				if text, ok := d["text"].(string); ok {
-					m.status.Description = text
-				}
-			}
-		}
-	}
-
-	return &m.status, nil
-}
-
-// StartStatusMonitoring 定期监控服务器状态
-func (m *MinecraftController) StartStatusMonitoring(interval time.Duration) {
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-m.ctx.Done():
-				return
-			case <-ticker.C:
-				m.CheckServerStatus()
-			}
-		}
-	}()
-}
+package mccontrol
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/xrjr/mcutils/pkg/ping"
+)
+
+// CheckServerStatus 检查服务器状态
+// 返回检测结果的副本，可以安全地保存和修改，不影响控制器的状态
+func (m *MinecraftController) CheckServerStatus() (*ServerStatus, error) {
+	return m.checkStatus(false)
+}
+
+// checkStatus 检查服务器状态并通知事件总线和闲置检测，monitoring为true时（定期监控）还会调用状态记录函数
+func (m *MinecraftController) checkStatus(monitoring bool) (*ServerStatus, error) {
+	status, err := m.checkServerStatus()
+	// 将检测结果提供给事件总线，用于生成玩家和服务器状态事件
+	m.events.observeStatus(status.clone())
+	m.observeIdle(status)
+	if monitoring {
+		m.stateMutex.RLock()
+		recorder := m.statusRecorder
+		m.stateMutex.RUnlock()
+		if recorder != nil {
+			recorder(status.clone())
+		}
+	}
+	return &status, err
+}
+
+// SetStatusRecorder 设置状态记录函数，StartStatusMonitoring每次定期检测完成后以检测结果调用，
+// 按需调用CheckServerStatus（如接口请求、重启等待）的结果不记录，历史记录的间隔与监控间隔一致
+// 可用于持久化状态历史，为nil则不记录
+func (m *MinecraftController) SetStatusRecorder(recorder func(ServerStatus)) {
+	m.stateMutex.Lock()
+	defer m.stateMutex.Unlock()
+
+	m.statusRecorder = recorder
+}
+
+// checkServerStatus 执行一次状态检测，检测结果一次性写入控制器状态，返回写入后状态的副本
+// 多个协程同时检测时，每个返回值都是某一次检测完整写入后的状态
+func (m *MinecraftController) checkServerStatus() (ServerStatus, error) {
+	// 获取Pod的Kubernetes状态，与检测结果一起写入
+	update := func(result func(status *ServerStatus)) ServerStatus {
+		details := m.collectPodDetails()
+		return m.updateStatus(func(status *ServerStatus) {
+			details.apply(status)
+			// 有Pod时服务器没有缩容到0
+			status.ScaledDown = false
+			result(status)
+		})
+	}
+
+	// 更新Pod状态
+	if _, err := m.updatePodInfoIfNeeded(false); err != nil {
+		var checkErr error
+		scaledDown := m.checkScaledDown()
+		status := update(func(status *ServerStatus) {
+			status.ScaledDown = scaledDown
+			if scaledDown {
+				// 缩容到0后没有Pod是正常状态
+				markOffline(status, "服务器已缩容到0")
+				return
+			}
+			status.LastError = fmt.Sprintf("更新Pod信息失败: %v", err)
+			checkErr = err
+		})
+		return status, checkErr
+	}
+
+	// 检查Minecraft服务器状态
+	properties, latency, err := ping.Ping(m.podIP(), m.gamePort)
+	if err != nil {
+		// 如果Ping失败，可能是Pod信息已过期，尝试强制更新一次
+		updated, updateErr := m.updatePodInfoIfNeeded(true)
+		if !updated || updateErr != nil {
+			// Ping失败且无法更新Pod信息
+			message := fmt.Sprintf("Ping服务器失败: %v", err)
+			return update(func(status *ServerStatus) { markOffline(status, message) }), nil
+		}
+		// 更新成功后重试Ping
+		properties, latency, err = ping.Ping(m.podIP(), m.gamePort)
+		if err != nil {
+			message := fmt.Sprintf("即使更新Pod信息后，Ping服务器仍然失败: %v", err)
+			return update(func(status *ServerStatus) { markOffline(status, message) }), nil
+		}
+	}
+
+	// 服务器可以Ping通，解析失败时仍记录为在线
+	markOnline := func(status *ServerStatus, lastError string) {
+		status.Online = true
+		status.Latency = int(latency)
+		status.LastChecked = time.Now()
+		status.LastError = lastError
+	}
+
+	// 使用 sonic 解析 JSON 数据
+	var mcStatus MinecraftStatus
+
+	// 将 map[string]interface{} 转换为 JSON 字符串
+	jsonData, err := sonic.Marshal(properties)
+	if err != nil {
+		message := fmt.Sprintf("序列化服务器属性失败: %v", err)
+		return update(func(status *ServerStatus) { markOnline(status, message) }), errors.New(message)
+	}
+
+	// 解析 JSON 到结构体
+	if err := sonic.Unmarshal(jsonData, &mcStatus); err != nil {
+		message := fmt.Sprintf("解析服务器状态失败: %v", err)
+		return update(func(status *ServerStatus) { markOnline(status, message) }), errors.New(message)
+	}
+
+	// 使用辅助方法从不同格式的描述字段中提取文本
+	description := mcStatus.GetDescriptionText()
+	if description == "" {
+		// 兼容性处理：如果辅助方法无法提取文本，尝试直接处理原始properties中的描述
+		if desc, ok := properties["description"]; ok {
+			switch d := desc.(type) {
+			case string:
+				description = d
+			case map[string]interface{}:
+				if text, ok := d["text"].(string); ok {
+					description = text
+				}
+			}
+		}
+	}
+
+	// 从解析后的结构体设置状态信息
+	return update(func(status *ServerStatus) {
+		markOnline(status, "")
+		if mcStatus.Version.Name != "" {
+			status.Version = mcStatus.Version.Name
+		}
+		status.Players = mcStatus.Players.Online
+		status.MaxPlayers = mcStatus.Players.Max
+		status.PlayerSample = mcStatus.Players.Sample
+		if description != "" {
+			status.Description = description
+		}
+	}), nil
+}
+
+// markOffline 将状态标记为离线
+func markOffline(status *ServerStatus, lastError string) {
+	status.Online = false
+	status.PlayerSample = nil
+	status.LastError = lastError
+	status.LastChecked = time.Now()
+}
+
+// StartStatusMonitoring 定期监控服务器状态
+func (m *MinecraftController) StartStatusMonitoring(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkStatus(true)
+			}
+		}
+	}()
+}
@@ -0,0 +1,40 @@
+package mccontrol
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatusRecorderOnlyRecordsMonitoring(t *testing.T) {
+	rcon := newFakeRconServer(t, "secret", echoHandler)
+	controller, _ := newTestController(t, rcon, testServerPod("mc-0", "127.0.0.1"))
+
+	var recorded atomic.Int32
+	controller.SetStatusRecorder(func(status ServerStatus) {
+		if status.PodName != "mc-0" {
+			t.Errorf("记录的状态错误: %+v", status)
+		}
+		recorded.Add(1)
+	})
+
+	// 按需的状态检测不记录历史
+	for i := 0; i < 3; i++ {
+		if _, err := controller.CheckServerStatus(); err != nil {
+			t.Fatalf("状态检测失败: %v", err)
+		}
+	}
+	if n := recorded.Load(); n != 0 {
+		t.Fatalf("按需的状态检测不应记录历史, 实际记录了%d次", n)
+	}
+
+	// 定期监控的检测结果被记录
+	controller.StartStatusMonitoring(10 * time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for recorded.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if recorded.Load() == 0 {
+		t.Fatal("定期监控的检测结果应被记录")
+	}
+}
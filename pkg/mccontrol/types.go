@@ -1,191 +1,246 @@
-package mccontrol
-
-import (
-	"time"
-)
-
-// CommandExecutor 命令执行器接口
-type CommandExecutor interface {
-	// ExecuteCommand 执行命令并返回结果
-	ExecuteCommand(cmd string) (string, error)
-
-	// Connect 连接到服务器
-	Connect() error
-
-	// Disconnect 断开与服务器的连接
-	Disconnect()
-
-	// IsConnected 检查是否已连接
-	IsConnected() bool
-}
-
-// ExecutorType 表示命令执行器的类型
-type ExecutorType string
-
-const (
-	// ExecutorRcon 使用RCON协议执行命令
-	ExecutorRcon ExecutorType = "rcon"
-
-	// ExecutorAttach 使用kubectl attach执行命令
-	ExecutorAttach ExecutorType = "attach"
-
-	// ExecutorExec 使用kubectl exec + 重定向到/proc/1/fd/0执行命令
-	ExecutorExec ExecutorType = "exec"
-
-	// ExecutorAuto 自动选择最合适的执行器
-	ExecutorAuto ExecutorType = "auto"
-)
-
-// MinecraftStatusData 相关结构体 - 用于解析Ping返回的JSON数据
-
-// MCModInfo 表示Minecraft模组信息
-type MCModInfo struct {
-	ID string `json:"modid"` // 模组ID
-}
-
-// MCDescriptionExtraItem 表示描述中的额外格式化文本项
-type MCDescriptionExtraItem struct {
-	Text  string `json:"text"`  // 文本内容
-	Color string `json:"color"` // 颜色
-}
-
-// MCOnlinePlayer 表示在线玩家信息
-type MCOnlinePlayer struct {
-	ID   string `json:"id"`   // 玩家UUID
-	Name string `json:"name"` // 玩家名称
-}
-
-// Version 表示服务器版本信息
-type Version struct {
-	Name     string `json:"name"`     // 版本名称
-	Protocol int    `json:"protocol"` // 协议版本
-}
-
-// Players 表示玩家信息
-type Players struct {
-	Max    int              `json:"max"`    // 最大玩家数
-	Online int              `json:"online"` // 在线玩家数
-	Sample []MCOnlinePlayer `json:"sample"` // 在线玩家样本
-}
-
-// ModInfo 表示模组信息
-type ModInfo struct {
-	Type    string      `json:"type"`    // 模组类型
-	ModList []MCModInfo `json:"modList"` // 模组列表
-}
-
-// Description 表示服务器描述
-type Description struct {
-	Text  string                   `json:"text"`  // 纯文本描述
-	Extra []MCDescriptionExtraItem `json:"extra"` // 额外格式化文本
-}
-
-// RawDescription 用于处理不同格式的描述字段
-type RawDescription struct {
-	Description interface{} `json:"description"` // 可能是字符串或对象
-}
-
-// MinecraftStatus 表示Minecraft服务器状态的完整数据结构
-type MinecraftStatus struct {
-	Version     Version     `json:"version"`     // 版本信息
-	Players     Players     `json:"players"`     // 玩家信息
-	Description interface{} `json:"description"` // 服务器描述，可能是字符串或对象
-	Favicon     string      `json:"favicon"`     // 服务器图标（Base64编码）
-	ModInfo     ModInfo     `json:"modinfo"`     // 模组信息
-}
-
-// GetDescriptionText 从不同格式的描述字段中提取纯文本
-func (m *MinecraftStatus) GetDescriptionText() string {
-	// 处理不同类型的描述
-	switch desc := m.Description.(type) {
-	case string:
-		// 直接是字符串的情况
-		return desc
-	case map[string]interface{}:
-		// 是对象的情况
-		if text, ok := desc["text"].(string); ok {
-			var result string = text
-
-			// 处理可能存在的额外文本
-			if extra, ok := desc["extra"].([]interface{}); ok {
-				for _, item := range extra {
-					if extraItem, ok := item.(map[string]interface{}); ok {
-						if extraText, ok := extraItem["text"].(string); ok {
-							result += extraText
-						}
-					}
-				}
-			}
-
-			return result
-		}
-	}
-
-	// 默认返回空字符串
-	return ""
-}
-
-// ServerStatus 包含Minecraft服务器状态信息
-type ServerStatus struct {
-	// 基本状态
-
-	Online      bool      // 服务器是否在线
-	LastChecked time.Time // 最后检查时间
-	LastError   string    // 最后一次错误信息
-
-	// 服务器信息
-
-	Players     int    // 当前在线玩家数量
-	MaxPlayers  int    // 最大玩家数量
-	Version     string // 服务器版本
-	Description string // 服务器描述
-	Latency     int    // 延迟，单位：毫秒
-
-	// Kubernetes信息
-
-	PodName    string // Pod名称
-	PodStatus  string // Pod状态
-	ClusterIP  string // 集群内IP
-	ExternalIP string // 外部IP（如果有）
-}
-
-// LogOptions 包含日志获取的配置选项
-type LogOptions struct {
-	// 日志范围选项
-
-	TailLines *int64     // 获取最近多少行日志，为nil则不限制行数
-	SinceTime *time.Time // 从何时开始获取日志，为nil则不限制起始时间
-	UntilTime *time.Time // 获取到何时的日志，为nil则不限制结束时间
-
-	// 容器选项
-
-	Container string // 容器名称，为空则使用默认容器
-	Previous  bool   // 是否获取以前终止的容器的日志
-
-	// 回调相关选项
-
-	BatchSize   int           // 批量回调大小，每收集到这么多行日志就触发一次回调，默认为10
-	MaxWaitTime time.Duration // 最大等待时间，即使缓冲区未满，但过了这个时间也会触发回调，默认为1秒
-
-	// 控制选项
-
-	StopSignal <-chan struct{} // 用于主动停止流式日志监听的信号通道
-}
-
-// K8sConfig 包含Kubernetes配置选项
-type K8sConfig struct {
-	// 连接配置
-
-	RunMode        string // 运行模式：InCluster（集群内）或OutOfCluster（集群外）
-	KubeconfigPath string // 当RunMode为OutOfCluster时使用的kubeconfig文件路径
-	Namespace      string // 命名空间
-
-	// 资源选择器
-
-	PodLabelSelector     string // 用于选择Pod的标签（如app=minecraft）
-	ServiceLabelSelector string // 用于选择Service的标签，为空则使用PodLabelSelector
-
-	// 容器配置
-
-	ContainerName string // 容器名称（在Pod中）
-}
+package mccontrol
+
+import (
+	"context"
+	"time"
+)
+
+// CommandExecutor 命令执行器接口
+type CommandExecutor interface {
+	// ExecuteCommand 执行命令并返回结果
+	ExecuteCommand(cmd string) (string, error)
+
+	// ExecuteCommandContext 执行命令并返回结果，上下文取消时中止执行和重试
+	ExecuteCommandContext(ctx context.Context, cmd string) (string, error)
+
+	// Connect 连接到服务器
+	Connect() error
+
+	// Disconnect 断开与服务器的连接
+	Disconnect()
+
+	// IsConnected 检查是否已连接
+	IsConnected() bool
+}
+
+// ExecutorType 表示命令执行器的类型
+type ExecutorType string
+
+const (
+	// ExecutorRcon 使用RCON协议执行命令
+	ExecutorRcon ExecutorType = "rcon"
+
+	// ExecutorAttach 使用kubectl attach执行命令
+	ExecutorAttach ExecutorType = "attach"
+
+	// ExecutorExec 使用kubectl exec + 重定向到/proc/1/fd/0执行命令
+	ExecutorExec ExecutorType = "exec"
+
+	// ExecutorAuto 自动选择最合适的执行器
+	ExecutorAuto ExecutorType = "auto"
+)
+
+// BestEffortResponse 执行器返回的响应是否只是尽力收集的结果
+// RCON的响应与命令一一对应；attach和exec执行器按时间窗口收集服务器输出，
+// 响应可能混入同时产生的其他日志，也可能缺少输出较慢的部分
+func (t ExecutorType) BestEffortResponse() bool {
+	return t == ExecutorAttach || t == ExecutorExec
+}
+
+// CommandResult 命令执行的详细结果
+type CommandResult struct {
+	Response     string        // 命令响应
+	ExecutorType ExecutorType  // 实际使用的执行器类型，执行器创建失败时为空
+	SessionID    string        // 命令会话ID，一次性执行时为空
+	PodName      string        // 固定在指定Pod上执行时的Pod名称，使用控制器当前的Pod时为空
+	Duration     time.Duration // 执行耗时
+}
+
+// MinecraftStatusData 相关结构体 - 用于解析Ping返回的JSON数据
+
+// MCModInfo 表示Minecraft模组信息
+type MCModInfo struct {
+	ID string `json:"modid"` // 模组ID
+}
+
+// MCDescriptionExtraItem 表示描述中的额外格式化文本项
+type MCDescriptionExtraItem struct {
+	Text  string `json:"text"`  // 文本内容
+	Color string `json:"color"` // 颜色
+}
+
+// MCOnlinePlayer 表示在线玩家信息
+type MCOnlinePlayer struct {
+	ID   string `json:"id"`   // 玩家UUID
+	Name string `json:"name"` // 玩家名称
+}
+
+// Version 表示服务器版本信息
+type Version struct {
+	Name     string `json:"name"`     // 版本名称
+	Protocol int    `json:"protocol"` // 协议版本
+}
+
+// Players 表示玩家信息
+type Players struct {
+	Max    int              `json:"max"`    // 最大玩家数
+	Online int              `json:"online"` // 在线玩家数
+	Sample []MCOnlinePlayer `json:"sample"` // 在线玩家样本
+}
+
+// ModInfo 表示模组信息
+type ModInfo struct {
+	Type    string      `json:"type"`    // 模组类型
+	ModList []MCModInfo `json:"modList"` // 模组列表
+}
+
+// Description 表示服务器描述
+type Description struct {
+	Text  string                   `json:"text"`  // 纯文本描述
+	Extra []MCDescriptionExtraItem `json:"extra"` // 额外格式化文本
+}
+
+// RawDescription 用于处理不同格式的描述字段
+type RawDescription struct {
+	Description interface{} `json:"description"` // 可能是字符串或对象
+}
+
+// MinecraftStatus 表示Minecraft服务器状态的完整数据结构
+type MinecraftStatus struct {
+	Version     Version     `json:"version"`     // 版本信息
+	Players     Players     `json:"players"`     // 玩家信息
+	Description interface{} `json:"description"` // 服务器描述，可能是字符串或对象
+	Favicon     string      `json:"favicon"`     // 服务器图标（Base64编码）
+	ModInfo     ModInfo     `json:"modinfo"`     // 模组信息
+}
+
+// GetDescriptionText 从不同格式的描述字段中提取纯文本
+func (m *MinecraftStatus) GetDescriptionText() string {
+	// 处理不同类型的描述
+	switch desc := m.Description.(type) {
+	case string:
+		// 直接是字符串的情况
+		return desc
+	case map[string]interface{}:
+		// 是对象的情况
+		if text, ok := desc["text"].(string); ok {
+			var result string = text
+
+			// 处理可能存在的额外文本
+			if extra, ok := desc["extra"].([]interface{}); ok {
+				for _, item := range extra {
+					if extraItem, ok := item.(map[string]interface{}); ok {
+						if extraText, ok := extraItem["text"].(string); ok {
+							result += extraText
+						}
+					}
+				}
+			}
+
+			return result
+		}
+	}
+
+	// 默认返回空字符串
+	return ""
+}
+
+// ServerStatus 包含Minecraft服务器状态信息
+type ServerStatus struct {
+	// 基本状态
+
+	Online      bool      `json:"online"`       // 服务器是否在线
+	LastChecked time.Time `json:"last_checked"` // 最后检查时间
+	LastError   string    `json:"last_error"`   // 最后一次错误信息
+
+	// 服务器信息
+
+	Players     int    `json:"players"`     // 当前在线玩家数量
+	MaxPlayers  int    `json:"max_players"` // 最大玩家数量
+	Version     string `json:"version"`     // 服务器版本
+	Description string `json:"description"` // 服务器描述
+	Latency     int    `json:"latency"`     // 延迟，单位：毫秒
+
+	PlayerSample []MCOnlinePlayer `json:"player_sample,omitempty"` // 在线玩家样本，服务器最多返回部分玩家
+
+	// Kubernetes信息
+
+	PodName    string `json:"pod_name"`    // Pod名称
+	PodStatus  string `json:"pod_status"`  // Pod状态
+	ClusterIP  string `json:"cluster_ip"`  // 集群内IP
+	ExternalIP string `json:"external_ip"` // 外部IP（如果有）
+	ScaledDown bool   `json:"scaled_down"` // 是否已由控制台缩容到0，根据工作负载的副本数和缩容记录确定
+	PinnedPod  string `json:"pinned_pod"`  // 固定使用的Pod名称，为空表示自动选择
+
+	// Pod状态，来自Kubernetes API
+
+	NodeName        string                `json:"node_name"`                  // Pod所在节点
+	Ready           bool                  `json:"ready"`                      // Pod是否就绪
+	Restarts        int32                 `json:"restarts"`                   // 容器重启次数
+	LastTermination *ContainerTermination `json:"last_termination,omitempty"` // 容器上一次终止的信息（如OOMKilled），没有终止过时为空
+	Conditions      []PodCondition        `json:"conditions,omitempty"`       // Pod状态条件
+	Events          []PodEvent            `json:"events,omitempty"`           // Pod的最近事件，按时间倒序
+	Usage           *ResourceUsage        `json:"usage,omitempty"`            // CPU和内存用量，集群未安装metrics-server时为空
+}
+
+// LogOptions 包含日志获取的配置选项
+type LogOptions struct {
+	// 日志范围选项
+
+	TailLines *int64     // 获取最近多少行日志，为nil则不限制行数
+	SinceTime *time.Time // 从何时开始获取日志，为nil则不限制起始时间
+	UntilTime *time.Time // 获取到何时的日志，为nil则不限制结束时间
+
+	// 容器选项
+
+	PodName   string // Pod名称，为空则使用控制器当前的Pod并在Pod切换时跟随；指定后只读取该Pod的日志
+	Container string // 容器名称，为空则使用默认容器
+	Previous  bool   // 是否获取以前终止的容器的日志
+
+	// 回调相关选项
+
+	BatchSize   int           // 批量回调大小，每收集到这么多行日志就触发一次回调，默认为10
+	MaxWaitTime time.Duration // 最大等待时间，即使缓冲区未满，但过了这个时间也会触发回调，默认为1秒
+
+	// 输出选项
+
+	Timestamps bool // 是否在返回的日志行前保留Kubernetes时间戳（RFC3339Nano格式），可用SplitLogTimestamp拆分
+
+	// 控制选项
+
+	StopSignal <-chan struct{} // 用于主动停止流式日志监听的信号通道
+	OnClose    func()          // 流式日志监听启动后，监听结束时调用（无论何种原因），为nil则忽略
+	Context    context.Context // 日志请求的上下文，取消后立即中止请求（包括阻塞中的读取），为nil则只受控制器生命周期约束
+}
+
+// K8sConfig 包含Kubernetes配置选项
+type K8sConfig struct {
+	// 连接配置
+
+	RunMode        string // 运行模式：InCluster（集群内）或OutOfCluster（集群外）
+	KubeconfigPath string // 当RunMode为OutOfCluster时使用的kubeconfig文件路径
+	Namespace      string // 命名空间
+
+	// 资源选择器
+
+	PodLabelSelector     string // 用于选择Pod的标签（如app=minecraft）
+	ServiceLabelSelector string // 用于选择Service的标签，为空则使用PodLabelSelector
+
+	// 容器配置
+
+	ContainerName string // 容器名称（在Pod中）
+
+	// Pod跟踪
+
+	DisablePodWatch bool // 不使用informer跟踪Pod（如ServiceAccount没有watch权限），改为按需通过List请求获取Pod信息
+
+	// RCON密码来源
+
+	// 按Secret、容器环境变量、server.properties的顺序读取，都读取失败时使用直接传入的密码；
+	// 认证失败时会重新读取，密码轮换后无需重启控制台
+
+	RconSecretName     string // 保存RCON密码的Secret名称
+	RconSecretKey      string // Secret中保存密码的键名，为空则使用rcon-password
+	RconPasswordEnv    string // 保存RCON密码的容器环境变量名称（如itzg/minecraft-server的RCON_PASSWORD）
+	RconPropertiesPath string // 容器内server.properties的路径（如/data/server.properties），从rcon.password读取密码
+}
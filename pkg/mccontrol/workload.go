@@ -0,0 +1,149 @@
+package mccontrol
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// 支持的工作负载类型
+const (
+	workloadStatefulSet = "StatefulSet"
+	workloadDeployment  = "Deployment"
+)
+
+// workloadRef 表示管理服务器Pod的工作负载
+type workloadRef struct {
+	kind string // StatefulSet或Deployment
+	name string
+}
+
+// String 返回用于消息的工作负载描述
+func (w workloadRef) String() string {
+	return fmt.Sprintf("%s '%s'", w.kind, w.name)
+}
+
+// findWorkload 查找Pod所属的StatefulSet或Deployment
+func (m *MinecraftController) findWorkload(ctx context.Context, pod *corev1.Pod) (workloadRef, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner != nil {
+		switch owner.Kind {
+		case workloadStatefulSet:
+			return workloadRef{kind: owner.Kind, name: owner.Name}, nil
+		case "ReplicaSet":
+			replicaSet, err := m.clientset.AppsV1().ReplicaSets(m.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+			if err != nil {
+				return workloadRef{}, fmt.Errorf("获取ReplicaSet '%s' 失败: %v", owner.Name, err)
+			}
+			if deployment := metav1.GetControllerOf(replicaSet); deployment != nil && deployment.Kind == workloadDeployment {
+				return workloadRef{kind: deployment.Kind, name: deployment.Name}, nil
+			}
+		}
+	}
+	return workloadRef{}, fmt.Errorf("Pod '%s' 不属于StatefulSet或Deployment", pod.Name)
+}
+
+// findWorkloadBySelector 查找Pod模板标签匹配Pod标签选择器的StatefulSet或Deployment
+// 用于没有Pod（如已缩容到0）时确定工作负载，必须恰好匹配一个
+func (m *MinecraftController) findWorkloadBySelector(ctx context.Context) (workloadRef, error) {
+	selector, err := labels.Parse(m.podLabelSelector)
+	if err != nil {
+		return workloadRef{}, fmt.Errorf("解析标签选择器 '%s' 失败: %v", m.podLabelSelector, err)
+	}
+
+	var found []workloadRef
+	statefulSets, err := m.clientset.AppsV1().StatefulSets(m.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return workloadRef{}, fmt.Errorf("获取StatefulSet列表失败: %v", err)
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if selector.Matches(labels.Set(statefulSet.Spec.Template.Labels)) {
+			found = append(found, workloadRef{kind: workloadStatefulSet, name: statefulSet.Name})
+		}
+	}
+	deployments, err := m.clientset.AppsV1().Deployments(m.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return workloadRef{}, fmt.Errorf("获取Deployment列表失败: %v", err)
+	}
+	for _, deployment := range deployments.Items {
+		if selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			found = append(found, workloadRef{kind: workloadDeployment, name: deployment.Name})
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return workloadRef{}, fmt.Errorf("未找到Pod模板匹配标签 '%s' 的StatefulSet或Deployment", m.podLabelSelector)
+	case 1:
+		return found[0], nil
+	default:
+		return workloadRef{}, fmt.Errorf("有%d个StatefulSet或Deployment的Pod模板匹配标签 '%s'", len(found), m.podLabelSelector)
+	}
+}
+
+// patchWorkload 修改工作负载
+func (m *MinecraftController) patchWorkload(ctx context.Context, workload workloadRef, patchType types.PatchType, data []byte) error {
+	var err error
+	switch workload.kind {
+	case workloadStatefulSet:
+		_, err = m.clientset.AppsV1().StatefulSets(m.namespace).Patch(ctx, workload.name, patchType, data, metav1.PatchOptions{})
+	case workloadDeployment:
+		_, err = m.clientset.AppsV1().Deployments(m.namespace).Patch(ctx, workload.name, patchType, data, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("不支持的工作负载类型: %s", workload.kind)
+	}
+	return err
+}
+
+// workloadAnnotations 获取工作负载的注解
+func (m *MinecraftController) workloadAnnotations(ctx context.Context, workload workloadRef) (map[string]string, error) {
+	var meta metav1.ObjectMeta
+	switch workload.kind {
+	case workloadStatefulSet:
+		statefulSet, err := m.clientset.AppsV1().StatefulSets(m.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		meta = statefulSet.ObjectMeta
+	case workloadDeployment:
+		deployment, err := m.clientset.AppsV1().Deployments(m.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		meta = deployment.ObjectMeta
+	default:
+		return nil, fmt.Errorf("不支持的工作负载类型: %s", workload.kind)
+	}
+	return meta.Annotations, nil
+}
+
+// getScale 获取工作负载的副本数
+func (m *MinecraftController) getScale(ctx context.Context, workload workloadRef) (*autoscalingv1.Scale, error) {
+	switch workload.kind {
+	case workloadStatefulSet:
+		return m.clientset.AppsV1().StatefulSets(m.namespace).GetScale(ctx, workload.name, metav1.GetOptions{})
+	case workloadDeployment:
+		return m.clientset.AppsV1().Deployments(m.namespace).GetScale(ctx, workload.name, metav1.GetOptions{})
+	default:
+		return nil, fmt.Errorf("不支持的工作负载类型: %s", workload.kind)
+	}
+}
+
+// updateScale 修改工作负载的副本数
+func (m *MinecraftController) updateScale(ctx context.Context, workload workloadRef, scale *autoscalingv1.Scale) error {
+	var err error
+	switch workload.kind {
+	case workloadStatefulSet:
+		_, err = m.clientset.AppsV1().StatefulSets(m.namespace).UpdateScale(ctx, workload.name, scale, metav1.UpdateOptions{})
+	case workloadDeployment:
+		_, err = m.clientset.AppsV1().Deployments(m.namespace).UpdateScale(ctx, workload.name, scale, metav1.UpdateOptions{})
+	default:
+		err = fmt.Errorf("不支持的工作负载类型: %s", workload.kind)
+	}
+	return err
+}